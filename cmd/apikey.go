@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/config"
+)
+
+// runAPIKeyCommand generates a new API key, or hashes an existing one, and prints the entry
+// to add to auth.api_keys.keys or to the API key file
+func runAPIKeyCommand(args []string, output io.Writer) error {
+	flags := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	flags.SetOutput(output)
+	tenant := flags.String("tenant", "", "tenant the usage of the key is accounted to (required)")
+	scopes := flags.String("scopes", "", "comma separated scopes granted to the key")
+	tier := flags.String("tier", "", "rate limit tier of the key")
+	key := flags.String("key", "", "existing key to hash instead of generating a new one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *tenant == "" {
+		return errors.New("the -tenant flag is required")
+	}
+
+	apiKey := *key
+	if apiKey == "" {
+		generated, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		apiKey = generated
+	}
+	id, hash, err := auth.HashAPIKey(apiKey)
+	if err != nil {
+		return err
+	}
+	entry := config.APIKeyConfig{ID: id, Hash: hash, Tenant: *tenant, Tier: *tier}
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			entry.Scopes = append(entry.Scopes, scope)
+		}
+	}
+	encodedEntry, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if *key == "" {
+		fmt.Fprintf(output, "API key, it is not stored and cannot be shown again:\n%s\n\n", apiKey)
+	}
+	fmt.Fprintf(output, "Entry to add to auth.api_keys.keys or to the API key file:\n%s\n", encodedEntry)
+	return nil
+}
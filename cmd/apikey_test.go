@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/config"
+)
+
+// readEntry returns the API key entry printed on the last line of the output
+func readEntry(t *testing.T, output string) config.APIKeyConfig {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var entry config.APIKeyConfig
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &entry))
+	return entry
+}
+
+// authenticate checks the key against the printed entry
+func authenticate(t *testing.T, entry config.APIKeyConfig, key string) error {
+	t.Helper()
+	authenticator, err := auth.NewAPIKeyAuthenticator(&config.APIKeysConfig{Keys: []config.APIKeyConfig{entry}})
+	assert.NoError(t, err)
+	_, err = authenticator.Authenticate(context.Background(), metadata.Pairs(auth.APIKeyMetadataKey, key))
+	return err
+}
+
+func TestRunAPIKeyCommand_Generate(t *testing.T) {
+	var output bytes.Buffer
+
+	err := runAPIKeyCommand([]string{"-tenant", "batch", "-scopes", "images:analyze, sessions", "-tier", "premium"}, &output)
+
+	assert.NoError(t, err)
+	var keys []string
+	for _, field := range strings.Fields(output.String()) {
+		if strings.HasPrefix(field, auth.APIKeyPrefix) {
+			keys = append(keys, field)
+		}
+	}
+	assert.Len(t, keys, 1, "the key is printed once")
+	entry := readEntry(t, output.String())
+	assert.Equal(t, "batch", entry.Tenant)
+	assert.Equal(t, []string{"images:analyze", "sessions"}, entry.Scopes)
+	assert.Equal(t, "premium", entry.Tier)
+	secret := keys[0][strings.LastIndex(keys[0], "_")+1:]
+	assert.Equal(t, 1, strings.Count(output.String(), secret), "the secret is printed once")
+	assert.NoError(t, authenticate(t, entry, keys[0]))
+}
+
+func TestRunAPIKeyCommand_ExistingKey(t *testing.T) {
+	key, err := auth.GenerateAPIKey()
+	assert.NoError(t, err)
+	var output bytes.Buffer
+
+	err = runAPIKeyCommand([]string{"-tenant", "partner", "-key", key}, &output)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, output.String(), key)
+	entry := readEntry(t, output.String())
+	assert.Equal(t, "partner", entry.Tenant)
+	assert.NoError(t, authenticate(t, entry, key))
+}
+
+func TestRunAPIKeyCommand_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "Error_MissingTenant",
+			args:          []string{"-scopes", "images:analyze"},
+			expectedError: "the -tenant flag is required",
+		},
+		{
+			name:          "Error_UnknownFlag",
+			args:          []string{"-tenant", "batch", "-owner", "me"},
+			expectedError: "flag provided but not defined: -owner",
+		},
+		{
+			name:          "Error_MalformedKey",
+			args:          []string{"-tenant", "batch", "-key", "secret"},
+			expectedError: "the API key is malformed",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+
+			err := runAPIKeyCommand(testCase.args, &output)
+
+			assert.EqualError(t, err, testCase.expectedError)
+			assert.NotContains(t, output.String(), auth.APIKeyPrefix)
+		})
+	}
+}
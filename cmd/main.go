@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	commontConfig "github.com/quadev-ltd/qd-common/pkg/config"
 
@@ -10,6 +11,13 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKeyCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalln("Failed to create the API key", err)
+		}
+		return
+	}
+
 	var configurations config.Config
 	configLocation := "./internal/config"
 	err := configurations.Load(configLocation)
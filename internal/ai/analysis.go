@@ -1,9 +1,184 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
 
-// Analyzer knows how to take an image and a prompt and return text
+// OutputFormat is the format the model is asked to write its response in
+type OutputFormat string
+
+const (
+	// OutputFormatMarkdown asks for a markdown response, it is the default
+	OutputFormatMarkdown OutputFormat = "markdown"
+	// OutputFormatPlainText asks for a response without any markup
+	OutputFormatPlainText OutputFormat = "plain_text"
+	// OutputFormatHTML asks for an HTML fragment
+	OutputFormatHTML OutputFormat = "html"
+	// OutputFormatJSON asks for a JSON document
+	OutputFormatJSON OutputFormat = "json"
+)
+
+// IsValid reports whether the output format is one of the supported formats
+func (outputFormat OutputFormat) IsValid() bool {
+	switch outputFormat {
+	case OutputFormatMarkdown, OutputFormatPlainText, OutputFormatHTML, OutputFormatJSON:
+		return true
+	}
+	return false
+}
+
+// GenerationParams overrides the generation settings of the provider configuration, nil fields keep the configured value.
+// Providers ignore the parameters they do not support, such as the candidate count on Ollama,
+// except for the seed which is rejected when the analyzer does not support it.
+type GenerationParams struct {
+	MaxTokens      *int32
+	Temperature    *float32
+	TopP           *float32
+	TopK           *int32
+	StopSequences  []string
+	Seed           *int32
+	CandidateCount *int32
+}
+
+// Options holds the per-request settings of an analysis, a nil Options uses the provider defaults
+type Options struct {
+	// OutputFormat is the format of the response, markdown when empty
+	OutputFormat OutputFormat
+	// JSONSchema is the JSON Schema document the JSON output must follow, it is optional
+	JSONSchema map[string]interface{}
+	// Generation overrides the configured generation parameters
+	Generation GenerationParams
+	// Model overrides the configured model name
+	Model string
+	// SystemInstruction is sent to the model separately from the prompt when set
+	SystemInstruction string
+}
+
+func (options *Options) systemInstruction() string {
+	if options == nil {
+		return ""
+	}
+	return options.SystemInstruction
+}
+
+func (options *Options) modelName(defaultModelName string) string {
+	if options == nil || options.Model == "" {
+		return defaultModelName
+	}
+	return options.Model
+}
+
+func (options *Options) generation() *GenerationParams {
+	if options == nil {
+		return &GenerationParams{}
+	}
+	return &options.Generation
+}
+
+func (options *Options) outputFormat() OutputFormat {
+	if options == nil || options.OutputFormat == "" {
+		return OutputFormatMarkdown
+	}
+	return options.OutputFormat
+}
+
+func (options *Options) jsonOutput() bool {
+	return options.outputFormat() == OutputFormatJSON
+}
+
+// Image is an image or a PDF document sent to the model together with its mime type
+type Image struct {
+	Data     []byte
+	MimeType string
+}
+
+// Role is the author of a conversation message
+type Role string
+
+const (
+	// RoleUser is the role of the messages sent by the client
+	RoleUser Role = "user"
+	// RoleModel is the role of the answers of the model
+	RoleModel Role = "model"
+)
+
+// Message is a turn of a conversation about images
+type Message struct {
+	Role Role
+	Text string
+}
+
+// Analyzer knows how to take images and a prompt and return text
 type Analyzer interface {
-	Analyze(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error)
+	Analyze(ctx context.Context, images []Image, prompt string, options *Options) (*Result, error)
+	// AnalyzeStream behaves like Analyze but hands the text to send as the model produces it.
+	// It stops as soon as ctx is cancelled or send fails.
+	// Once the stream ends it returns the streamed text as a single candidate with the usage reported by the provider.
+	// When the stream fails after the model started answering, the partial result is returned along with the error.
+	AnalyzeStream(
+		ctx context.Context,
+		images []Image,
+		prompt string,
+		options *Options,
+		send func(chunk string) error,
+	) (*Result, error)
+	// Chat answers the prompt as the next turn of a conversation about the images.
+	// The history is replayed first with the images attached to its first message, or to the prompt when it is empty.
+	Chat(ctx context.Context, images []Image, history []Message, prompt string, options *Options) (*Result, error)
+	// SupportsDocuments reports whether PDF documents can be sent like images
+	SupportsDocuments() bool
+	// SupportsSeed reports whether the seed of the generation parameters is honoured
+	SupportsSeed() bool
 	Close() error
 }
+
+// newConversation appends the formatted prompt to a copy of the history
+func newConversation(history []Message, prompt string, options *Options) []Message {
+	conversation := make([]Message, 0, len(history)+1)
+	conversation = append(conversation, history...)
+	return append(conversation, Message{Role: RoleUser, Text: formatPrompt(prompt, options)})
+}
+
+// formatPrompt wraps the user prompt with the formatting instructions sent to every provider
+func formatPrompt(prompt string, options *Options) string {
+	switch options.outputFormat() {
+	case OutputFormatPlainText:
+		return fmt.Sprintf(
+			"Please format your response as plain text without any markdown or HTML. Here is the analysis request: %s",
+			prompt,
+		)
+	case OutputFormatHTML:
+		return fmt.Sprintf(
+			"Please format your response as an HTML fragment without html, head or body tags. Here is the analysis request: %s",
+			prompt,
+		)
+	case OutputFormatJSON:
+	default:
+		return fmt.Sprintf("Please format your response as markdown. Here is the analysis request: %s", prompt)
+	}
+	if options.JSONSchema == nil {
+		return fmt.Sprintf("Please respond only with a JSON document. Here is the analysis request: %s", prompt)
+	}
+	schema, _ := json.Marshal(options.JSONSchema)
+	return fmt.Sprintf(
+		"Please respond only with a JSON document following this JSON Schema: %s. Here is the analysis request: %s",
+		schema,
+		prompt,
+	)
+}
+
+func int32OrDefault(value *int32, defaultValue int32) int32 {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func float32OrDefault(value *float32, defaultValue float32) float32 {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
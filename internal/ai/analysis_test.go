@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testPNGImages  = []Image{{Data: []byte("image"), MimeType: "image/png"}}
+	testJPEGImages = []Image{{Data: []byte("image"), MimeType: "image/jpeg"}}
+)
+
+func TestFormatPrompt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  *Options
+		expected string
+	}{
+		{
+			name:     "default is markdown",
+			options:  nil,
+			expected: "Please format your response as markdown. Here is the analysis request: Describe it",
+		},
+		{
+			name:     "plain text",
+			options:  &Options{OutputFormat: OutputFormatPlainText},
+			expected: "Please format your response as plain text without any markdown or HTML. Here is the analysis request: Describe it",
+		},
+		{
+			name:     "html",
+			options:  &Options{OutputFormat: OutputFormatHTML},
+			expected: "Please format your response as an HTML fragment without html, head or body tags. Here is the analysis request: Describe it",
+		},
+		{
+			name:     "json",
+			options:  &Options{OutputFormat: OutputFormatJSON},
+			expected: "Please respond only with a JSON document. Here is the analysis request: Describe it",
+		},
+		{
+			name:    "json with schema",
+			options: &Options{OutputFormat: OutputFormatJSON, JSONSchema: map[string]interface{}{"type": "object"}},
+			expected: `Please respond only with a JSON document following this JSON Schema: {"type":"object"}. ` +
+				"Here is the analysis request: Describe it",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, formatPrompt("Describe it", testCase.options))
+		})
+	}
+}
+
+func TestOutputFormat_IsValid(t *testing.T) {
+	assert.True(t, OutputFormatPlainText.IsValid())
+	assert.False(t, OutputFormat("xml").IsValid())
+	assert.False(t, OutputFormat("").IsValid())
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind classifies the failures reported by the analyzer providers
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown is used when the failure cannot be classified
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindInvalidRequest is used when the provider rejects the request content
+	ErrorKindInvalidRequest
+	// ErrorKindRateLimited is used when the provider throttles the request
+	ErrorKindRateLimited
+	// ErrorKindUnavailable is used when the provider fails or cannot be reached
+	ErrorKindUnavailable
+)
+
+// Error is the error type returned by the analyzers when the provider fails a request
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int
+	Message    string
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("analyzer provider returned status %d: %s", e.StatusCode, e.Message)
+	}
+	return e.Message
+}
+
+func newHTTPError(statusCode int, message string) *Error {
+	kind := ErrorKindUnknown
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		kind = ErrorKindRateLimited
+	case statusCode >= http.StatusInternalServerError:
+		kind = ErrorKindUnavailable
+	case statusCode == http.StatusBadRequest,
+		statusCode == http.StatusRequestEntityTooLarge,
+		statusCode == http.StatusUnprocessableEntity:
+		kind = ErrorKindInvalidRequest
+	}
+	return &Error{
+		Kind:       kind,
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
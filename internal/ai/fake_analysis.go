@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+// ProviderFake is the provider name of the deterministic fixtures based analyzer
+const ProviderFake = "fake"
+
+// FakeRule is a canned response returned when all of its conditions match the request.
+// The image conditions match when any image of the request satisfies them.
+// Empty conditions are ignored, so a rule without conditions matches every request.
+type FakeRule struct {
+	ImageSHA256   string `yaml:"image_sha256"`
+	MimeType      string `yaml:"mime_type"`
+	PromptPattern string `yaml:"prompt_pattern"`
+	Response      string `yaml:"response"`
+	FinishReason  string `yaml:"finish_reason"`
+	Error         string `yaml:"error"`
+
+	promptRegexp *regexp.Regexp
+}
+
+// FakeFixtures is the content of the fixtures file used by the FakeAnalyzer
+type FakeFixtures struct {
+	DefaultResponse string     `yaml:"default_response"`
+	Rules           []FakeRule `yaml:"rules"`
+}
+
+// LoadFakeFixtures reads and compiles the fixtures file at the given path
+func LoadFakeFixtures(path string) (*FakeFixtures, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures file: %v", err)
+	}
+	var fixtures FakeFixtures
+	if err := yaml.Unmarshal(content, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures file: %v", err)
+	}
+	for index := range fixtures.Rules {
+		rule := &fixtures.Rules[index]
+		rule.ImageSHA256 = strings.ToLower(rule.ImageSHA256)
+		if rule.PromptPattern == "" {
+			continue
+		}
+		rule.promptRegexp, err = regexp.Compile(rule.PromptPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt_pattern in rule %d: %v", index, err)
+		}
+	}
+	return &fixtures, nil
+}
+
+func (rule *FakeRule) matches(imageHashes []string, images []Image, prompt string) bool {
+	if rule.promptRegexp != nil && !rule.promptRegexp.MatchString(prompt) {
+		return false
+	}
+	if rule.ImageSHA256 == "" && rule.MimeType == "" {
+		return true
+	}
+	for index, image := range images {
+		if (rule.ImageSHA256 == "" || rule.ImageSHA256 == imageHashes[index]) &&
+			(rule.MimeType == "" || rule.MimeType == image.MimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// FakeAnalyzer is a deterministic implementation of Analyzer that answers from a fixtures file.
+// It is meant for end-to-end tests and demos where the real model cannot be used.
+type FakeAnalyzer struct {
+	fixtures *FakeFixtures
+}
+
+var _ Analyzer = &FakeAnalyzer{}
+
+// NewFakeAnalyzer creates a new instance of FakeAnalyzer answering from the provided fixtures
+func NewFakeAnalyzer(fixtures *FakeFixtures) *FakeAnalyzer {
+	return &FakeAnalyzer{fixtures: fixtures}
+}
+
+// Analyze returns the response of the first rule matching the image SHA-256, mime type and prompt.
+// It falls back to the default response and fails when there is none.
+func (fakeAnalyzer *FakeAnalyzer) Analyze(ctx context.Context, images []Image, prompt string, options *Options) (*Result, error) {
+	imageHashes := make([]string, 0, len(images))
+	for _, image := range images {
+		imageHash := sha256.Sum256(image.Data)
+		imageHashes = append(imageHashes, hex.EncodeToString(imageHash[:]))
+	}
+	for index := range fakeAnalyzer.fixtures.Rules {
+		rule := &fakeAnalyzer.fixtures.Rules[index]
+		if !rule.matches(imageHashes, images, prompt) {
+			continue
+		}
+		if rule.Error != "" {
+			return nil, &Error{Kind: ErrorKindUnknown, Message: rule.Error}
+		}
+		return newFakeResult(rule.Response, rule.FinishReason), nil
+	}
+	if fakeAnalyzer.fixtures.DefaultResponse == "" {
+		return nil, fmt.Errorf("no fixture matches images %v and prompt %q", imageHashes, prompt)
+	}
+	return newFakeResult(fakeAnalyzer.fixtures.DefaultResponse, ""), nil
+}
+
+func newFakeResult(response, finishReason string) *Result {
+	reason := FinishReasonStop
+	if finishReason != "" {
+		reason = FinishReason(strings.ToUpper(finishReason))
+	}
+	return &Result{
+		Candidates: []Candidate{{Text: response, FinishReason: reason}},
+	}
+}
+
+// AnalyzeStream sends the same response Analyze would return as a single chunk and returns it
+func (fakeAnalyzer *FakeAnalyzer) AnalyzeStream(
+	ctx context.Context,
+	images []Image,
+	prompt string,
+	options *Options,
+	send func(chunk string) error,
+) (*Result, error) {
+	result, err := fakeAnalyzer.Analyze(ctx, images, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := send(result.Text()); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Chat answers the prompt like Analyze, ignoring the history
+func (fakeAnalyzer *FakeAnalyzer) Chat(
+	ctx context.Context,
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+) (*Result, error) {
+	return fakeAnalyzer.Analyze(ctx, images, prompt, options)
+}
+
+// SupportsDocuments reports true as the fake analyzer answers from its fixtures whatever it is sent
+func (fakeAnalyzer *FakeAnalyzer) SupportsDocuments() bool {
+	return true
+}
+
+// SupportsSeed reports true as the answers of the fake analyzer are deterministic anyway
+func (fakeAnalyzer *FakeAnalyzer) SupportsSeed() bool {
+	return true
+}
+
+// Close does nothing as the fake analyzer holds no resources
+func (fakeAnalyzer *FakeAnalyzer) Close() error {
+	return nil
+}
+
+// FakeAnalyzerFactory builds fake analyzers from the application configuration
+type FakeAnalyzerFactory struct{}
+
+var _ AnalyzerFactoryer = &FakeAnalyzerFactory{}
+
+// Validate checks that the fixtures file is configured and can be loaded
+func (factory *FakeAnalyzerFactory) Validate(config *configPkg.Config) error {
+	if config.Fake.FixturesPath == "" {
+		return fmt.Errorf("fake.fixtures_path is required")
+	}
+	_, err := LoadFakeFixtures(config.Fake.FixturesPath)
+	return err
+}
+
+// Create builds a new FakeAnalyzer
+func (factory *FakeAnalyzerFactory) Create(config *configPkg.Config) (Analyzer, error) {
+	fixtures, err := LoadFakeFixtures(config.Fake.FixturesPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewFakeAnalyzer(fixtures), nil
+}
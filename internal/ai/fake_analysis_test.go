@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+const testFixturesPath = "testdata/fake_fixtures.yml"
+
+func TestFakeAnalyzer_Analyze(t *testing.T) {
+	fixtures, err := LoadFakeFixtures(testFixturesPath)
+	assert.NoError(t, err)
+	analyzer := NewFakeAnalyzer(fixtures)
+
+	testCases := []struct {
+		name                 string
+		images               []Image
+		prompt               string
+		expectedResponse     string
+		expectedFinishReason FinishReason
+		expectedError        string
+	}{
+		{
+			name:                 "image and prompt match",
+			images:               []Image{{Data: []byte("image"), MimeType: "image/png"}},
+			prompt:               "Is there any Damage?",
+			expectedResponse:     "# Damage report\n\nThe bumper is scratched.",
+			expectedFinishReason: FinishReasonMaxTokens,
+		},
+		{
+			name:                 "image hash match",
+			images:               []Image{{Data: []byte("image"), MimeType: "image/png"}},
+			prompt:               "What is this?",
+			expectedResponse:     "# Image\n\nA car parked on the street.",
+			expectedFinishReason: FinishReasonStop,
+		},
+		{
+			name: "any image matches",
+			images: []Image{
+				{Data: []byte("other-image"), MimeType: "image/jpeg"},
+				{Data: []byte("image"), MimeType: "image/png"},
+			},
+			prompt:               "What is this?",
+			expectedResponse:     "# Image\n\nA car parked on the street.",
+			expectedFinishReason: FinishReasonStop,
+		},
+		{
+			name:          "error rule",
+			images:        []Image{{Data: []byte("other-image"), MimeType: "image/png"}},
+			prompt:        "fail please",
+			expectedError: "simulated provider failure",
+		},
+		{
+			name:                 "default response",
+			images:               []Image{{Data: []byte("other-image"), MimeType: "image/png"}},
+			prompt:               "What is this?",
+			expectedResponse:     "# Analysis\n\nNothing remarkable found.",
+			expectedFinishReason: FinishReasonStop,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := analyzer.Analyze(context.Background(), testCase.images, testCase.prompt, nil)
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedResponse, result.Text())
+			assert.Equal(t, testCase.expectedFinishReason, result.FinishReason())
+		})
+	}
+}
+
+func TestFakeAnalyzer_Analyze_NoMatchWithoutDefault(t *testing.T) {
+	analyzer := NewFakeAnalyzer(&FakeFixtures{})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", nil)
+
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "no fixture matches images [6105d6cc")
+}
+
+func TestLoadFakeFixtures_ConfigExample(t *testing.T) {
+	fixtures, err := LoadFakeFixtures("../config/fake_fixtures.yml")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, fixtures)
+}
+
+func TestLoadFakeFixtures_InvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("rules:\n  - prompt_pattern: \"(\"\n"), 0o600))
+
+	fixtures, err := LoadFakeFixtures(path)
+
+	assert.Nil(t, fixtures)
+	assert.Contains(t, err.Error(), "invalid prompt_pattern in rule 0")
+}
+
+func TestFakeAnalyzerFactory_Validate(t *testing.T) {
+	factory := &FakeAnalyzerFactory{}
+
+	assert.EqualError(t, factory.Validate(&configPkg.Config{}), "fake.fixtures_path is required")
+	assert.NoError(t, factory.Validate(&configPkg.Config{Fake: configPkg.FakeConfig{FixturesPath: testFixturesPath}}))
+}
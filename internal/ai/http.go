@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 60 * time.Second
+	maxErrorBodySize   = 4096
+	maxStreamLineSize  = 1024 * 1024
+)
+
+// errStreamDone is returned by the stream line handlers to stop reading once the provider signals the end
+var errStreamDone = errors.New("stream done")
+
+// newHTTPClient creates the HTTP client used by the HTTP based providers
+func newHTTPClient(timeoutSeconds int) *http.Client {
+	timeout := defaultHTTPTimeout
+	if timeoutSeconds > 0 {
+		timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// readHTTPErrorMessage extracts the error message from a failed provider response.
+// It understands both the OpenAI ({"error":{"message":...}}) and Ollama ({"error":...}) payloads
+// and falls back to the raw body otherwise.
+func readHTTPErrorMessage(body io.Reader) string {
+	content, err := io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	if err != nil {
+		return err.Error()
+	}
+	var errorResponse struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(content, &errorResponse) == nil && len(errorResponse.Error) > 0 {
+		var message string
+		if json.Unmarshal(errorResponse.Error, &message) == nil && message != "" {
+			return message
+		}
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(errorResponse.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// post sends the payload as JSON to the endpoint and returns the response when it succeeded.
+// Transport failures and non 200 responses are returned as *Error so they can be mapped by the callers.
+func post(
+	ctx context.Context,
+	httpClient *http.Client,
+	endpoint string,
+	headers map[string]string,
+	payload interface{},
+) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request to %s: %v", endpoint, err)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request to %s: %v", endpoint, err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		httpRequest.Header.Set(key, value)
+	}
+
+	httpResponse, err := httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, &Error{
+			Kind:    ErrorKindUnavailable,
+			Message: fmt.Sprintf("request to %s failed: %v", endpoint, err),
+		}
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		defer httpResponse.Body.Close()
+		return nil, newHTTPError(httpResponse.StatusCode, readHTTPErrorMessage(httpResponse.Body))
+	}
+	return httpResponse, nil
+}
+
+// postJSON sends the payload as JSON to the endpoint and decodes the JSON response into result
+func postJSON(
+	ctx context.Context,
+	httpClient *http.Client,
+	endpoint string,
+	headers map[string]string,
+	payload interface{},
+	result interface{},
+) error {
+	httpResponse, err := post(ctx, httpClient, endpoint, headers, payload)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if err := json.NewDecoder(httpResponse.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %v", endpoint, err)
+	}
+	return nil
+}
+
+// postStream sends the payload as JSON to the endpoint and calls handleLine for every non empty line
+// of the response body until the body ends, handleLine returns errStreamDone or fails.
+func postStream(
+	ctx context.Context,
+	httpClient *http.Client,
+	endpoint string,
+	headers map[string]string,
+	payload interface{},
+	handleLine func(line []byte) error,
+) error {
+	httpResponse, err := post(ctx, httpClient, endpoint, headers, payload)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	scanner := bufio.NewScanner(httpResponse.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		err := handleLine(line)
+		if err == errStreamDone {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &Error{
+			Kind:    ErrorKindUnavailable,
+			Message: fmt.Sprintf("failed to read stream from %s: %v", endpoint, err),
+		}
+	}
+	return nil
+}
+
+// validateBaseURL checks that a provider base URL is set and uses http or https
+func validateBaseURL(key, baseURL string) error {
+	switch {
+	case baseURL == "":
+		return fmt.Errorf("%s is required", key)
+	case !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://"):
+		return fmt.Errorf("%s must be an http or https URL", key)
+	}
+	return nil
+}
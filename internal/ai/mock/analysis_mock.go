@@ -6,6 +6,7 @@ package mock
 
 import (
 	context "context"
+	ai "qd-image-analysis-api/internal/ai"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,18 +36,48 @@ func (m *MockAnalyzer) EXPECT() *MockAnalyzerMockRecorder {
 }
 
 // Analyze mocks base method.
-func (m *MockAnalyzer) Analyze(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error) {
+func (m *MockAnalyzer) Analyze(ctx context.Context, images []ai.Image, prompt string, options *ai.Options) (*ai.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Analyze", ctx, imageData, mimeType, prompt)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Analyze", ctx, images, prompt, options)
+	ret0, _ := ret[0].(*ai.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Analyze indicates an expected call of Analyze.
-func (mr *MockAnalyzerMockRecorder) Analyze(ctx, imageData, mimeType, prompt interface{}) *gomock.Call {
+func (mr *MockAnalyzerMockRecorder) Analyze(ctx, images, prompt, options interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Analyze", reflect.TypeOf((*MockAnalyzer)(nil).Analyze), ctx, imageData, mimeType, prompt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Analyze", reflect.TypeOf((*MockAnalyzer)(nil).Analyze), ctx, images, prompt, options)
+}
+
+// AnalyzeStream mocks base method.
+func (m *MockAnalyzer) AnalyzeStream(ctx context.Context, images []ai.Image, prompt string, options *ai.Options, send func(string) error) (*ai.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnalyzeStream", ctx, images, prompt, options, send)
+	ret0, _ := ret[0].(*ai.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnalyzeStream indicates an expected call of AnalyzeStream.
+func (mr *MockAnalyzerMockRecorder) AnalyzeStream(ctx, images, prompt, options, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeStream", reflect.TypeOf((*MockAnalyzer)(nil).AnalyzeStream), ctx, images, prompt, options, send)
+}
+
+// Chat mocks base method.
+func (m *MockAnalyzer) Chat(ctx context.Context, images []ai.Image, history []ai.Message, prompt string, options *ai.Options) (*ai.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Chat", ctx, images, history, prompt, options)
+	ret0, _ := ret[0].(*ai.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Chat indicates an expected call of Chat.
+func (mr *MockAnalyzerMockRecorder) Chat(ctx, images, history, prompt, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chat", reflect.TypeOf((*MockAnalyzer)(nil).Chat), ctx, images, history, prompt, options)
 }
 
 // Close mocks base method.
@@ -62,3 +93,31 @@ func (mr *MockAnalyzerMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockAnalyzer)(nil).Close))
 }
+
+// SupportsDocuments mocks base method.
+func (m *MockAnalyzer) SupportsDocuments() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsDocuments")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsDocuments indicates an expected call of SupportsDocuments.
+func (mr *MockAnalyzerMockRecorder) SupportsDocuments() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsDocuments", reflect.TypeOf((*MockAnalyzer)(nil).SupportsDocuments))
+}
+
+// SupportsSeed mocks base method.
+func (m *MockAnalyzer) SupportsSeed() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsSeed")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsSeed indicates an expected call of SupportsSeed.
+func (mr *MockAnalyzerMockRecorder) SupportsSeed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsSeed", reflect.TypeOf((*MockAnalyzer)(nil).SupportsSeed))
+}
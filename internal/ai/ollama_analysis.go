@@ -0,0 +1,331 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+// ProviderOllama is the provider name of the Ollama analyzer
+const ProviderOllama = "ollama"
+
+const (
+	ollamaGeneratePath = "/api/generate"
+	ollamaChatPath     = "/api/chat"
+)
+
+type ollamaOptions struct {
+	Temperature float32  `json:"temperature"`
+	NumPredict  int32    `json:"num_predict,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	TopK        *int32   `json:"top_k,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int32   `json:"seed,omitempty"`
+}
+
+type ollamaGenerateRequest struct {
+	Model   string        `json:"model"`
+	System  string        `json:"system,omitempty"`
+	Prompt  string        `json:"prompt"`
+	Images  []string      `json:"images"`
+	Stream  bool          `json:"stream"`
+	Options ollamaOptions `json:"options"`
+	// Format is either "json" or a JSON Schema object
+	Format interface{} `json:"format,omitempty"`
+}
+
+type ollamaChatMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []ollamaChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+	Options  ollamaOptions       `json:"options"`
+	Format   interface{}         `json:"format,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaChatMessage `json:"message"`
+	Done            bool              `json:"done"`
+	DoneReason      string            `json:"done_reason"`
+	PromptEvalCount int32             `json:"prompt_eval_count"`
+	EvalCount       int32             `json:"eval_count"`
+}
+
+type ollamaGenerateResponse struct {
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	Error           string `json:"error"`
+	PromptEvalCount int32  `json:"prompt_eval_count"`
+	EvalCount       int32  `json:"eval_count"`
+}
+
+// OllamaAnalyzer is a concrete implementation of Analyzer using a local Ollama generate endpoint.
+// It needs no cloud credentials which makes it suitable for offline development with models such as llava.
+type OllamaAnalyzer struct {
+	httpClient *http.Client
+	config     *configPkg.OllamaConfig
+}
+
+var _ Analyzer = &OllamaAnalyzer{}
+
+// NewOllamaAnalyzer creates a new instance of OllamaAnalyzer with the provided configuration
+func NewOllamaAnalyzer(config *configPkg.OllamaConfig) *OllamaAnalyzer {
+	return &OllamaAnalyzer{
+		httpClient: newHTTPClient(config.TimeoutSeconds),
+		config:     config,
+	}
+}
+
+// Analyze sends the base64 encoded images together with the prompt to the generate endpoint.
+// It returns the generated response as a single candidate or an error if the analysis fails.
+func (ollamaAnalyzer *OllamaAnalyzer) Analyze(ctx context.Context, images []Image, prompt string, options *Options) (*Result, error) {
+	var generateResponse ollamaGenerateResponse
+	err := postJSON(
+		ctx,
+		ollamaAnalyzer.httpClient,
+		ollamaAnalyzer.endpoint(ollamaGeneratePath),
+		nil,
+		ollamaAnalyzer.newGenerateRequest(images, prompt, options, false),
+		&generateResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !generateResponse.Done {
+		return nil, fmt.Errorf("incomplete response from ollama")
+	}
+	return &Result{
+		Candidates: []Candidate{
+			{
+				Text:         generateResponse.Response,
+				FinishReason: finishReasonFromOpenAI(generateResponse.DoneReason),
+			},
+		},
+		Model: options.modelName(ollamaAnalyzer.config.ModelName),
+		Usage: Usage{
+			PromptTokens:     generateResponse.PromptEvalCount,
+			CandidatesTokens: generateResponse.EvalCount,
+			TotalTokens:      generateResponse.PromptEvalCount + generateResponse.EvalCount,
+		},
+	}, nil
+}
+
+// AnalyzeStream requests a streamed generation and sends every newline delimited response fragment
+// until ollama reports it is done, the usage is read from the last fragment.
+func (ollamaAnalyzer *OllamaAnalyzer) AnalyzeStream(
+	ctx context.Context,
+	images []Image,
+	prompt string,
+	options *Options,
+	send func(chunk string) error,
+) (*Result, error) {
+	var text strings.Builder
+	result := &Result{Model: options.modelName(ollamaAnalyzer.config.ModelName)}
+	finishReason := FinishReasonUnspecified
+	chunks := 0
+	err := postStream(
+		ctx,
+		ollamaAnalyzer.httpClient,
+		ollamaAnalyzer.endpoint(ollamaGeneratePath),
+		nil,
+		ollamaAnalyzer.newGenerateRequest(images, prompt, options, true),
+		func(line []byte) error {
+			var generateResponse ollamaGenerateResponse
+			if err := json.Unmarshal(line, &generateResponse); err != nil {
+				return fmt.Errorf("failed to decode ollama chunk: %v", err)
+			}
+			if generateResponse.Error != "" {
+				return &Error{Kind: ErrorKindUnavailable, Message: generateResponse.Error}
+			}
+			if generateResponse.Response != "" {
+				text.WriteString(generateResponse.Response)
+				chunks++
+				if err := send(generateResponse.Response); err != nil {
+					return err
+				}
+			}
+			if generateResponse.Done {
+				finishReason = finishReasonFromOpenAI(generateResponse.DoneReason)
+				result.Usage = Usage{
+					PromptTokens:     generateResponse.PromptEvalCount,
+					CandidatesTokens: generateResponse.EvalCount,
+					TotalTokens:      generateResponse.PromptEvalCount + generateResponse.EvalCount,
+				}
+				return errStreamDone
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return partialStreamResult(result, text.String(), chunks), err
+	}
+	result.Candidates = []Candidate{{Text: text.String(), FinishReason: finishReason}}
+	return result, nil
+}
+
+// Chat sends the history as previous chat messages followed by the prompt to the chat endpoint
+func (ollamaAnalyzer *OllamaAnalyzer) Chat(
+	ctx context.Context,
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+) (*Result, error) {
+	var chatResponse ollamaChatResponse
+	err := postJSON(
+		ctx,
+		ollamaAnalyzer.httpClient,
+		ollamaAnalyzer.endpoint(ollamaChatPath),
+		nil,
+		ollamaAnalyzer.newChatRequest(images, history, prompt, options),
+		&chatResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !chatResponse.Done {
+		return nil, fmt.Errorf("incomplete response from ollama")
+	}
+	return &Result{
+		Candidates: []Candidate{
+			{
+				Text:         chatResponse.Message.Content,
+				FinishReason: finishReasonFromOpenAI(chatResponse.DoneReason),
+			},
+		},
+		Model: options.modelName(ollamaAnalyzer.config.ModelName),
+		Usage: Usage{
+			PromptTokens:     chatResponse.PromptEvalCount,
+			CandidatesTokens: chatResponse.EvalCount,
+			TotalTokens:      chatResponse.PromptEvalCount + chatResponse.EvalCount,
+		},
+	}, nil
+}
+
+func (ollamaAnalyzer *OllamaAnalyzer) newGenerateRequest(
+	images []Image,
+	prompt string,
+	options *Options,
+	stream bool,
+) *ollamaGenerateRequest {
+	return &ollamaGenerateRequest{
+		Model:   options.modelName(ollamaAnalyzer.config.ModelName),
+		System:  options.systemInstruction(),
+		Prompt:  formatPrompt(prompt, options),
+		Images:  newOllamaImages(images),
+		Stream:  stream,
+		Options: ollamaAnalyzer.newOptions(options),
+		Format:  newOllamaFormat(options),
+	}
+}
+
+func (ollamaAnalyzer *OllamaAnalyzer) newChatRequest(
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+) *ollamaChatRequest {
+	messages := make([]ollamaChatMessage, 0, len(history)+2)
+	if systemInstruction := options.systemInstruction(); systemInstruction != "" {
+		messages = append(messages, ollamaChatMessage{Role: "system", Content: systemInstruction})
+	}
+	for index, message := range newConversation(history, prompt, options) {
+		chatMessage := ollamaChatMessage{Role: "user", Content: message.Text}
+		if message.Role == RoleModel {
+			chatMessage.Role = "assistant"
+		}
+		if index == 0 {
+			chatMessage.Images = newOllamaImages(images)
+		}
+		messages = append(messages, chatMessage)
+	}
+	return &ollamaChatRequest{
+		Model:    options.modelName(ollamaAnalyzer.config.ModelName),
+		Messages: messages,
+		Options:  ollamaAnalyzer.newOptions(options),
+		Format:   newOllamaFormat(options),
+	}
+}
+
+func (ollamaAnalyzer *OllamaAnalyzer) newOptions(options *Options) ollamaOptions {
+	generation := options.generation()
+	return ollamaOptions{
+		Temperature: float32OrDefault(generation.Temperature, ollamaAnalyzer.config.Temperature),
+		NumPredict:  int32OrDefault(generation.MaxTokens, ollamaAnalyzer.config.MaxTokens),
+		TopP:        generation.TopP,
+		TopK:        generation.TopK,
+		Stop:        generation.StopSequences,
+		Seed:        generation.Seed,
+	}
+}
+
+func newOllamaImages(images []Image) []string {
+	encodedImages := make([]string, 0, len(images))
+	for _, image := range images {
+		encodedImages = append(encodedImages, base64.StdEncoding.EncodeToString(image.Data))
+	}
+	return encodedImages
+}
+
+// newOllamaFormat returns "json", the JSON Schema of the options or nil when no JSON output is requested
+func newOllamaFormat(options *Options) interface{} {
+	if !options.jsonOutput() {
+		return nil
+	}
+	if options.JSONSchema != nil {
+		return options.JSONSchema
+	}
+	return "json"
+}
+
+func (ollamaAnalyzer *OllamaAnalyzer) endpoint(path string) string {
+	return strings.TrimRight(ollamaAnalyzer.config.BaseURL, "/") + path
+}
+
+// SupportsDocuments reports false, ollama only accepts images
+func (ollamaAnalyzer *OllamaAnalyzer) SupportsDocuments() bool {
+	return false
+}
+
+// SupportsSeed reports true, the seed is sent in the generation options
+func (ollamaAnalyzer *OllamaAnalyzer) SupportsSeed() bool {
+	return true
+}
+
+// Close releases the idle connections kept by the HTTP client
+func (ollamaAnalyzer *OllamaAnalyzer) Close() error {
+	ollamaAnalyzer.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// OllamaAnalyzerFactory builds Ollama analyzers from the application configuration
+type OllamaAnalyzerFactory struct{}
+
+var _ AnalyzerFactoryer = &OllamaAnalyzerFactory{}
+
+// Validate checks that the endpoint and model of the Ollama provider are configured
+func (factory *OllamaAnalyzerFactory) Validate(config *configPkg.Config) error {
+	if err := validateBaseURL("ollama.base_url", config.Ollama.BaseURL); err != nil {
+		return err
+	}
+	if config.Ollama.ModelName == "" {
+		return fmt.Errorf("ollama.model_name is required")
+	}
+	return nil
+}
+
+// Create builds a new OllamaAnalyzer
+func (factory *OllamaAnalyzerFactory) Create(config *configPkg.Config) (Analyzer, error) {
+	return NewOllamaAnalyzer(&config.Ollama), nil
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+func newTestOllamaAnalyzer(t *testing.T, handler http.HandlerFunc) *OllamaAnalyzer {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOllamaAnalyzer(&configPkg.OllamaConfig{
+		BaseURL:   server.URL,
+		ModelName: "llava",
+		MaxTokens: 128,
+	})
+}
+
+func TestOllamaAnalyzer_Analyze_Success(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/api/generate", request.URL.Path)
+
+		var generateRequest ollamaGenerateRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&generateRequest))
+		assert.Equal(t, "llava", generateRequest.Model)
+		assert.Equal(t, formatPrompt("Describe it", nil), generateRequest.Prompt)
+		assert.Equal(t, []string{"aW1hZ2U="}, generateRequest.Images)
+		assert.False(t, generateRequest.Stream)
+		assert.Equal(t, int32(128), generateRequest.Options.NumPredict)
+
+		_, _ = writer.Write([]byte(`{"model":"llava","response":"A red car","done":true,"done_reason":"length","prompt_eval_count":600,"eval_count":4}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testJPEGImages, "Describe it", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "A red car", result.Text())
+	assert.Equal(t, FinishReasonMaxTokens, result.FinishReason())
+	assert.Equal(t, Usage{PromptTokens: 600, CandidatesTokens: 4, TotalTokens: 604}, result.Usage)
+}
+
+func TestOllamaAnalyzer_Analyze_ModelNotFound(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte(`{"error":"model 'llava' not found"}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testJPEGImages, "Describe it", nil)
+
+	assert.Nil(t, result)
+	analyzerErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorKindUnknown, analyzerErr.Kind)
+	assert.Equal(t, "model 'llava' not found", analyzerErr.Message)
+}
+
+func TestOllamaAnalyzerFactory_Validate(t *testing.T) {
+	factory := &OllamaAnalyzerFactory{}
+
+	assert.EqualError(t, factory.Validate(&configPkg.Config{}), "ollama.base_url is required")
+	assert.EqualError(
+		t,
+		factory.Validate(&configPkg.Config{Ollama: configPkg.OllamaConfig{BaseURL: "http://localhost:11434"}}),
+		"ollama.model_name is required",
+	)
+}
+
+func TestOllamaAnalyzer_AnalyzeStream_Success(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var generateRequest ollamaGenerateRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&generateRequest))
+		assert.True(t, generateRequest.Stream)
+
+		_, _ = writer.Write([]byte("{\"response\":\"A red\",\"done\":false}\n"))
+		_, _ = writer.Write([]byte("{\"response\":\" car\",\"done\":false}\n"))
+		_, _ = writer.Write([]byte(
+			"{\"response\":\"\",\"done\":true,\"done_reason\":\"stop\",\"prompt_eval_count\":12,\"eval_count\":3}\n",
+		))
+	})
+
+	var chunks []string
+	result, err := analyzer.AnalyzeStream(context.Background(), testJPEGImages, "Describe it", nil, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A red", " car"}, chunks)
+	assert.Equal(t, "A red car", result.Text())
+	assert.Equal(t, FinishReasonStop, result.FinishReason())
+	assert.Equal(t, Usage{PromptTokens: 12, CandidatesTokens: 3, TotalTokens: 15}, result.Usage)
+}
+
+func TestOllamaAnalyzer_AnalyzeStream_SendError(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("{\"response\":\"A red\",\"done\":false}\n"))
+		_, _ = writer.Write([]byte("{\"response\":\" car\",\"done\":false}\n"))
+	})
+	sendErr := errors.New("client went away")
+
+	calls := 0
+	result, err := analyzer.AnalyzeStream(context.Background(), testJPEGImages, "Describe it", nil, func(chunk string) error {
+		calls++
+		return sendErr
+	})
+
+	assert.Equal(t, sendErr, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "A red", result.Text())
+	assert.Equal(t, Usage{CandidatesTokens: 1, TotalTokens: 1}, result.Usage)
+}
+
+func TestOllamaAnalyzer_Analyze_SystemInstruction(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var generateRequest ollamaGenerateRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&generateRequest))
+		assert.Equal(t, "You are a bookkeeper.", generateRequest.System)
+
+		_, _ = writer.Write([]byte(`{"model":"llava","response":"Two coffees","done":true,"done_reason":"stop"}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testJPEGImages, "Describe it", &Options{
+		SystemInstruction: "You are a bookkeeper.",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Two coffees", result.Text())
+}
+
+func TestOllamaAnalyzer_Chat_History(t *testing.T) {
+	analyzer := newTestOllamaAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/api/chat", request.URL.Path)
+
+		var chatRequest ollamaChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		assert.Equal(t, "llava", chatRequest.Model)
+		assert.False(t, chatRequest.Stream)
+		assert.Equal(t, []ollamaChatMessage{
+			{Role: "system", Content: "Answer briefly."},
+			{Role: "user", Content: "What is this?", Images: []string{"aW1hZ2U="}},
+			{Role: "assistant", Content: "A red car."},
+			{Role: "user", Content: formatPrompt("How many doors?", nil)},
+		}, chatRequest.Messages)
+
+		_, _ = writer.Write([]byte(`{"model":"llava","message":{"role":"assistant","content":"Two"},"done":true,"done_reason":"stop","prompt_eval_count":700,"eval_count":2}`))
+	})
+
+	result, err := analyzer.Chat(context.Background(), testJPEGImages, []Message{
+		{Role: RoleUser, Text: "What is this?"},
+		{Role: RoleModel, Text: "A red car."},
+	}, "How many doors?", &Options{SystemInstruction: "Answer briefly."})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Two", result.Text())
+	assert.Equal(t, FinishReasonStop, result.FinishReason())
+	assert.Equal(t, Usage{PromptTokens: 700, CandidatesTokens: 2, TotalTokens: 702}, result.Usage)
+}
@@ -0,0 +1,337 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+// ProviderOpenAI is the provider name of the OpenAI-compatible analyzer
+const ProviderOpenAI = "openai-compatible"
+
+const openAIChatCompletionsPath = "/v1/chat/completions"
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIMessage struct {
+	Role    string              `json:"role"`
+	Content []openAIContentPart `json:"content"`
+}
+
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIStreamOptions asks for the usage in a last chunk of the stream without choices
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	MaxTokens      int32                 `json:"max_tokens,omitempty"`
+	Temperature    float32               `json:"temperature"`
+	TopP           *float32              `json:"top_p,omitempty"`
+	TopK           *int32                `json:"top_k,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	Seed           *int32                `json:"seed,omitempty"`
+	N              *int32                `json:"n,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Model string      `json:"model"`
+	Usage openAIUsage `json:"usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int32 `json:"prompt_tokens"`
+	CompletionTokens int32 `json:"completion_tokens"`
+	TotalTokens      int32 `json:"total_tokens"`
+}
+
+type openAIChatStreamResponse struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Model string       `json:"model"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// OpenAIAnalyzer is a concrete implementation of Analyzer using an OpenAI-compatible chat completions endpoint
+type OpenAIAnalyzer struct {
+	httpClient *http.Client
+	config     *configPkg.OpenAIConfig
+}
+
+var _ Analyzer = &OpenAIAnalyzer{}
+
+// NewOpenAIAnalyzer creates a new instance of OpenAIAnalyzer with the provided configuration
+func NewOpenAIAnalyzer(config *configPkg.OpenAIConfig) *OpenAIAnalyzer {
+	return &OpenAIAnalyzer{
+		httpClient: newHTTPClient(config.TimeoutSeconds),
+		config:     config,
+	}
+}
+
+// Analyze sends the images as base64 data URLs together with the prompt to the chat completions endpoint.
+// It returns every choice as a candidate or an error if the analysis fails.
+func (openAIAnalyzer *OpenAIAnalyzer) Analyze(ctx context.Context, images []Image, prompt string, options *Options) (*Result, error) {
+	return openAIAnalyzer.complete(ctx, openAIAnalyzer.newChatRequest(images, nil, prompt, options, false))
+}
+
+// Chat sends the history as previous chat messages followed by the prompt
+func (openAIAnalyzer *OpenAIAnalyzer) Chat(
+	ctx context.Context,
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+) (*Result, error) {
+	return openAIAnalyzer.complete(ctx, openAIAnalyzer.newChatRequest(images, history, prompt, options, false))
+}
+
+func (openAIAnalyzer *OpenAIAnalyzer) complete(ctx context.Context, chatRequest *openAIChatRequest) (*Result, error) {
+	var chatResponse openAIChatResponse
+	err := postJSON(
+		ctx,
+		openAIAnalyzer.httpClient,
+		openAIAnalyzer.endpoint(),
+		openAIAnalyzer.headers(),
+		chatRequest,
+		&chatResponse,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(chatResponse.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices")
+	}
+	result := &Result{
+		Candidates: make([]Candidate, 0, len(chatResponse.Choices)),
+		Model:      chatResponse.Model,
+		Usage: Usage{
+			PromptTokens:     chatResponse.Usage.PromptTokens,
+			CandidatesTokens: chatResponse.Usage.CompletionTokens,
+			TotalTokens:      chatResponse.Usage.TotalTokens,
+		},
+	}
+	for _, choice := range chatResponse.Choices {
+		result.Candidates = append(result.Candidates, Candidate{
+			Text:         choice.Message.Content,
+			FinishReason: finishReasonFromOpenAI(choice.FinishReason),
+		})
+	}
+	return result, nil
+}
+
+// AnalyzeStream requests a streamed chat completion and sends the content deltas of the first choice
+// as they arrive in the server-sent events. The usage is read from the last chunk of the stream.
+func (openAIAnalyzer *OpenAIAnalyzer) AnalyzeStream(
+	ctx context.Context,
+	images []Image,
+	prompt string,
+	options *Options,
+	send func(chunk string) error,
+) (*Result, error) {
+	var text strings.Builder
+	result := &Result{Model: options.modelName(openAIAnalyzer.config.ModelName)}
+	finishReason := FinishReasonUnspecified
+	chunks := 0
+	err := postStream(
+		ctx,
+		openAIAnalyzer.httpClient,
+		openAIAnalyzer.endpoint(),
+		openAIAnalyzer.headers(),
+		openAIAnalyzer.newChatRequest(images, nil, prompt, options, true),
+		func(line []byte) error {
+			data, ok := bytes.CutPrefix(line, []byte("data:"))
+			if !ok {
+				return nil
+			}
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				return errStreamDone
+			}
+			var streamResponse openAIChatStreamResponse
+			if err := json.Unmarshal(data, &streamResponse); err != nil {
+				return fmt.Errorf("failed to decode chat completions chunk: %v", err)
+			}
+			if streamResponse.Model != "" {
+				result.Model = streamResponse.Model
+			}
+			if usage := streamResponse.Usage; usage != nil {
+				result.Usage = Usage{
+					PromptTokens:     usage.PromptTokens,
+					CandidatesTokens: usage.CompletionTokens,
+					TotalTokens:      usage.TotalTokens,
+				}
+			}
+			if len(streamResponse.Choices) == 0 {
+				return nil
+			}
+			choice := streamResponse.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = finishReasonFromOpenAI(choice.FinishReason)
+			}
+			if choice.Delta.Content == "" {
+				return nil
+			}
+			text.WriteString(choice.Delta.Content)
+			chunks++
+			return send(choice.Delta.Content)
+		},
+	)
+	if err != nil {
+		return partialStreamResult(result, text.String(), chunks), err
+	}
+	result.Candidates = []Candidate{{Text: text.String(), FinishReason: finishReason}}
+	return result, nil
+}
+
+func (openAIAnalyzer *OpenAIAnalyzer) newChatRequest(
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+	stream bool,
+) *openAIChatRequest {
+	generation := options.generation()
+	messages := make([]openAIMessage, 0, len(history)+2)
+	if systemInstruction := options.systemInstruction(); systemInstruction != "" {
+		messages = append(messages, openAIMessage{
+			Role:    "system",
+			Content: []openAIContentPart{{Type: "text", Text: systemInstruction}},
+		})
+	}
+	for index, message := range newConversation(history, prompt, options) {
+		var messageImages []Image
+		if index == 0 {
+			messageImages = images
+		}
+		messages = append(messages, newOpenAIMessage(message.Role, message.Text, messageImages))
+	}
+	chatRequest := &openAIChatRequest{
+		Model:       options.modelName(openAIAnalyzer.config.ModelName),
+		Messages:    messages,
+		MaxTokens:   int32OrDefault(generation.MaxTokens, openAIAnalyzer.config.MaxTokens),
+		Temperature: float32OrDefault(generation.Temperature, openAIAnalyzer.config.Temperature),
+		TopP:        generation.TopP,
+		TopK:        generation.TopK,
+		Stop:        generation.StopSequences,
+		Seed:        generation.Seed,
+		Stream:      stream,
+	}
+	if stream {
+		chatRequest.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	} else {
+		chatRequest.N = generation.CandidateCount
+	}
+	if options.jsonOutput() {
+		chatRequest.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+		if options.JSONSchema != nil {
+			chatRequest.ResponseFormat = &openAIResponseFormat{
+				Type: "json_schema",
+				JSONSchema: &openAIJSONSchema{
+					Name:   "analysis_response",
+					Schema: options.JSONSchema,
+				},
+			}
+		}
+	}
+	return chatRequest
+}
+
+func newOpenAIMessage(role Role, text string, images []Image) openAIMessage {
+	openAIRole := "user"
+	if role == RoleModel {
+		openAIRole = "assistant"
+	}
+	content := []openAIContentPart{{Type: "text", Text: text}}
+	for _, image := range images {
+		dataURL := fmt.Sprintf("data:%s;base64,%s", image.MimeType, base64.StdEncoding.EncodeToString(image.Data))
+		content = append(content, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURL}})
+	}
+	return openAIMessage{Role: openAIRole, Content: content}
+}
+
+func (openAIAnalyzer *OpenAIAnalyzer) endpoint() string {
+	return strings.TrimRight(openAIAnalyzer.config.BaseURL, "/") + openAIChatCompletionsPath
+}
+
+func (openAIAnalyzer *OpenAIAnalyzer) headers() map[string]string {
+	headers := map[string]string{}
+	if openAIAnalyzer.config.APIKey != "" {
+		headers["Authorization"] = "Bearer " + openAIAnalyzer.config.APIKey
+	}
+	return headers
+}
+
+// SupportsDocuments reports false, the chat completions endpoint only accepts images
+func (openAIAnalyzer *OpenAIAnalyzer) SupportsDocuments() bool {
+	return false
+}
+
+// SupportsSeed reports true, the seed is sent with the chat completion request
+func (openAIAnalyzer *OpenAIAnalyzer) SupportsSeed() bool {
+	return true
+}
+
+// Close releases the idle connections kept by the HTTP client
+func (openAIAnalyzer *OpenAIAnalyzer) Close() error {
+	openAIAnalyzer.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// OpenAIAnalyzerFactory builds OpenAI-compatible analyzers from the application configuration
+type OpenAIAnalyzerFactory struct{}
+
+var _ AnalyzerFactoryer = &OpenAIAnalyzerFactory{}
+
+// Validate checks that the endpoint and model of the OpenAI-compatible provider are configured
+func (factory *OpenAIAnalyzerFactory) Validate(config *configPkg.Config) error {
+	if err := validateBaseURL("openai.base_url", config.OpenAI.BaseURL); err != nil {
+		return err
+	}
+	if config.OpenAI.ModelName == "" {
+		return fmt.Errorf("openai.model_name is required")
+	}
+	return nil
+}
+
+// Create builds a new OpenAIAnalyzer
+func (factory *OpenAIAnalyzerFactory) Create(config *configPkg.Config) (Analyzer, error) {
+	return NewOpenAIAnalyzer(&config.OpenAI), nil
+}
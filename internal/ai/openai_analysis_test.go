@@ -0,0 +1,283 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+func newTestOpenAIAnalyzer(t *testing.T, handler http.HandlerFunc) *OpenAIAnalyzer {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOpenAIAnalyzer(&configPkg.OpenAIConfig{
+		BaseURL:     server.URL,
+		APIKey:      "test-api-key",
+		ModelName:   "test-model",
+		MaxTokens:   256,
+		Temperature: 0.2,
+	})
+}
+
+func TestOpenAIAnalyzer_Analyze_Success(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "/v1/chat/completions", request.URL.Path)
+		assert.Equal(t, "Bearer test-api-key", request.Header.Get("Authorization"))
+
+		var chatRequest openAIChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		assert.Equal(t, "test-model", chatRequest.Model)
+		assert.Equal(t, int32(256), chatRequest.MaxTokens)
+		assert.Len(t, chatRequest.Messages, 1)
+		assert.Equal(t, formatPrompt("What is this?", nil), chatRequest.Messages[0].Content[0].Text)
+		assert.Equal(t, "data:image/png;base64,aW1hZ2U=", chatRequest.Messages[0].Content[1].ImageURL.URL)
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"# A cat"},"finish_reason":"stop"},{"message":{"role":"assistant","content":"# A"},"finish_reason":"length"}],"model":"test-model","usage":{"prompt_tokens":120,"completion_tokens":8,"total_tokens":128}}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "What is this?", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# A cat", result.Text())
+	assert.Equal(t, FinishReasonStop, result.FinishReason())
+	assert.Len(t, result.Candidates, 2)
+	assert.Equal(t, Candidate{Text: "# A", FinishReason: FinishReasonMaxTokens}, result.Candidates[1])
+	assert.Equal(t, "test-model", result.Model)
+	assert.Equal(t, Usage{PromptTokens: 120, CandidatesTokens: 8, TotalTokens: 128}, result.Usage)
+}
+
+func TestOpenAIAnalyzer_Analyze_RateLimited(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusTooManyRequests)
+		_, _ = writer.Write([]byte(`{"error":{"message":"slow down"}}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", nil)
+
+	assert.Nil(t, result)
+	analyzerErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorKindRateLimited, analyzerErr.Kind)
+	assert.Equal(t, "analyzer provider returned status 429: slow down", analyzerErr.Error())
+}
+
+func TestOpenAIAnalyzer_Analyze_ServerError(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "upstream model crashed", http.StatusBadGateway)
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", nil)
+
+	assert.Nil(t, result)
+	analyzerErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorKindUnavailable, analyzerErr.Kind)
+	assert.Equal(t, http.StatusBadGateway, analyzerErr.StatusCode)
+	assert.Equal(t, "upstream model crashed", analyzerErr.Message)
+}
+
+func TestOpenAIAnalyzer_Analyze_NoChoices(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`{"choices":[]}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", nil)
+
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "no response choices")
+}
+
+func TestOpenAIAnalyzerFactory_Validate(t *testing.T) {
+	factory := &OpenAIAnalyzerFactory{}
+
+	assert.EqualError(t, factory.Validate(&configPkg.Config{}), "openai.base_url is required")
+	assert.EqualError(
+		t,
+		factory.Validate(&configPkg.Config{OpenAI: configPkg.OpenAIConfig{BaseURL: "localhost:4000"}}),
+		"openai.base_url must be an http or https URL",
+	)
+	assert.EqualError(
+		t,
+		factory.Validate(&configPkg.Config{OpenAI: configPkg.OpenAIConfig{BaseURL: "http://localhost:4000"}}),
+		"openai.model_name is required",
+	)
+	assert.NoError(t, factory.Validate(&configPkg.Config{
+		OpenAI: configPkg.OpenAIConfig{BaseURL: "http://localhost:4000", ModelName: "model"},
+	}))
+}
+
+func TestOpenAIAnalyzer_AnalyzeStream_Success(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var chatRequest openAIChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		assert.True(t, chatRequest.Stream)
+		assert.Equal(t, &openAIStreamOptions{IncludeUsage: true}, chatRequest.StreamOptions)
+
+		writer.Header().Set("Content-Type", "text/event-stream")
+		_, _ = writer.Write([]byte("data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n"))
+		_, _ = writer.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"# A \"}}]}\n\n"))
+		_, _ = writer.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"cat\"},\"finish_reason\":\"stop\"}]}\n\n"))
+		_, _ = writer.Write([]byte(
+			"data: {\"model\":\"gpt-4o\",\"choices\":[],\"usage\":{\"prompt_tokens\":20,\"completion_tokens\":2,\"total_tokens\":22}}\n\n",
+		))
+		_, _ = writer.Write([]byte("data: [DONE]\n\n"))
+	})
+
+	var chunks []string
+	result, err := analyzer.AnalyzeStream(context.Background(), testPNGImages, "prompt", nil, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"# A ", "cat"}, chunks)
+	assert.Equal(t, "# A cat", result.Text())
+	assert.Equal(t, "gpt-4o", result.Model)
+	assert.Equal(t, FinishReasonStop, result.FinishReason())
+	assert.Equal(t, Usage{PromptTokens: 20, CandidatesTokens: 2, TotalTokens: 22}, result.Usage)
+}
+
+func TestOpenAIAnalyzer_AnalyzeStream_Cancelled(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/event-stream")
+		_, _ = writer.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"# A \"}}]}\n\n"))
+		_, _ = writer.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"cat\"}}]}\n\n"))
+		writer.(http.Flusher).Flush()
+		// the usage would only be reported after the last chunk
+		<-request.Context().Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var chunks []string
+	result, err := analyzer.AnalyzeStream(ctx, testPNGImages, "prompt", nil, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		if len(chunks) == 2 {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "# A cat", result.Text())
+	assert.Equal(t, FinishReasonUnspecified, result.FinishReason())
+	assert.Equal(t, Usage{CandidatesTokens: 2, TotalTokens: 2}, result.Usage)
+}
+
+func TestOpenAIAnalyzer_Analyze_JSONSchema(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var chatRequest openAIChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		assert.NotNil(t, chatRequest.ResponseFormat)
+		assert.Equal(t, "json_schema", chatRequest.ResponseFormat.Type)
+		assert.Equal(t, "analysis_response", chatRequest.ResponseFormat.JSONSchema.Name)
+		assert.Equal(t, "object", chatRequest.ResponseFormat.JSONSchema.Schema["type"])
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"choices":[{"message":{"content":"{\"total\":1}"},"finish_reason":"stop"}]}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", &Options{
+		OutputFormat: OutputFormatJSON,
+		JSONSchema:   map[string]interface{}{"type": "object"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"total":1}`, result.Text())
+}
+
+func TestOpenAIAnalyzer_Analyze_Overrides(t *testing.T) {
+	topP := float32(0.9)
+	seed := int32(42)
+	candidateCount := int32(2)
+	maxTokens := int32(64)
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var chatRequest map[string]interface{}
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		assert.Equal(t, float64(64), chatRequest["max_tokens"])
+		assert.InDelta(t, 0.2, chatRequest["temperature"], 0.0001)
+		assert.InDelta(t, 0.9, chatRequest["top_p"], 0.0001)
+		assert.Equal(t, []interface{}{"END"}, chatRequest["stop"])
+		assert.Equal(t, float64(42), chatRequest["seed"])
+		assert.Equal(t, float64(2), chatRequest["n"])
+		assert.NotContains(t, chatRequest, "top_k")
+		assert.Equal(t, "other-model", chatRequest["model"])
+		messages := chatRequest["messages"].([]interface{})
+		assert.Len(t, messages, 2)
+		assert.Equal(t, "system", messages[0].(map[string]interface{})["role"])
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"choices":[{"message":{"content":"caption"},"finish_reason":"stop"}]}`))
+	})
+
+	_, err := analyzer.Analyze(context.Background(), testPNGImages, "prompt", &Options{
+		Generation: GenerationParams{
+			MaxTokens:      &maxTokens,
+			TopP:           &topP,
+			StopSequences:  []string{"END"},
+			Seed:           &seed,
+			CandidateCount: &candidateCount,
+		},
+		Model:             "other-model",
+		SystemInstruction: "Write short captions.",
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestOpenAIAnalyzer_Analyze_MultipleImages(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var chatRequest openAIChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		content := chatRequest.Messages[0].Content
+		assert.Len(t, content, 3)
+		assert.Equal(t, "data:image/png;base64,YmVmb3Jl", content[1].ImageURL.URL)
+		assert.Equal(t, "data:image/jpeg;base64,YWZ0ZXI=", content[2].ImageURL.URL)
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"choices":[{"message":{"content":"The door is dented"},"finish_reason":"stop"}]}`))
+	})
+
+	result, err := analyzer.Analyze(context.Background(), []Image{
+		{Data: []byte("before"), MimeType: "image/png"},
+		{Data: []byte("after"), MimeType: "image/jpeg"},
+	}, "Compare these photos", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "The door is dented", result.Text())
+}
+
+func TestOpenAIAnalyzer_Chat_History(t *testing.T) {
+	analyzer := newTestOpenAIAnalyzer(t, func(writer http.ResponseWriter, request *http.Request) {
+		var chatRequest openAIChatRequest
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&chatRequest))
+		messages := chatRequest.Messages
+		assert.Len(t, messages, 3)
+		assert.Equal(t, "user", messages[0].Role)
+		assert.Equal(t, "What is this?", messages[0].Content[0].Text)
+		assert.Equal(t, "data:image/png;base64,aW1hZ2U=", messages[0].Content[1].ImageURL.URL)
+		assert.Equal(t, "assistant", messages[1].Role)
+		assert.Equal(t, []openAIContentPart{{Type: "text", Text: "A cat."}}, messages[1].Content)
+		assert.Equal(t, "user", messages[2].Role)
+		assert.Equal(t, []openAIContentPart{{Type: "text", Text: formatPrompt("What colour is it?", nil)}}, messages[2].Content)
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"choices":[{"message":{"content":"Black"},"finish_reason":"stop"}]}`))
+	})
+
+	result, err := analyzer.Chat(context.Background(), testPNGImages, []Message{
+		{Role: RoleUser, Text: "What is this?"},
+		{Role: RoleModel, Text: "A cat."},
+	}, "What colour is it?", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Black", result.Text())
+}
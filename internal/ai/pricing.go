@@ -0,0 +1,16 @@
+package ai
+
+import configPkg "qd-image-analysis-api/internal/config"
+
+const tokensPerMillion = 1000000
+
+// estimateCost computes the cost of the usage with the price of the model in the price table.
+// It returns zero when the model has no price configured.
+func estimateCost(prices map[string]configPkg.ModelPrice, model string, usage Usage) float64 {
+	price, ok := prices[model]
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.InputPerMillionTokens +
+		float64(usage.CandidatesTokens)*price.OutputPerMillionTokens) / tokensPerMillion
+}
@@ -0,0 +1,20 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+func TestEstimateCost(t *testing.T) {
+	prices := map[string]configPkg.ModelPrice{
+		"gemini": {InputPerMillionTokens: 1.25, OutputPerMillionTokens: 5},
+	}
+	usage := Usage{PromptTokens: 2000, CandidatesTokens: 1000, TotalTokens: 3000}
+
+	assert.InDelta(t, 0.0075, estimateCost(prices, "gemini", usage), 1e-9)
+	assert.Zero(t, estimateCost(prices, "unknown", usage))
+	assert.Zero(t, estimateCost(nil, "gemini", usage))
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+// ProviderVertex is the provider name of the Vertex AI analyzer
+const ProviderVertex = "vertex"
+
+// AnalyzerFactoryer knows how to validate the configuration of a provider and build its analyzer
+type AnalyzerFactoryer interface {
+	Validate(config *configPkg.Config) error
+	Create(config *configPkg.Config) (Analyzer, error)
+}
+
+// Registry keeps the analyzer factories indexed by provider name
+type Registry struct {
+	factories map[string]AnalyzerFactoryer
+}
+
+// NewRegistry creates an empty analyzer registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]AnalyzerFactoryer)}
+}
+
+// NewDefaultRegistry creates a registry with all the built-in providers registered
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register(ProviderVertex, &VertexAnalyzerFactory{})
+	registry.Register(ProviderOpenAI, &OpenAIAnalyzerFactory{})
+	registry.Register(ProviderOllama, &OllamaAnalyzerFactory{})
+	registry.Register(ProviderFake, &FakeAnalyzerFactory{})
+	return registry
+}
+
+// Register adds a factory under the given provider name, replacing any previous one
+func (registry *Registry) Register(provider string, factory AnalyzerFactoryer) {
+	registry.factories[strings.ToLower(provider)] = factory
+}
+
+// Providers returns the sorted names of the registered providers
+func (registry *Registry) Providers() []string {
+	providers := make([]string, 0, len(registry.factories))
+	for provider := range registry.factories {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Create validates the configuration of the configured provider and builds its analyzer.
+// When no provider is configured it falls back to Vertex AI.
+func (registry *Registry) Create(config *configPkg.Config) (Analyzer, error) {
+	provider := strings.ToLower(config.Provider)
+	if provider == "" {
+		provider = ProviderVertex
+	}
+	factory, ok := registry.factories[provider]
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown analyzer provider %q, available providers: %s",
+			config.Provider,
+			strings.Join(registry.Providers(), ", "),
+		)
+	}
+	if err := factory.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration for provider %q: %v", provider, err)
+	}
+	return factory.Create(config)
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configPkg "qd-image-analysis-api/internal/config"
+)
+
+type stubFactory struct {
+	analyzer    Analyzer
+	validateErr error
+}
+
+func (factory *stubFactory) Validate(config *configPkg.Config) error {
+	return factory.validateErr
+}
+
+func (factory *stubFactory) Create(config *configPkg.Config) (Analyzer, error) {
+	return factory.analyzer, nil
+}
+
+func TestRegistry_Create_RegisteredProvider(t *testing.T) {
+	fakeAnalyzer := NewFakeAnalyzer(&FakeFixtures{})
+	registry := NewRegistry()
+	registry.Register("Custom", &stubFactory{analyzer: fakeAnalyzer})
+
+	analyzer, err := registry.Create(&configPkg.Config{Provider: "custom"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, fakeAnalyzer, analyzer)
+}
+
+func TestRegistry_Create_UnknownProvider(t *testing.T) {
+	registry := NewDefaultRegistry()
+
+	analyzer, err := registry.Create(&configPkg.Config{Provider: "unknown"})
+
+	assert.Error(t, err)
+	assert.Nil(t, analyzer)
+	assert.Contains(t, err.Error(), "unknown analyzer provider \"unknown\"")
+	assert.Contains(t, err.Error(), ProviderVertex)
+}
+
+func TestRegistry_Create_InvalidConfig(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("custom", &stubFactory{validateErr: errors.New("missing endpoint")})
+
+	analyzer, err := registry.Create(&configPkg.Config{Provider: "custom"})
+
+	assert.Error(t, err)
+	assert.Nil(t, analyzer)
+	assert.Equal(t, "invalid configuration for provider \"custom\": missing endpoint", err.Error())
+}
+
+func TestRegistry_Create_DefaultsToVertex(t *testing.T) {
+	registry := NewDefaultRegistry()
+
+	analyzer, err := registry.Create(&configPkg.Config{})
+
+	assert.Error(t, err)
+	assert.Nil(t, analyzer)
+	assert.Equal(t, "invalid configuration for provider \"vertex\": vertex_ai.project_id is required", err.Error())
+}
+
+func TestVertexAnalyzerFactory_Validate_MissingCredentials(t *testing.T) {
+	factory := &VertexAnalyzerFactory{}
+
+	err := factory.Validate(&configPkg.Config{
+		VertexAI: configPkg.VertexAIConfig{
+			ProjectID:  "project",
+			Location:   "us-central1",
+			ModelName:  "gemini",
+			ConfigPath: "/does/not/exist.json",
+		},
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "vertex_ai.config_path is not accessible")
+}
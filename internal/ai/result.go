@@ -0,0 +1,104 @@
+package ai
+
+import "image"
+
+// FinishReason is the reason why the model stopped generating a candidate
+type FinishReason string
+
+const (
+	// FinishReasonUnspecified is used when the provider does not report why it stopped
+	FinishReasonUnspecified FinishReason = "UNSPECIFIED"
+	// FinishReasonStop is a natural stop point of the model or a provided stop sequence
+	FinishReasonStop FinishReason = "STOP"
+	// FinishReasonMaxTokens is used when the maximum number of output tokens was reached
+	FinishReasonMaxTokens FinishReason = "MAX_TOKENS"
+	// FinishReasonSafety is used when the response was flagged for safety reasons
+	FinishReasonSafety FinishReason = "SAFETY"
+	// FinishReasonRecitation is used when the response was flagged for unauthorized citations
+	FinishReasonRecitation FinishReason = "RECITATION"
+	// FinishReasonBlocked is used when the response was flagged for blocked or prohibited content
+	FinishReasonBlocked FinishReason = "BLOCKED"
+	// FinishReasonOther is used for any other reason reported by the provider
+	FinishReasonOther FinishReason = "OTHER"
+)
+
+// Candidate is one of the answers generated by the model
+type Candidate struct {
+	Text         string
+	FinishReason FinishReason
+}
+
+// Usage holds the number of tokens consumed by an analysis
+type Usage struct {
+	PromptTokens     int32
+	CandidatesTokens int32
+	TotalTokens      int32
+}
+
+// TileAnswer is the answer of the model about one of the tiles of an image analysed in tiling mode
+type TileAnswer struct {
+	// Label identifies the tile in the merged answer, such as R1C2, the thumbnail of the whole image is the overview
+	Label string
+	// Row and Column start at 1, both are zero for the overview
+	Row    int
+	Column int
+	// Bounds of the tile in the pixels of the analysed image
+	Bounds image.Rectangle
+	Text   string
+}
+
+// Result is the outcome of an analysis, holding every candidate returned by the model
+// together with the tokens it consumed and their estimated cost
+type Result struct {
+	Candidates    []Candidate
+	Model         string
+	Usage         Usage
+	EstimatedCost float64
+	// Tiles holds the answers about every tile in tiling mode, the candidates then hold the merged answer
+	Tiles []TileAnswer
+}
+
+// Text returns the text of the first candidate
+func (result *Result) Text() string {
+	if result == nil || len(result.Candidates) == 0 {
+		return ""
+	}
+	return result.Candidates[0].Text
+}
+
+// FinishReason returns the finish reason of the first candidate
+func (result *Result) FinishReason() FinishReason {
+	if result == nil || len(result.Candidates) == 0 {
+		return FinishReasonUnspecified
+	}
+	return result.Candidates[0].FinishReason
+}
+
+// partialStreamResult returns the result of a stream that failed after the model started answering, nil when it did not.
+// Providers report the usage when the stream ends, every streamed chunk is counted as a candidates token until they do.
+func partialStreamResult(result *Result, text string, chunks int) *Result {
+	if chunks == 0 && result.Usage.TotalTokens == 0 {
+		return nil
+	}
+	if result.Usage.TotalTokens == 0 {
+		result.Usage = Usage{CandidatesTokens: int32(chunks), TotalTokens: int32(chunks)}
+	}
+	result.Candidates = []Candidate{{Text: text, FinishReason: FinishReasonUnspecified}}
+	return result
+}
+
+// finishReasonFromOpenAI maps the finish reasons used by the OpenAI and Ollama APIs
+func finishReasonFromOpenAI(reason string) FinishReason {
+	switch reason {
+	case "":
+		return FinishReasonUnspecified
+	case "stop":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonMaxTokens
+	case "content_filter":
+		return FinishReasonSafety
+	default:
+		return FinishReasonOther
+	}
+}
@@ -3,8 +3,11 @@ package ai
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
 	configPkg "qd-image-analysis-api/internal/config"
@@ -27,28 +30,222 @@ func NewVertexAnalyzer(config *configPkg.VertexAIConfig) (*VertexAnalyzer, error
 	return &VertexAnalyzer{client: cli, config: config}, nil
 }
 
-// Analyze processes an image with a given prompt using Vertex AI's generative model.
-// It returns the model's response as a string or an error if the analysis fails.
-func (vertexAnalyzer *VertexAnalyzer) Analyze(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error) {
-	model := vertexAnalyzer.client.GenerativeModel(vertexAnalyzer.config.ModelName)
-	model.SetMaxOutputTokens(vertexAnalyzer.config.MaxTokens)
-	model.SetTemperature(vertexAnalyzer.config.Temperature)
-
-	img := genai.ImageData(mimeType, imageData)
-	txt := genai.Text(fmt.Sprintf("Please format your response as markdown. Here is the analysis request: %s", prompt))
+// Analyze processes the images with a given prompt using Vertex AI's generative model.
+// It returns every candidate with all of its text parts concatenated, or an error if the analysis fails.
+func (vertexAnalyzer *VertexAnalyzer) Analyze(ctx context.Context, images []Image, prompt string, options *Options) (*Result, error) {
+	model := vertexAnalyzer.newModel(options)
+	resp, err := model.GenerateContent(ctx, newVertexParts(images, prompt, options)...)
+	if err != nil {
+		return nil, err
+	}
+	return vertexAnalyzer.newResult(resp, options)
+}
 
-	resp, err := model.GenerateContent(ctx, img, txt)
+// Chat replays the history through a genai chat session before sending the prompt
+func (vertexAnalyzer *VertexAnalyzer) Chat(
+	ctx context.Context,
+	images []Image,
+	history []Message,
+	prompt string,
+	options *Options,
+) (*Result, error) {
+	chatSession := vertexAnalyzer.newModel(options).StartChat()
+	chatSession.History = newVertexHistory(images, history)
+	parts := []genai.Part{genai.Text(formatPrompt(prompt, options))}
+	if len(history) == 0 {
+		parts = newVertexParts(images, prompt, options)
+	}
+	resp, err := chatSession.SendMessage(ctx, parts...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return vertexAnalyzer.newResult(resp, options)
+}
+
+func (vertexAnalyzer *VertexAnalyzer) newResult(resp *genai.GenerateContentResponse, options *Options) (*Result, error) {
 	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no response candidates")
+		return nil, fmt.Errorf("no response candidates")
+	}
+	result := &Result{
+		Candidates: make([]Candidate, 0, len(resp.Candidates)),
+		Model:      options.modelName(vertexAnalyzer.config.ModelName),
 	}
-	contentPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		return "", fmt.Errorf("unexpected response format")
+	if resp.UsageMetadata != nil {
+		result.Usage = Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CandidatesTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+		result.EstimatedCost = estimateCost(vertexAnalyzer.config.Prices, result.Model, result.Usage)
 	}
-	return string(contentPart), nil
+	for _, candidate := range resp.Candidates {
+		result.Candidates = append(result.Candidates, Candidate{
+			Text:         vertexCandidateText(candidate),
+			FinishReason: finishReasonFromVertex(candidate.FinishReason),
+		})
+	}
+	return result, nil
+}
+
+// AnalyzeStream processes the images with a given prompt using Vertex AI's streaming API.
+// The text parts of the first candidate are sent as soon as the model produces them,
+// the usage is read from the last response of the stream.
+func (vertexAnalyzer *VertexAnalyzer) AnalyzeStream(
+	ctx context.Context,
+	images []Image,
+	prompt string,
+	options *Options,
+	send func(chunk string) error,
+) (*Result, error) {
+	model := vertexAnalyzer.newModel(options)
+	responses := model.GenerateContentStream(ctx, newVertexParts(images, prompt, options)...)
+	var text strings.Builder
+	result := &Result{Model: options.modelName(vertexAnalyzer.config.ModelName)}
+	finishReason := FinishReasonUnspecified
+	chunks := 0
+	for {
+		resp, err := responses.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return vertexAnalyzer.partialStreamResult(result, text.String(), chunks), err
+		}
+		if resp.UsageMetadata != nil {
+			result.Usage = Usage{
+				PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+				CandidatesTokens: resp.UsageMetadata.CandidatesTokenCount,
+				TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+			}
+		}
+		if len(resp.Candidates) == 0 {
+			continue
+		}
+		if reason := resp.Candidates[0].FinishReason; reason != genai.FinishReasonUnspecified {
+			finishReason = finishReasonFromVertex(reason)
+		}
+		if resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			chunk, ok := part.(genai.Text)
+			if !ok || chunk == "" {
+				continue
+			}
+			text.WriteString(string(chunk))
+			chunks++
+			if err := send(string(chunk)); err != nil {
+				return vertexAnalyzer.partialStreamResult(result, text.String(), chunks), err
+			}
+		}
+	}
+	result.Candidates = []Candidate{{Text: text.String(), FinishReason: finishReason}}
+	result.EstimatedCost = estimateCost(vertexAnalyzer.config.Prices, result.Model, result.Usage)
+	return result, nil
+}
+
+// partialStreamResult returns the result of a failed stream with the estimated cost of its usage
+func (vertexAnalyzer *VertexAnalyzer) partialStreamResult(result *Result, text string, chunks int) *Result {
+	partial := partialStreamResult(result, text, chunks)
+	if partial != nil {
+		partial.EstimatedCost = estimateCost(vertexAnalyzer.config.Prices, partial.Model, partial.Usage)
+	}
+	return partial
+}
+
+func (vertexAnalyzer *VertexAnalyzer) newModel(options *Options) *genai.GenerativeModel {
+	model := vertexAnalyzer.client.GenerativeModel(options.modelName(vertexAnalyzer.config.ModelName))
+	generation := options.generation()
+	model.SetMaxOutputTokens(int32OrDefault(generation.MaxTokens, vertexAnalyzer.config.MaxTokens))
+	model.SetTemperature(float32OrDefault(generation.Temperature, vertexAnalyzer.config.Temperature))
+	if candidateCount := int32OrDefault(generation.CandidateCount, vertexAnalyzer.config.CandidateCount); candidateCount > 1 {
+		model.SetCandidateCount(candidateCount)
+	}
+	if generation.TopP != nil {
+		model.SetTopP(*generation.TopP)
+	}
+	if generation.TopK != nil {
+		model.SetTopK(*generation.TopK)
+	}
+	model.StopSequences = generation.StopSequences
+	if systemInstruction := options.systemInstruction(); systemInstruction != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemInstruction)}}
+	}
+	if options.jsonOutput() {
+		model.ResponseMIMEType = "application/json"
+		if options.JSONSchema != nil {
+			model.ResponseSchema = newVertexSchema(options.JSONSchema)
+		}
+	}
+	return model
+}
+
+// newVertexParts builds one blob part per image or document followed by the prompt.
+// genai.ImageData is not used as it expects the format without the "image/" prefix of the mime type.
+func newVertexParts(images []Image, prompt string, options *Options) []genai.Part {
+	parts := make([]genai.Part, 0, len(images)+1)
+	for _, image := range images {
+		parts = append(parts, genai.Blob{MIMEType: image.MimeType, Data: image.Data})
+	}
+	return append(parts, genai.Text(formatPrompt(prompt, options)))
+}
+
+// newVertexHistory converts the conversation history into chat contents, attaching the images to the first message
+func newVertexHistory(images []Image, history []Message) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(history))
+	for index, message := range history {
+		content := &genai.Content{Role: string(message.Role)}
+		if index == 0 {
+			for _, image := range images {
+				content.Parts = append(content.Parts, genai.Blob{MIMEType: image.MimeType, Data: image.Data})
+			}
+		}
+		content.Parts = append(content.Parts, genai.Text(message.Text))
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+func vertexCandidateText(candidate *genai.Candidate) string {
+	if candidate.Content == nil {
+		return ""
+	}
+	var builder strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			builder.WriteString(string(text))
+		}
+	}
+	return builder.String()
+}
+
+func finishReasonFromVertex(reason genai.FinishReason) FinishReason {
+	switch reason {
+	case genai.FinishReasonUnspecified:
+		return FinishReasonUnspecified
+	case genai.FinishReasonStop:
+		return FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return FinishReasonMaxTokens
+	case genai.FinishReasonSafety, genai.FinishReasonSpii:
+		return FinishReasonSafety
+	case genai.FinishReasonRecitation:
+		return FinishReasonRecitation
+	case genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent:
+		return FinishReasonBlocked
+	default:
+		return FinishReasonOther
+	}
+}
+
+// SupportsDocuments reports true, Gemini models read PDF documents
+func (vertexAnalyzer *VertexAnalyzer) SupportsDocuments() bool {
+	return true
+}
+
+// SupportsSeed reports false, the generation config of the Vertex AI SDK has no seed
+func (vertexAnalyzer *VertexAnalyzer) SupportsSeed() bool {
+	return false
 }
 
 // Close closes the connection to the Vertex AI service.
@@ -56,3 +253,32 @@ func (vertexAnalyzer *VertexAnalyzer) Analyze(ctx context.Context, imageData []b
 func (vertexAnalyzer *VertexAnalyzer) Close() error {
 	return vertexAnalyzer.client.Close()
 }
+
+// VertexAnalyzerFactory builds Vertex AI analyzers from the application configuration
+type VertexAnalyzerFactory struct{}
+
+var _ AnalyzerFactoryer = &VertexAnalyzerFactory{}
+
+// Validate checks that the Vertex AI configuration is complete and the credentials file exists
+func (factory *VertexAnalyzerFactory) Validate(config *configPkg.Config) error {
+	vertexConfig := config.VertexAI
+	switch {
+	case vertexConfig.ProjectID == "":
+		return fmt.Errorf("vertex_ai.project_id is required")
+	case vertexConfig.Location == "":
+		return fmt.Errorf("vertex_ai.location is required")
+	case vertexConfig.ModelName == "":
+		return fmt.Errorf("vertex_ai.model_name is required")
+	case vertexConfig.ConfigPath == "":
+		return fmt.Errorf("vertex_ai.config_path is required")
+	}
+	if _, err := os.Stat(vertexConfig.ConfigPath); err != nil {
+		return fmt.Errorf("vertex_ai.config_path is not accessible: %v", err)
+	}
+	return nil
+}
+
+// Create builds a new VertexAnalyzer
+func (factory *VertexAnalyzerFactory) Create(config *configPkg.Config) (Analyzer, error) {
+	return NewVertexAnalyzer(&config.VertexAI)
+}
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVertexParts(t *testing.T) {
+	parts := newVertexParts([]Image{
+		{Data: []byte("before"), MimeType: "image/png"},
+		{Data: []byte("after"), MimeType: "image/jpeg"},
+	}, "Compare these photos", nil)
+
+	assert.Equal(t, []genai.Part{
+		genai.Blob{MIMEType: "image/png", Data: []byte("before")},
+		genai.Blob{MIMEType: "image/jpeg", Data: []byte("after")},
+		genai.Text(formatPrompt("Compare these photos", nil)),
+	}, parts)
+}
+
+func TestNewVertexHistory(t *testing.T) {
+	history := newVertexHistory(testPNGImages, []Message{
+		{Role: RoleUser, Text: "What is this?"},
+		{Role: RoleModel, Text: "A cat."},
+	})
+
+	assert.Equal(t, []*genai.Content{
+		{
+			Role: "user",
+			Parts: []genai.Part{
+				genai.Blob{MIMEType: "image/png", Data: []byte("image")},
+				genai.Text("What is this?"),
+			},
+		},
+		{Role: "model", Parts: []genai.Part{genai.Text("A cat.")}},
+	}, history)
+}
@@ -0,0 +1,95 @@
+package ai
+
+import "cloud.google.com/go/vertexai/genai"
+
+// newVertexSchema converts a JSON Schema document into the OpenAPI subset understood by Vertex AI.
+// Keywords Vertex AI does not support are ignored, the full schema is still enforced by the service.
+func newVertexSchema(document map[string]interface{}) *genai.Schema {
+	if document == nil {
+		return nil
+	}
+	schema := &genai.Schema{
+		Format:      stringValue(document["format"]),
+		Title:       stringValue(document["title"]),
+		Description: stringValue(document["description"]),
+	}
+	switch typeValue := document["type"].(type) {
+	case string:
+		schema.Type = vertexType(typeValue)
+	case []interface{}:
+		for _, item := range typeValue {
+			if item == "null" {
+				schema.Nullable = true
+				continue
+			}
+			schema.Type = vertexType(stringValue(item))
+		}
+	}
+	if items, ok := document["items"].(map[string]interface{}); ok {
+		schema.Items = newVertexSchema(items)
+	}
+	if properties, ok := document["properties"].(map[string]interface{}); ok {
+		schema.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if propertyDocument, ok := property.(map[string]interface{}); ok {
+				schema.Properties[name] = newVertexSchema(propertyDocument)
+			}
+		}
+	}
+	schema.Required = stringValues(document["required"])
+	schema.Enum = stringValues(document["enum"])
+	schema.MinItems = int64Value(document["minItems"])
+	schema.MaxItems = int64Value(document["maxItems"])
+	schema.MinLength = int64Value(document["minLength"])
+	schema.MaxLength = int64Value(document["maxLength"])
+	if minimum, ok := document["minimum"].(float64); ok {
+		schema.Minimum = minimum
+	}
+	if maximum, ok := document["maximum"].(float64); ok {
+		schema.Maximum = maximum
+	}
+	return schema
+}
+
+func vertexType(jsonType string) genai.Type {
+	switch jsonType {
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
+	case "object":
+		return genai.TypeObject
+	default:
+		return genai.TypeUnspecified
+	}
+}
+
+func stringValue(value interface{}) string {
+	text, _ := value.(string)
+	return text
+}
+
+func stringValues(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if text, ok := item.(string); ok {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+func int64Value(value interface{}) int64 {
+	number, _ := value.(float64)
+	return int64(number)
+}
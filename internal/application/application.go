@@ -2,15 +2,23 @@ package application
 
 import (
 	"fmt"
+	"time"
 
 	commonConfig "github.com/quadev-ltd/qd-common/pkg/config"
 	"github.com/quadev-ltd/qd-common/pkg/grpcserver"
 	"github.com/quadev-ltd/qd-common/pkg/log"
 
 	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/budget"
+	"qd-image-analysis-api/internal/caller"
 	"qd-image-analysis-api/internal/config"
 	grpcFactory "qd-image-analysis-api/internal/grpcserver"
+	"qd-image-analysis-api/internal/ratelimit"
+	"qd-image-analysis-api/internal/schema"
 	"qd-image-analysis-api/internal/service"
+	"qd-image-analysis-api/internal/session"
+	"qd-image-analysis-api/internal/templates"
 )
 
 // Applicationer defines the interface for the application's core functionality
@@ -26,10 +34,12 @@ type Application struct {
 	grpcServiceServer grpcserver.GRPCServicer
 	grpcServerAddress string
 	service           service.ImageAnalysisServicer
+	rateLimiter       ratelimit.RateLimiter
 }
 
-// NewApplication creates a new instance of the application with the provided configuration
-func NewApplication(config *config.Config, centralConfig *commonConfig.Config) (Applicationer, error) {
+// NewApplication creates a new instance of the application with the provided configuration,
+// the analyzer and the rate limiter created before a failing step are closed
+func NewApplication(config *config.Config, centralConfig *commonConfig.Config) (application Applicationer, err error) {
 	logFactory := log.NewLogFactory(config.Environment)
 	logger := logFactory.NewLogger()
 	if centralConfig.TLSEnabled {
@@ -38,12 +48,77 @@ func NewApplication(config *config.Config, centralConfig *commonConfig.Config) (
 		logger.Info("TLS is disabled")
 	}
 
-	aiAnalyser, err := ai.NewVertexAnalyzer(&config.VertexAI)
+	aiAnalyser, err := ai.NewDefaultRegistry().Create(config)
 	if err != nil {
 		logger.Error(err, "Failed to create AI analyzer")
 		return nil, err
 	}
-	imageAnalysisService := service.NewImageAnalysisService(aiAnalyser)
+	var rateLimiter ratelimit.RateLimiter
+	defer func() {
+		if err == nil {
+			return
+		}
+		if closeErr := aiAnalyser.Close(); closeErr != nil {
+			logger.Error(closeErr, "Failed to close AI analyzer")
+		}
+		if rateLimiter != nil {
+			if closeErr := rateLimiter.Close(); closeErr != nil {
+				logger.Error(closeErr, "Failed to close rate limiter")
+			}
+		}
+	}()
+	schemas, err := schema.LoadRegistry(config.Schemas.Directory)
+	if err != nil {
+		logger.Error(err, "Failed to load JSON schemas")
+		return nil, err
+	}
+	promptTemplates, err := templates.LoadRegistry(config.Templates.Directory)
+	if err != nil {
+		logger.Error(err, "Failed to load prompt templates")
+		return nil, err
+	}
+	imageAnalysisService := service.NewImageAnalysisService(
+		aiAnalyser,
+		schemas,
+		promptTemplates,
+		config,
+	)
+	sessionService := service.NewSessionService(
+		imageAnalysisService,
+		session.NewMemoryStore(
+			time.Duration(config.Sessions.TTLSeconds)*time.Second,
+			session.Limits{
+				MaxSessions:         config.Sessions.MaxSessions,
+				MaxSessionsPerOwner: config.Sessions.MaxSessionsPerClient,
+			},
+		),
+		&config.Sessions,
+	)
+
+	limiter, err := ratelimit.New(&config.RateLimit)
+	if err != nil {
+		logger.Error(err, "Failed to create the rate limiter")
+		return nil, err
+	}
+	rateLimiter = limiter
+
+	var budgets *budget.Tracker
+	if config.Budgets.Enabled {
+		budgets, err = budget.New(&config.Budgets)
+		if err != nil {
+			logger.Error(err, "Failed to create the budget tracker")
+			return nil, err
+		}
+	}
+
+	var authenticator auth.Authenticator
+	if config.Auth.Enabled {
+		authenticator, err = auth.New(&config.Auth)
+		if err != nil {
+			logger.Error(err, "Failed to create the authenticator")
+			return nil, err
+		}
+	}
 
 	grpcServerAddress := fmt.Sprintf(
 		"%s:%s",
@@ -54,27 +129,50 @@ func NewApplication(config *config.Config, centralConfig *commonConfig.Config) (
 	grpcServiceServer, err := (&grpcFactory.Factory{}).Create(
 		grpcServerAddress,
 		imageAnalysisService,
+		sessionService,
 		logFactory,
 		centralConfig.TLSEnabled,
+		grpcFactory.Options{
+			MaxUploadSize: config.Upload.MaxImageSizeBytes,
+			RateLimiter:   rateLimiter,
+			Budgets:       budgets,
+			APIKeys:       caller.NewAPIKeys(configuredClients(config)...),
+			Authenticator: authenticator,
+		},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return New(grpcServiceServer, grpcServerAddress, imageAnalysisService, logger), nil
+	return New(grpcServiceServer, grpcServerAddress, imageAnalysisService, rateLimiter, logger), nil
 }
 
-// New creates a new Application instance with the provided dependencies
+// configuredClients returns the identities of the clients the rate limits and the budgets are configured for
+func configuredClients(serviceConfig *config.Config) []config.ClientIdentityConfig {
+	clients := make([]config.ClientIdentityConfig, 0, len(serviceConfig.RateLimit.Clients)+len(serviceConfig.Budgets.Clients))
+	for _, client := range serviceConfig.RateLimit.Clients {
+		clients = append(clients, client.ClientIdentityConfig)
+	}
+	for _, client := range serviceConfig.Budgets.Clients {
+		clients = append(clients, client.ClientIdentityConfig)
+	}
+	return clients
+}
+
+// New creates a new Application instance with the provided dependencies,
+// the rate limiter is closed with the application and may be nil
 func New(
 	grpcServiceServer grpcserver.GRPCServicer,
 	grpcServerAddress string,
 	service service.ImageAnalysisServicer,
+	rateLimiter ratelimit.RateLimiter,
 	logger log.Loggerer,
 ) Applicationer {
 	return &Application{
 		grpcServiceServer: grpcServiceServer,
 		grpcServerAddress: grpcServerAddress,
 		service:           service,
+		rateLimiter:       rateLimiter,
 		logger:            logger,
 	}
 }
@@ -107,6 +205,11 @@ func (application *Application) Close() {
 	if err != nil {
 		application.logger.Error(err, "Failed to close service")
 	}
+	if application.rateLimiter != nil {
+		if err := application.rateLimiter.Close(); err != nil {
+			application.logger.Error(err, "Failed to close rate limiter")
+		}
+	}
 	application.logger.Info("gRPC server closed")
 }
 
@@ -1,22 +1,28 @@
 package application
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
-	commonPB "github.com/quadev-ltd/qd-common/pb/gen/go/pb_image_analysis"
 	commonConfig "github.com/quadev-ltd/qd-common/pkg/config"
 	commonLog "github.com/quadev-ltd/qd-common/pkg/log"
 	commonTLS "github.com/quadev-ltd/qd-common/pkg/tls"
 	"github.com/stretchr/testify/assert"
 
+	"qd-image-analysis-api/internal/ai"
 	aiMock "qd-image-analysis-api/internal/ai/mock"
 	"qd-image-analysis-api/internal/config"
 	grpcFactory "qd-image-analysis-api/internal/grpcserver"
 	"qd-image-analysis-api/internal/service"
+	"qd-image-analysis-api/internal/session"
+	pb "qd-image-analysis-api/pb/gen/go/pb_image_analysis"
 )
 
 func isServerUp(address string, tlsEnabled bool) bool {
@@ -64,7 +70,7 @@ func setUpTestEnvironment(t *testing.T) *EnvironmentParams {
 
 	controller := gomock.NewController(t)
 	mockAiAnalyser := aiMock.NewMockAnalyzer(controller)
-	imageAnalysisService := service.NewImageAnalysisService(mockAiAnalyser)
+	imageAnalysisService := service.NewImageAnalysisService(mockAiAnalyser, nil, nil, nil)
 
 	// Create the application using the factory pattern similar to NewApplication
 	application := createTestApplication(&testConfig, &mockCentralConfig, imageAnalysisService)
@@ -99,11 +105,20 @@ func createTestApplication(config *config.Config, centralConfig *commonConfig.Co
 	grpcServiceServer, _ := (&grpcFactory.Factory{}).Create(
 		grpcServerAddress,
 		imageAnalysisService,
+		service.NewSessionService(imageAnalysisService, session.NewMemoryStore(time.Minute, session.Limits{}), nil),
 		logFactory,
 		centralConfig.TLSEnabled,
+		grpcFactory.Options{MaxUploadSize: config.Upload.MaxImageSizeBytes},
 	)
 
-	return New(grpcServiceServer, grpcServerAddress, imageAnalysisService, logger)
+	return New(grpcServiceServer, grpcServerAddress, imageAnalysisService, nil, logger)
+}
+
+func newTestPNG(t *testing.T) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 32, 32))))
+	return buffer.Bytes()
 }
 
 func TestImageAnalysisEndpoints(t *testing.T) {
@@ -120,16 +135,16 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		defer connection.Close()
 
 		ctxWithCorrelationID := commonLog.AddCorrelationIDToOutgoingContext(context.Background(), correlationID)
-		grpcClient := commonPB.NewImageAnalysisServiceClient(connection)
+		grpcClient := pb.NewImageAnalysisServiceClient(connection)
 
-		testImageData := []byte("test-image-data")
+		testImageData := newTestPNG(t)
 		testPrompt := "What is in this image?"
 		testMimeType := "image/png"
 		expectedResponse := "# Image Analysis\n\nThis is a test image containing sample data."
 
 		envParams.MockAIAnalyser.EXPECT().
-			Analyze(gomock.Any(), testImageData, testMimeType, testPrompt).
-			Return(expectedResponse, nil)
+			Analyze(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
+			Return(&ai.Result{Candidates: []ai.Candidate{{Text: expectedResponse, FinishReason: ai.FinishReasonStop}}}, nil)
 
 		envParams.MockAIAnalyser.EXPECT().
 			Close().
@@ -137,7 +152,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 
 		response, err := grpcClient.ProcessImageAndPrompt(
 			ctxWithCorrelationID,
-			&commonPB.ImagePromptRequest{
+			&pb.ImagePromptRequest{
 				ImageData: testImageData,
 				Prompt:    testPrompt,
 				MimeType:  testMimeType,
@@ -147,6 +162,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, response)
 		assert.Equal(t, expectedResponse, response.ResponseToPrompt)
+		assert.Equal(t, "STOP", response.FinishReason)
 
 		envParams.Application.Close()
 		envParams.Controller.Finish()
@@ -163,7 +179,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		defer connection.Close()
 
 		ctxWithCorrelationID := commonLog.AddCorrelationIDToOutgoingContext(context.Background(), correlationID)
-		grpcClient := commonPB.NewImageAnalysisServiceClient(connection)
+		grpcClient := pb.NewImageAnalysisServiceClient(connection)
 
 		testImageData := []byte("")
 		testMimeType := "image/png"
@@ -175,7 +191,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 
 		response, err := grpcClient.ProcessImageAndPrompt(
 			ctxWithCorrelationID,
-			&commonPB.ImagePromptRequest{
+			&pb.ImagePromptRequest{
 				ImageData: testImageData,
 				Prompt:    testPrompt,
 				MimeType:  testMimeType,
@@ -201,9 +217,9 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		defer connection.Close()
 
 		ctxWithCorrelationID := commonLog.AddCorrelationIDToOutgoingContext(context.Background(), correlationID)
-		grpcClient := commonPB.NewImageAnalysisServiceClient(connection)
+		grpcClient := pb.NewImageAnalysisServiceClient(connection)
 
-		testImageData := []byte("test-image-data")
+		testImageData := newTestPNG(t)
 		testMimeType := "image/gif" // Invalid mime type
 		testPrompt := "What is in this image?"
 
@@ -213,7 +229,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 
 		response, err := grpcClient.ProcessImageAndPrompt(
 			ctxWithCorrelationID,
-			&commonPB.ImagePromptRequest{
+			&pb.ImagePromptRequest{
 				ImageData: testImageData,
 				Prompt:    testPrompt,
 				MimeType:  testMimeType,
@@ -240,9 +256,9 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		defer connection.Close()
 
 		ctxWithCorrelationID := commonLog.AddCorrelationIDToOutgoingContext(context.Background(), correlationID)
-		grpcClient := commonPB.NewImageAnalysisServiceClient(connection)
+		grpcClient := pb.NewImageAnalysisServiceClient(connection)
 
-		testImageData := []byte("test-image-data")
+		testImageData := newTestPNG(t)
 		testMimeType := "image/png"
 		testPrompt := ""
 
@@ -252,7 +268,7 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 
 		response, err := grpcClient.ProcessImageAndPrompt(
 			ctxWithCorrelationID,
-			&commonPB.ImagePromptRequest{
+			&pb.ImagePromptRequest{
 				ImageData: testImageData,
 				Prompt:    testPrompt,
 				MimeType:  testMimeType,
@@ -266,4 +282,64 @@ func TestImageAnalysisEndpoints(t *testing.T) {
 		envParams.Application.Close()
 		envParams.Controller.Finish()
 	})
+
+	t.Run("ProcessImageAndPromptStream_Success", func(t *testing.T) {
+		envParams := setUpTestEnvironment(t)
+
+		connection, err := commonTLS.CreateGRPCConnection(
+			envParams.Application.GetGRPCServerAddress(),
+			envParams.CentralConfig.TLSEnabled,
+		)
+		assert.NoError(t, err)
+		defer connection.Close()
+
+		ctxWithCorrelationID := commonLog.AddCorrelationIDToOutgoingContext(context.Background(), correlationID)
+		grpcClient := pb.NewImageAnalysisServiceClient(connection)
+
+		testImageData := newTestPNG(t)
+		testPrompt := "What is in this image?"
+		testMimeType := "image/png"
+
+		envParams.MockAIAnalyser.EXPECT().
+			AnalyzeStream(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, _ *ai.Options, send func(chunk string) error) (*ai.Result, error) {
+				if err := send("# Image Analysis\n\n"); err != nil {
+					return nil, err
+				}
+				if err := send("This is a test image."); err != nil {
+					return nil, err
+				}
+				return &ai.Result{
+					Candidates: []ai.Candidate{{Text: "# Image Analysis\n\nThis is a test image.", FinishReason: ai.FinishReasonStop}},
+				}, nil
+			})
+
+		envParams.MockAIAnalyser.EXPECT().
+			Close().
+			Return(nil)
+
+		stream, err := grpcClient.ProcessImageAndPromptStream(
+			ctxWithCorrelationID,
+			&pb.ImagePromptRequest{
+				ImageData: testImageData,
+				Prompt:    testPrompt,
+				MimeType:  testMimeType,
+			},
+		)
+		assert.NoError(t, err)
+
+		var chunks []string
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			chunks = append(chunks, response.ResponseChunk)
+		}
+		assert.Equal(t, []string{"# Image Analysis\n\n", "This is a test image."}, chunks)
+
+		envParams.Application.Close()
+		envParams.Controller.Finish()
+	})
 }
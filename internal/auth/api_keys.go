@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+const (
+	// APIKeyMetadataKey is the metadata key of the API key
+	APIKeyMetadataKey = "x-api-key"
+	// APIKeyPrefix starts every generated API key so that leaked keys are easy to recognise
+	APIKeyPrefix = "qdk_"
+	// hashAlgorithm prefixes the stored hashes so that the algorithm can change without breaking the stored keys
+	hashAlgorithm = "sha256"
+)
+
+// GenerateAPIKey returns a new API key made of its public ID and a random secret
+func GenerateAPIKey() (string, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s_%s", APIKeyPrefix, hex.EncodeToString(id), hex.EncodeToString(secret)), nil
+}
+
+// HashAPIKey returns the ID of the API key and the salted hash of its secret to store.
+// The secrets are random, so a fast hash is enough to keep them from being recovered from the store.
+func HashAPIKey(key string) (string, string, error) {
+	id, secret, err := splitAPIKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", err
+	}
+	return id, fmt.Sprintf("%s:%s:%s", hashAlgorithm, hex.EncodeToString(salt), hex.EncodeToString(hashSecret(salt, secret))), nil
+}
+
+func splitAPIKey(key string) (string, string, error) {
+	id, secret, found := strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), "_")
+	if !strings.HasPrefix(key, APIKeyPrefix) || !found || id == "" || secret == "" {
+		return "", "", errors.New("the API key is malformed")
+	}
+	return id, secret, nil
+}
+
+func hashSecret(salt []byte, secret string) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, salt...), secret...))
+	return hash[:]
+}
+
+// storedAPIKey is an API key of the store with its parsed hash
+type storedAPIKey struct {
+	config.APIKeyConfig
+	salt []byte
+	hash []byte
+}
+
+// APIKeyAuthenticator is an implementation of Authenticator checking the API keys of the callers
+// against the salted hashes of the store. The caller gets the tenant, the scopes and the rate limit tier of its key.
+type APIKeyAuthenticator struct {
+	keys map[string]*storedAPIKey
+}
+
+var _ Authenticator = &APIKeyAuthenticator{}
+
+// NewAPIKeyAuthenticator creates a new instance of APIKeyAuthenticator with the keys of the configuration and of its file.
+// It returns an error when the file cannot be read or a key is malformed or registered twice.
+func NewAPIKeyAuthenticator(apiKeys *config.APIKeysConfig) (*APIKeyAuthenticator, error) {
+	keys := apiKeys.Keys
+	if apiKeys.File != "" {
+		data, err := os.ReadFile(apiKeys.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the API key file: %w", err)
+		}
+		var fileKeys []config.APIKeyConfig
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse the API key file %s: %w", apiKeys.File, err)
+		}
+		keys = append(append([]config.APIKeyConfig{}, keys...), fileKeys...)
+	}
+
+	authenticator := &APIKeyAuthenticator{keys: map[string]*storedAPIKey{}}
+	for _, key := range keys {
+		if key.ID == "" || key.Tenant == "" {
+			return nil, fmt.Errorf("the API key %q has no ID or tenant", key.ID)
+		}
+		if _, ok := authenticator.keys[key.ID]; ok {
+			return nil, fmt.Errorf("the API key %q is registered twice", key.ID)
+		}
+		parts := strings.Split(key.Hash, ":")
+		if len(parts) != 3 || parts[0] != hashAlgorithm {
+			return nil, fmt.Errorf("the hash of the API key %q is malformed", key.ID)
+		}
+		salt, saltErr := hex.DecodeString(parts[1])
+		hash, hashErr := hex.DecodeString(parts[2])
+		if saltErr != nil || hashErr != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("the hash of the API key %q is malformed", key.ID)
+		}
+		authenticator.keys[key.ID] = &storedAPIKey{APIKeyConfig: key, salt: salt, hash: hash}
+	}
+	return authenticator, nil
+}
+
+// Authenticate checks the API key of the x-api-key metadata and returns the tenant, the scopes and the tier of the key
+func (authenticator *APIKeyAuthenticator) Authenticate(_ context.Context, md metadata.MD) (*caller.Principal, error) {
+	values := md.Get(APIKeyMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return nil, ErrNoCredentials
+	}
+	key := values[0]
+	id, secret, err := splitAPIKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key: %w", err)
+	}
+	stored, ok := authenticator.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("invalid API key: the API key %q is not registered", id)
+	}
+	if subtle.ConstantTimeCompare(hashSecret(stored.salt, secret), stored.hash) != 1 {
+		return nil, fmt.Errorf("invalid API key: the secret of the API key %q does not match", id)
+	}
+	return &caller.Principal{
+		Identity: caller.Identity{Kind: caller.KindAPIKey, Value: key},
+		Scopes:   stored.Scopes,
+		Tenant:   stored.Tenant,
+		Tier:     stored.Tier,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+func newTestAPIKey(t *testing.T, tenant string, scopes []string, tier string) (string, config.APIKeyConfig) {
+	t.Helper()
+	key, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	id, hash, err := HashAPIKey(key)
+	assert.NoError(t, err)
+	return key, config.APIKeyConfig{ID: id, Hash: hash, Tenant: tenant, Scopes: scopes, Tier: tier}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+
+	id, hash, err := HashAPIKey(key)
+	assert.NoError(t, err)
+	_, otherHash, err := HashAPIKey(key)
+	assert.NoError(t, err)
+
+	assert.Len(t, id, 16)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix+id+"_"))
+	assert.True(t, strings.HasPrefix(hash, "sha256:"))
+	assert.NotContains(t, hash, strings.TrimPrefix(key, APIKeyPrefix+id+"_"))
+	assert.NotEqual(t, hash, otherHash, "every hash is salted")
+
+	_, _, err = HashAPIKey("secret")
+	assert.EqualError(t, err, "the API key is malformed")
+}
+
+func TestAPIKeyAuthenticator_Authenticate(t *testing.T) {
+	configuredKey, configuredEntry := newTestAPIKey(t, "batch", []string{"images:analyze"}, "premium")
+	fileKey, fileEntry := newTestAPIKey(t, "partner", nil, "")
+	filePath := filepath.Join(t.TempDir(), "api_keys.json")
+	data, err := json.Marshal([]config.APIKeyConfig{fileEntry})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filePath, data, 0o600))
+
+	authenticator, err := NewAPIKeyAuthenticator(&config.APIKeysConfig{
+		File: filePath,
+		Keys: []config.APIKeyConfig{configuredEntry},
+	})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		md                metadata.MD
+		expectedPrincipal *caller.Principal
+		expectedError     string
+	}{
+		{
+			name: "Success_ConfiguredKey",
+			md:   metadata.Pairs("x-api-key", configuredKey),
+			expectedPrincipal: &caller.Principal{
+				Identity: caller.Identity{Kind: caller.KindAPIKey, Value: configuredKey},
+				Scopes:   []string{"images:analyze"},
+				Tenant:   "batch",
+				Tier:     "premium",
+			},
+		},
+		{
+			name: "Success_FileKey",
+			md:   metadata.Pairs("x-api-key", fileKey),
+			expectedPrincipal: &caller.Principal{
+				Identity: caller.Identity{Kind: caller.KindAPIKey, Value: fileKey},
+				Tenant:   "partner",
+			},
+		},
+		{
+			name:          "Error_NoCredentials",
+			md:            metadata.Pairs("authorization", "Bearer token"),
+			expectedError: ErrNoCredentials.Error(),
+		},
+		{
+			name:          "Error_Malformed",
+			md:            metadata.Pairs("x-api-key", "secret"),
+			expectedError: "invalid API key: the API key is malformed",
+		},
+		{
+			name:          "Error_NotRegistered",
+			md:            metadata.Pairs("x-api-key", "qdk_0000000000000000_secret"),
+			expectedError: `invalid API key: the API key "0000000000000000" is not registered`,
+		},
+		{
+			name:          "Error_WrongSecret",
+			md:            metadata.Pairs("x-api-key", APIKeyPrefix+configuredEntry.ID+"_secret"),
+			expectedError: `invalid API key: the secret of the API key "` + configuredEntry.ID + `" does not match`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), testCase.md)
+
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+				assert.Nil(t, principal)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedPrincipal, principal)
+		})
+	}
+}
+
+func TestNewAPIKeyAuthenticator_Errors(t *testing.T) {
+	_, entry := newTestAPIKey(t, "batch", nil, "")
+	corruptPath := filepath.Join(t.TempDir(), "api_keys.json")
+	assert.NoError(t, os.WriteFile(corruptPath, []byte("{"), 0o600))
+
+	testCases := []struct {
+		name          string
+		apiKeys       *config.APIKeysConfig
+		expectedError string
+	}{
+		{
+			name:          "Error_MissingFile",
+			apiKeys:       &config.APIKeysConfig{File: filepath.Join(t.TempDir(), "missing.json")},
+			expectedError: "failed to read the API key file",
+		},
+		{
+			name:          "Error_CorruptFile",
+			apiKeys:       &config.APIKeysConfig{File: corruptPath},
+			expectedError: "failed to parse the API key file " + corruptPath,
+		},
+		{
+			name:          "Error_NoTenant",
+			apiKeys:       &config.APIKeysConfig{Keys: []config.APIKeyConfig{{ID: entry.ID, Hash: entry.Hash}}},
+			expectedError: `the API key "` + entry.ID + `" has no ID or tenant`,
+		},
+		{
+			name:          "Error_Duplicate",
+			apiKeys:       &config.APIKeysConfig{Keys: []config.APIKeyConfig{entry, entry}},
+			expectedError: `the API key "` + entry.ID + `" is registered twice`,
+		},
+		{
+			name:          "Error_MalformedHash",
+			apiKeys:       &config.APIKeysConfig{Keys: []config.APIKeyConfig{{ID: entry.ID, Hash: "md5:00:00", Tenant: "batch"}}},
+			expectedError: `the hash of the API key "` + entry.ID + `" is malformed`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewAPIKeyAuthenticator(testCase.apiKeys)
+
+			assert.ErrorContains(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// ErrNoCredentials is returned when the metadata of a request carries no credentials the authenticator accepts
+var ErrNoCredentials = errors.New("the request carries no credentials")
+
+// Authenticator authenticates the callers by the credentials in the metadata of their requests
+type Authenticator interface {
+	// Authenticate returns the authenticated caller, ErrNoCredentials when there are no credentials
+	// and another error when they are invalid
+	Authenticate(ctx context.Context, md metadata.MD) (*caller.Principal, error)
+}
+
+// New creates the authenticators of the configuration, a caller is authenticated by the first of them
+// finding credentials in its metadata.
+// It returns an error when neither bearer tokens nor API keys are configured.
+func New(auth *config.AuthConfig) (Authenticator, error) {
+	var authenticators chain
+	jwt := &auth.JWT
+	if jwt.JWKSFile != "" || jwt.JWKSURL != "" || len(jwt.PublicKeyFiles) > 0 {
+		jwtAuthenticator, err := NewJWTAuthenticator(jwt)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+	if auth.APIKeys.File != "" || len(auth.APIKeys.Keys) > 0 {
+		apiKeyAuthenticator, err := NewAPIKeyAuthenticator(&auth.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, apiKeyAuthenticator)
+	}
+	if len(authenticators) == 0 {
+		return nil, errors.New("authentication is enabled without bearer token keys or API keys")
+	}
+	return authenticators, nil
+}
+
+// chain authenticates the callers by the first authenticator finding credentials in their metadata
+type chain []Authenticator
+
+// Authenticate returns the caller authenticated by the first authenticator finding credentials
+func (authenticators chain) Authenticate(ctx context.Context, md metadata.MD) (*caller.Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(ctx, md)
+		if !errors.Is(err, ErrNoCredentials) {
+			return principal, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksPath, newJWKS(t, newJWK(t, "rsa-1", &rsaKey.PublicKey)), 0o600))
+	apiKey, apiKeyEntry := newTestAPIKey(t, "batch", nil, "")
+
+	authenticator, err := New(&config.AuthConfig{
+		Enabled: true,
+		JWT:     config.JWTConfig{JWKSFile: jwksPath},
+		APIKeys: config.APIKeysConfig{Keys: []config.APIKeyConfig{apiKeyEntry}},
+	})
+	assert.NoError(t, err)
+
+	principal, err := authenticator.Authenticate(context.Background(), bearer(signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims(nil))))
+	assert.NoError(t, err)
+	assert.Equal(t, caller.KindToken, principal.Identity.Kind)
+
+	principal, err = authenticator.Authenticate(context.Background(), metadata.Pairs("x-api-key", apiKey))
+	assert.NoError(t, err)
+	assert.Equal(t, "batch", principal.Tenant)
+
+	// invalid credentials are rejected without trying the next authenticator
+	_, err = authenticator.Authenticate(context.Background(), metadata.Pairs("authorization", "Bearer token", "x-api-key", apiKey))
+	assert.ErrorContains(t, err, "invalid bearer token")
+
+	_, err = authenticator.Authenticate(context.Background(), metadata.MD{})
+	assert.ErrorIs(t, err, ErrNoCredentials)
+
+	_, err = New(&config.AuthConfig{Enabled: true})
+	assert.EqualError(t, err, "authentication is enabled without bearer token keys or API keys")
+}
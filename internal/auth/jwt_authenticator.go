@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/metadata"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// authorizationMetadataKey is the metadata key of the bearer token
+const authorizationMetadataKey = "authorization"
+
+// signingMethods are the algorithms accepted in the tokens, symmetric algorithms are refused
+// so that a public key can never be used as a shared secret
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// scopes reads the scopes of a token, granted either as a space separated string or as an array
+type scopes []string
+
+// UnmarshalJSON parses a space separated string or an array of strings
+func (tokenScopes *scopes) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*tokenScopes = strings.Fields(value)
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return errors.New("the scopes are neither a string nor an array of strings")
+	}
+	*tokenScopes = values
+	return nil
+}
+
+// tokenClaims are the claims read from the tokens, the scopes are granted by the scope or the scp claim
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope scopes `json:"scope"`
+	Scp   scopes `json:"scp"`
+}
+
+// JWTAuthenticator is an implementation of Authenticator validating the bearer JSON Web Tokens of the callers
+// against the configured public keys. The caller is identified by the subject of its token.
+type JWTAuthenticator struct {
+	keys       *keySet
+	remoteKeys *remoteKeySet
+	parser     *jwt.Parser
+}
+
+var _ Authenticator = &JWTAuthenticator{}
+
+// NewJWTAuthenticator creates a new instance of JWTAuthenticator loading the keys of the configuration.
+// It returns an error when no keys are configured or they cannot be loaded.
+func NewJWTAuthenticator(jwtConfig *config.JWTConfig) (*JWTAuthenticator, error) {
+	if jwtConfig.JWKSFile == "" && jwtConfig.JWKSURL == "" && len(jwtConfig.PublicKeyFiles) == 0 {
+		return nil, errors.New("no JWKS file, JWKS URL or public key file is configured to validate the tokens")
+	}
+	keys := newKeySet()
+	if jwtConfig.JWKSFile != "" {
+		data, err := os.ReadFile(jwtConfig.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the JWKS file: %w", err)
+		}
+		fileKeys, err := parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+		keys.merge(fileKeys)
+	}
+	for _, path := range jwtConfig.PublicKeyFiles {
+		key, err := loadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys.add("", key)
+	}
+
+	authenticator := &JWTAuthenticator{keys: keys}
+	if jwtConfig.JWKSURL != "" {
+		authenticator.remoteKeys = newRemoteKeySet(
+			jwtConfig.JWKSURL,
+			time.Duration(jwtConfig.RefreshIntervalSeconds)*time.Second,
+		)
+		if _, err := authenticator.remoteKeys.get(context.Background(), ""); err != nil {
+			return nil, err
+		}
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Duration(jwtConfig.LeewaySeconds) * time.Second),
+	}
+	if jwtConfig.Issuer != "" {
+		options = append(options, jwt.WithIssuer(jwtConfig.Issuer))
+	}
+	if jwtConfig.Audience != "" {
+		options = append(options, jwt.WithAudience(jwtConfig.Audience))
+	}
+	authenticator.parser = jwt.NewParser(options...)
+	return authenticator, nil
+}
+
+// Authenticate validates the bearer token of the authorization metadata and returns the subject and the scopes it grants
+func (authenticator *JWTAuthenticator) Authenticate(ctx context.Context, md metadata.MD) (*caller.Principal, error) {
+	tokenString, ok := bearerToken(md)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	claims := &tokenClaims{}
+	_, err := authenticator.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return authenticator.verificationKeys(ctx, keyID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid bearer token: the token has no subject")
+	}
+	return &caller.Principal{
+		Identity: caller.Identity{Kind: caller.KindToken, Value: claims.Subject},
+		Scopes:   append(claims.Scope, claims.Scp...),
+	}, nil
+}
+
+// verificationKeys returns the key of the key ID or, when it is missing or unknown, the keys without an ID
+func (authenticator *JWTAuthenticator) verificationKeys(ctx context.Context, keyID string) (interface{}, error) {
+	keys := newKeySet()
+	keys.merge(authenticator.keys)
+	var refreshErr error
+	if authenticator.remoteKeys != nil {
+		var remoteKeys *keySet
+		remoteKeys, refreshErr = authenticator.remoteKeys.get(ctx, keyID)
+		keys.merge(remoteKeys)
+	}
+	if key, ok := keys.byID[keyID]; ok && keyID != "" {
+		return key, nil
+	}
+	if len(keys.anonymous) == 0 {
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+	verificationKeys := make([]jwt.VerificationKey, 0, len(keys.anonymous))
+	for _, key := range keys.anonymous {
+		verificationKeys = append(verificationKeys, key)
+	}
+	return jwt.VerificationKeySet{Keys: verificationKeys}, nil
+}
+
+// bearerToken returns the token of the bearer authorization metadata
+func bearerToken(md metadata.MD) (string, bool) {
+	for _, authorization := range md.Get(authorizationMetadataKey) {
+		scheme, token, found := strings.Cut(authorization, " ")
+		if found && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// newJWK returns the JSON Web Key of a public key
+func newJWK(t *testing.T, keyID string, key interface{}) map[string]string {
+	t.Helper()
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"kid": keyID,
+			"n":   encodeBase64URL(publicKey.N.Bytes()),
+			"e":   encodeBase64URL(big.NewInt(int64(publicKey.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		return map[string]string{
+			"kty": "EC",
+			"kid": keyID,
+			"crv": publicKey.Curve.Params().Name,
+			"x":   encodeBase64URL(publicKey.X.Bytes()),
+			"y":   encodeBase64URL(publicKey.Y.Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": keyID, "crv": "Ed25519", "x": encodeBase64URL(publicKey)}
+	}
+	t.Fatalf("unsupported key %T", key)
+	return nil
+}
+
+func newJWKS(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	edPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	encryptionKey := newJWK(t, "encryption", &rsaKey.PublicKey)
+	encryptionKey["use"] = "enc"
+
+	keys, err := parseJWKS(newJWKS(t,
+		newJWK(t, "rsa", &rsaKey.PublicKey),
+		newJWK(t, "ec", &ecKey.PublicKey),
+		newJWK(t, "", edPublicKey),
+		encryptionKey,
+		map[string]string{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+	))
+
+	assert.NoError(t, err)
+	assert.Len(t, keys.byID, 2)
+	assert.True(t, rsaKey.PublicKey.Equal(keys.byID["rsa"]))
+	assert.True(t, ecKey.PublicKey.Equal(keys.byID["ec"]))
+	assert.Len(t, keys.anonymous, 1)
+	assert.True(t, edPublicKey.Equal(keys.anonymous[0]))
+}
+
+func TestParseJWKS_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		jwks          string
+		expectedError string
+	}{
+		{
+			name:          "Error_NotJSON",
+			jwks:          "keys",
+			expectedError: "failed to parse the JSON Web Key Set",
+		},
+		{
+			name:          "Error_MissingModulus",
+			jwks:          `{"keys":[{"kty":"RSA","kid":"rsa","e":"AQAB"}]}`,
+			expectedError: `failed to parse the key "rsa" of the JSON Web Key Set: missing key parameter`,
+		},
+		{
+			name:          "Error_UnsupportedCurve",
+			jwks:          `{"keys":[{"kty":"EC","kid":"ec","crv":"P-192","x":"AQ","y":"AQ"}]}`,
+			expectedError: `failed to parse the key "ec" of the JSON Web Key Set: unsupported curve "P-192"`,
+		},
+		{
+			name:          "Error_PointNotOnCurve",
+			jwks:          `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+			expectedError: `failed to parse the key "ec" of the JSON Web Key Set: the point is not on the curve`,
+		},
+		{
+			name:          "Error_Ed25519Size",
+			jwks:          `{"keys":[{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"AQ"}]}`,
+			expectedError: `failed to parse the key "ed" of the JSON Web Key Set: the Ed25519 key has an invalid size`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := parseJWKS([]byte(testCase.jwks))
+
+			assert.ErrorContains(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestLoadPublicKeyFile(t *testing.T) {
+	directory := t.TempDir()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	publicKeyPath := filepath.Join(directory, "public.pem")
+	assert.NoError(t, os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	notPEMPath := filepath.Join(directory, "public.txt")
+	assert.NoError(t, os.WriteFile(notPEMPath, []byte("public key"), 0o600))
+
+	key, err := loadPublicKeyFile(publicKeyPath)
+	assert.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	_, err = loadPublicKeyFile(notPEMPath)
+	assert.EqualError(t, err, "the public key "+notPEMPath+" is not PEM encoded")
+
+	_, err = loadPublicKeyFile(filepath.Join(directory, "missing.pem"))
+	assert.ErrorContains(t, err, "failed to read the public key")
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRefreshInterval is how often the JWKS URL is fetched again when no interval is configured
+	DefaultRefreshInterval = time.Hour
+	// unknownKeyRefreshInterval throttles the fetches caused by tokens signed by unknown keys,
+	// so that forged key IDs cannot flood the issuer
+	unknownKeyRefreshInterval = time.Minute
+	// maxJWKSSize bounds the size of the fetched JSON Web Key Set
+	maxJWKSSize = 1 << 20
+)
+
+// remoteKeySet keeps the keys of a JSON Web Key Set URL, refreshing them every refresh interval
+// and when a token is signed by an unknown key so that rotated keys are picked up.
+// The keys fetched last are kept while the URL is unavailable, failed fetches are retried after a minute.
+type remoteKeySet struct {
+	mutex           sync.Mutex
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	keys            *keySet
+	fetchedAt       time.Time
+	failed          bool
+	now             func() time.Time
+}
+
+func newRemoteKeySet(url string, refreshInterval time.Duration) *remoteKeySet {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	return &remoteKeySet{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: refreshInterval,
+		keys:            newKeySet(),
+		now:             time.Now,
+	}
+}
+
+// get returns the keys, refreshing them first when they are stale or do not hold the key ID
+func (remoteKeys *remoteKeySet) get(ctx context.Context, keyID string) (*keySet, error) {
+	remoteKeys.mutex.Lock()
+	defer remoteKeys.mutex.Unlock()
+
+	sinceFetch := remoteKeys.now().Sub(remoteKeys.fetchedAt)
+	_, known := remoteKeys.keys.byID[keyID]
+	retry := remoteKeys.failed || (keyID != "" && !known)
+	if sinceFetch >= remoteKeys.refreshInterval || (retry && sinceFetch >= unknownKeyRefreshInterval) {
+		remoteKeys.fetchedAt = remoteKeys.now()
+		err := remoteKeys.refresh(ctx)
+		remoteKeys.failed = err != nil
+		if err != nil {
+			return remoteKeys.keys, err
+		}
+	}
+	return remoteKeys.keys, nil
+}
+
+// refresh fetches the keys, it is called with the mutex held
+func (remoteKeys *remoteKeySet) refresh(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteKeys.url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := remoteKeys.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the JSON Web Key Set: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch the JSON Web Key Set: %s", response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+	if err != nil {
+		return fmt.Errorf("failed to fetch the JSON Web Key Set: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	remoteKeys.keys = keys
+	return nil
+}
@@ -0,0 +1,84 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"qd-image-analysis-api/internal/config"
+)
+
+const (
+	// StoreMemory keeps the usage in memory, it is lost when the service restarts
+	StoreMemory = "memory"
+	// StoreFile keeps the usage in a JSON file
+	StoreFile = "file"
+)
+
+// Usage is the number of tokens and the estimated cost consumed by a tenant
+type Usage struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"`
+}
+
+// Storer keeps the usage of every tenant per period, a period being a day or a month
+type Storer interface {
+	// Add adds the usage to the usage of the tenant in every period, the periods are the current ones
+	// so that the stores may drop the usage of the periods that are over
+	Add(ctx context.Context, tenant string, periods []string, usage Usage) error
+	// Get returns the usage of the tenant in the period, a zero usage when it has consumed nothing
+	Get(ctx context.Context, tenant string, period string) (Usage, error)
+}
+
+// New creates a tracker keeping the usage in the configured store
+func New(budgets *config.BudgetsConfig) (*Tracker, error) {
+	var store Storer
+	switch budgets.Store {
+	case "", StoreMemory:
+		store = NewMemoryStore()
+	case StoreFile:
+		fileStore, err := NewFileStore(budgets.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	default:
+		return nil, fmt.Errorf("unknown budget store %q", budgets.Store)
+	}
+	return NewTracker(store, budgets)
+}
+
+// usageTable is the usage indexed by tenant and period
+type usageTable map[string]map[string]Usage
+
+func (table usageTable) add(tenant string, periods []string, usage Usage) {
+	tenantUsage, ok := table[tenant]
+	if !ok {
+		tenantUsage = map[string]Usage{}
+		table[tenant] = tenantUsage
+	}
+	for _, period := range periods {
+		periodUsage := tenantUsage[period]
+		periodUsage.Tokens += usage.Tokens
+		periodUsage.Cost += usage.Cost
+		tenantUsage[period] = periodUsage
+	}
+}
+
+// prune drops the usage of the periods other than the current ones and the tenants left without usage
+func (table usageTable) prune(current []string) {
+	for tenant, tenantUsage := range table {
+		for period := range tenantUsage {
+			if !slices.Contains(current, period) {
+				delete(tenantUsage, period)
+			}
+		}
+		if len(tenantUsage) == 0 {
+			delete(table, tenant)
+		}
+	}
+}
+
+func (table usageTable) get(tenant string, period string) Usage {
+	return table[tenant][period]
+}
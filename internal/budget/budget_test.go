@@ -0,0 +1,23 @@
+package budget
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tracker, err := New(&config.BudgetsConfig{})
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryStore{}, tracker.store)
+
+	tracker, err = New(&config.BudgetsConfig{Store: StoreFile, FilePath: filepath.Join(t.TempDir(), "budgets.json")})
+	assert.NoError(t, err)
+	assert.IsType(t, &FileStore{}, tracker.store)
+
+	_, err = New(&config.BudgetsConfig{Store: "redis"})
+	assert.EqualError(t, err, `unknown budget store "redis"`)
+}
@@ -0,0 +1,89 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is an implementation of Storer keeping the usage in a JSON file, so that it survives restarts.
+// The file is read once when the store is created and written again whenever usage is added,
+// it must not be shared by several instances of the service.
+type FileStore struct {
+	mutex sync.Mutex
+	path  string
+	usage usageTable
+}
+
+var _ Storer = &FileStore{}
+
+// NewFileStore creates a new instance of FileStore with the usage of the file, which is created when it is missing
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.New("the file path of the budget store is not configured")
+	}
+	fileStore := &FileStore{path: path, usage: usageTable{}}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fileStore, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read the budget usage: %v", err)
+	}
+	if err := json.Unmarshal(data, &fileStore.usage); err != nil {
+		return nil, fmt.Errorf("failed to parse the budget usage of %s: %v", path, err)
+	}
+	return fileStore, nil
+}
+
+// Add adds the usage to the usage of the tenant in every period and writes the file
+// without the usage of the periods that are over, so that it does not grow with every past day
+func (fileStore *FileStore) Add(ctx context.Context, tenant string, periods []string, usage Usage) error {
+	fileStore.mutex.Lock()
+	defer fileStore.mutex.Unlock()
+
+	fileStore.usage.prune(periods)
+	fileStore.usage.add(tenant, periods, usage)
+	return fileStore.save()
+}
+
+// Get returns the usage of the tenant in the period
+func (fileStore *FileStore) Get(ctx context.Context, tenant string, period string) (Usage, error) {
+	fileStore.mutex.Lock()
+	defer fileStore.mutex.Unlock()
+
+	return fileStore.usage.get(tenant, period), nil
+}
+
+// save writes the usage to a temporary file renamed over the file, so that a crash cannot leave it truncated
+func (fileStore *FileStore) save() error {
+	data, err := json.Marshal(fileStore.usage)
+	if err != nil {
+		return err
+	}
+	directory := filepath.Dir(fileStore.path)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create the budget usage directory: %v", err)
+	}
+	temporary, err := os.CreateTemp(directory, filepath.Base(fileStore.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write the budget usage: %v", err)
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(data); err != nil {
+		temporary.Close()
+		return fmt.Errorf("failed to write the budget usage: %v", err)
+	}
+	if err := temporary.Close(); err != nil {
+		return fmt.Errorf("failed to write the budget usage: %v", err)
+	}
+	if err := os.Rename(temporary.Name(), fileStore.path); err != nil {
+		return fmt.Errorf("failed to write the budget usage: %v", err)
+	}
+	return nil
+}
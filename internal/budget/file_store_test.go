@@ -0,0 +1,73 @@
+package budget
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SurvivesRestarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data", "budgets.json")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, store.Add(ctx, "tenant", []string{"day:2024-01-01", "month:2024-01"}, Usage{Tokens: 100, Cost: 0.5}))
+
+		restarted, err := NewFileStore(path)
+		assert.NoError(t, err)
+		usage, err := restarted.Get(ctx, "tenant", "month:2024-01")
+		assert.NoError(t, err)
+		assert.Equal(t, Usage{Tokens: 100, Cost: 0.5}, usage)
+
+		assert.NoError(t, restarted.Add(ctx, "tenant", []string{"day:2024-01-01", "month:2024-01"}, Usage{Tokens: 20}))
+		usage, err = restarted.Get(ctx, "tenant", "day:2024-01-01")
+		assert.NoError(t, err)
+		assert.Equal(t, Usage{Tokens: 120, Cost: 0.5}, usage)
+		entries, err := os.ReadDir(filepath.Dir(path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Success_PrunesPastPeriods", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "budgets.json")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, store.Add(ctx, "yesterday", []string{"day:2024-01-01", "month:2024-01"}, Usage{Tokens: 100}))
+		assert.NoError(t, store.Add(ctx, "today", []string{"day:2024-01-02", "month:2024-01"}, Usage{Tokens: 20}))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"yesterday": {"month:2024-01": {"tokens": 100, "cost": 0}},
+			"today": {"day:2024-01-02": {"tokens": 20, "cost": 0}, "month:2024-01": {"tokens": 20, "cost": 0}}
+		}`, string(data))
+
+		assert.NoError(t, store.Add(ctx, "today", []string{"day:2024-02-01", "month:2024-02"}, Usage{Tokens: 5}))
+
+		data, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"today": {"day:2024-02-01": {"tokens": 5, "cost": 0}, "month:2024-02": {"tokens": 5, "cost": 0}}}`, string(data))
+	})
+
+	t.Run("Error_NoPath", func(t *testing.T) {
+		_, err := NewFileStore("")
+
+		assert.EqualError(t, err, "the file path of the budget store is not configured")
+	})
+
+	t.Run("Error_Corrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "budgets.json")
+		assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		_, err := NewFileStore(path)
+
+		assert.ErrorContains(t, err, "failed to parse the budget usage of "+path)
+	})
+}
@@ -0,0 +1,37 @@
+package budget
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-process implementation of Storer, the usage is lost when the service restarts
+type MemoryStore struct {
+	mutex sync.Mutex
+	usage usageTable
+}
+
+var _ Storer = &MemoryStore{}
+
+// NewMemoryStore creates a new instance of MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: usageTable{}}
+}
+
+// Add adds the usage to the usage of the tenant in every period and drops the usage of the periods that are over
+func (memoryStore *MemoryStore) Add(ctx context.Context, tenant string, periods []string, usage Usage) error {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	memoryStore.usage.prune(periods)
+	memoryStore.usage.add(tenant, periods, usage)
+	return nil
+}
+
+// Get returns the usage of the tenant in the period
+func (memoryStore *MemoryStore) Get(ctx context.Context, tenant string, period string) (Usage, error) {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	return memoryStore.usage.get(tenant, period), nil
+}
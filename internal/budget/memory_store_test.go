@@ -0,0 +1,31 @@
+package budget
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	assert.NoError(t, store.Add(ctx, "tenant", []string{"day:2024-01-01", "month:2024-01"}, Usage{Tokens: 100, Cost: 0.5}))
+	assert.NoError(t, store.Add(ctx, "tenant", []string{"day:2024-01-02", "month:2024-01"}, Usage{Tokens: 50, Cost: 0.25}))
+	assert.NoError(t, store.Add(ctx, "other", []string{"day:2024-01-02", "month:2024-01"}, Usage{Tokens: 10}))
+
+	usage, err := store.Get(ctx, "tenant", "month:2024-01")
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{Tokens: 150, Cost: 0.75}, usage)
+	usage, err = store.Get(ctx, "tenant", "day:2024-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{Tokens: 50, Cost: 0.25}, usage)
+	// the previous day is over
+	usage, err = store.Get(ctx, "tenant", "day:2024-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{}, usage)
+	usage, err = store.Get(ctx, "unknown", "day:2024-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{}, usage)
+}
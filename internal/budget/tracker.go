@@ -0,0 +1,158 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// ExhaustedError is returned when a budget of the tenant is spent
+type ExhaustedError struct {
+	Tenant string
+	// Budget is the spent budget, such as daily tokens
+	Budget string
+	// ResetsAt is when the spent budget is available again
+	ResetsAt time.Time
+}
+
+// Error returns the error message
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("the %s budget of tenant %s is spent until %s", e.Budget, e.Tenant, e.ResetsAt.Format(time.RFC3339))
+}
+
+// Period is the usage of a tenant in the current day or month and its limits, zero limits are unbounded
+type Period struct {
+	Usage      Usage
+	TokenLimit int64
+	CostLimit  float64
+	ResetsAt   time.Time
+}
+
+// RemainingTokens returns the tokens left in the period, zero once they are spent or when they are unbounded
+func (period Period) RemainingTokens() int64 {
+	return max(period.TokenLimit-period.Usage.Tokens, 0)
+}
+
+// RemainingCost returns the cost left in the period, zero once it is spent or when it is unbounded
+func (period Period) RemainingCost() float64 {
+	return max(period.CostLimit-period.Usage.Cost, 0)
+}
+
+// Status is the usage of a tenant in the current day and month
+type Status struct {
+	Tenant  string
+	Daily   Period
+	Monthly Period
+}
+
+// Tracker records the tokens and the estimated cost consumed by the tenants and checks them against their budgets.
+// The usage of a request is only known once it completes, so requests started before a budget is spent may exceed it.
+type Tracker struct {
+	store         Storer
+	defaultLimits config.BudgetLimitsConfig
+	tenants       map[string]config.BudgetLimitsConfig
+	clientTenants map[string]string
+	now           func() time.Time
+}
+
+// NewTracker creates a new instance of Tracker keeping the usage in the store.
+// It returns an error when a configured client has no API key, token subject, subject or address.
+func NewTracker(store Storer, budgets *config.BudgetsConfig) (*Tracker, error) {
+	tracker := &Tracker{
+		store:         store,
+		defaultLimits: budgets.Default,
+		tenants:       budgets.Tenants,
+		clientTenants: map[string]string{},
+		now:           time.Now,
+	}
+	for _, client := range budgets.Clients {
+		identity, ok := caller.FromConfig(client.ClientIdentityConfig)
+		if !ok {
+			return nil, fmt.Errorf("a client of the tenant %q has no API key, token subject, subject or address", client.Tenant)
+		}
+		tracker.clientTenants[identity.Key()] = client.Tenant
+	}
+	return tracker, nil
+}
+
+// TenantOf returns the tenant of the client, clients without a configured tenant are tenants on their own
+func (tracker *Tracker) TenantOf(identity caller.Identity) string {
+	if tenant, ok := tracker.clientTenants[identity.Key()]; ok {
+		return tenant
+	}
+	return identity.String()
+}
+
+// Check returns an ExhaustedError when a budget of the tenant is spent,
+// the monthly budgets are checked first as they reset last
+func (tracker *Tracker) Check(ctx context.Context, tenant string) error {
+	status, err := tracker.Status(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	budgets := []struct {
+		name  string
+		spent bool
+		reset time.Time
+	}{
+		{"monthly token", status.Monthly.TokenLimit > 0 && status.Monthly.RemainingTokens() == 0, status.Monthly.ResetsAt},
+		{"monthly cost", status.Monthly.CostLimit > 0 && status.Monthly.RemainingCost() == 0, status.Monthly.ResetsAt},
+		{"daily token", status.Daily.TokenLimit > 0 && status.Daily.RemainingTokens() == 0, status.Daily.ResetsAt},
+		{"daily cost", status.Daily.CostLimit > 0 && status.Daily.RemainingCost() == 0, status.Daily.ResetsAt},
+	}
+	for _, budget := range budgets {
+		if budget.spent {
+			return &ExhaustedError{Tenant: tenant, Budget: budget.name, ResetsAt: budget.reset}
+		}
+	}
+	return nil
+}
+
+// Record adds the usage of a request to the usage of the tenant in the current day and month
+func (tracker *Tracker) Record(ctx context.Context, tenant string, usage Usage) error {
+	day, month := periodsOf(tracker.now())
+	return tracker.store.Add(ctx, tenant, []string{day, month}, usage)
+}
+
+// Status returns the usage of the tenant in the current day and month with its limits
+func (tracker *Tracker) Status(ctx context.Context, tenant string) (*Status, error) {
+	now := tracker.now().UTC()
+	day, month := periodsOf(now)
+	dailyUsage, err := tracker.store.Get(ctx, tenant, day)
+	if err != nil {
+		return nil, err
+	}
+	monthlyUsage, err := tracker.store.Get(ctx, tenant, month)
+	if err != nil {
+		return nil, err
+	}
+	limits, ok := tracker.tenants[tenant]
+	if !ok {
+		limits = tracker.defaultLimits
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return &Status{
+		Tenant: tenant,
+		Daily: Period{
+			Usage:      dailyUsage,
+			TokenLimit: limits.DailyTokens,
+			CostLimit:  limits.DailyCost,
+			ResetsAt:   today.AddDate(0, 0, 1),
+		},
+		Monthly: Period{
+			Usage:      monthlyUsage,
+			TokenLimit: limits.MonthlyTokens,
+			CostLimit:  limits.MonthlyCost,
+			ResetsAt:   time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, nil
+}
+
+// periodsOf returns the day and the month of the time in UTC
+func periodsOf(now time.Time) (string, string) {
+	now = now.UTC()
+	return "day:" + now.Format(time.DateOnly), "month:" + now.Format("2006-01")
+}
@@ -0,0 +1,152 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+var testBudgets = &config.BudgetsConfig{
+	Default: config.BudgetLimitsConfig{DailyTokens: 1000, MonthlyTokens: 5000, DailyCost: 1, MonthlyCost: 10},
+	Tenants: map[string]config.BudgetLimitsConfig{
+		"partner":   {DailyTokens: 10000, MonthlyCost: 2},
+		"unbounded": {},
+	},
+	Clients: []config.BudgetClientConfig{
+		{ClientIdentityConfig: config.ClientIdentityConfig{APIKey: "partner-key"}, Tenant: "partner"},
+		{ClientIdentityConfig: config.ClientIdentityConfig{Subject: "CN=partner"}, Tenant: "partner"},
+	},
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	tracker, err := NewTracker(NewMemoryStore(), testBudgets)
+	assert.NoError(t, err)
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestTracker_TenantOf(t *testing.T) {
+	tracker, _ := newTestTracker(t)
+
+	assert.Equal(t, "partner", tracker.TenantOf(caller.Identity{Kind: caller.KindAPIKey, Value: "partner-key"}))
+	assert.Equal(t, "partner", tracker.TenantOf(caller.Identity{Kind: caller.KindSubject, Value: "CN=partner"}))
+	assert.Equal(t, "address:10.0.0.1", tracker.TenantOf(caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"}))
+}
+
+func TestTracker_Check(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name             string
+		tenant           string
+		usage            Usage
+		expectedBudget   string
+		expectedResetsAt time.Time
+	}{
+		{
+			name:   "Success_WithinBudgets",
+			tenant: "address:10.0.0.1",
+			usage:  Usage{Tokens: 999, Cost: 0.99},
+		},
+		{
+			name:             "Error_DailyTokens",
+			tenant:           "address:10.0.0.1",
+			usage:            Usage{Tokens: 1000},
+			expectedBudget:   "daily token",
+			expectedResetsAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "Error_DailyCost",
+			tenant:           "address:10.0.0.1",
+			usage:            Usage{Tokens: 10, Cost: 1.5},
+			expectedBudget:   "daily cost",
+			expectedResetsAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "Error_MonthlyFirst",
+			tenant:           "partner",
+			usage:            Usage{Tokens: 10000, Cost: 2},
+			expectedBudget:   "monthly cost",
+			expectedResetsAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "Success_Unbounded",
+			tenant: "unbounded",
+			usage:  Usage{Tokens: 1000000, Cost: 1000},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tracker, _ := newTestTracker(t)
+			assert.NoError(t, tracker.Record(ctx, testCase.tenant, testCase.usage))
+
+			err := tracker.Check(ctx, testCase.tenant)
+
+			if testCase.expectedBudget == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var exhausted *ExhaustedError
+			assert.ErrorAs(t, err, &exhausted)
+			assert.Equal(t, &ExhaustedError{
+				Tenant:   testCase.tenant,
+				Budget:   testCase.expectedBudget,
+				ResetsAt: testCase.expectedResetsAt,
+			}, exhausted)
+		})
+	}
+}
+
+func TestTracker_Status(t *testing.T) {
+	ctx := context.Background()
+	tracker, now := newTestTracker(t)
+
+	assert.NoError(t, tracker.Record(ctx, "address:10.0.0.1", Usage{Tokens: 400, Cost: 0.25}))
+	*now = now.Add(24 * time.Hour)
+	assert.NoError(t, tracker.Record(ctx, "address:10.0.0.1", Usage{Tokens: 100, Cost: 0.5}))
+
+	status, err := tracker.Status(ctx, "address:10.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{
+		Tenant: "address:10.0.0.1",
+		Daily: Period{
+			Usage:      Usage{Tokens: 100, Cost: 0.5},
+			TokenLimit: 1000,
+			CostLimit:  1,
+			ResetsAt:   time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+		},
+		Monthly: Period{
+			Usage:      Usage{Tokens: 100, Cost: 0.5},
+			TokenLimit: 5000,
+			CostLimit:  10,
+			ResetsAt:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, status)
+	assert.Equal(t, int64(900), status.Daily.RemainingTokens())
+	assert.Equal(t, 0.5, status.Daily.RemainingCost())
+}
+
+func TestPeriod_Remaining(t *testing.T) {
+	spent := Period{Usage: Usage{Tokens: 150, Cost: 3}, TokenLimit: 100, CostLimit: 2}
+	unbounded := Period{Usage: Usage{Tokens: 150, Cost: 3}}
+
+	assert.Equal(t, int64(0), spent.RemainingTokens())
+	assert.Equal(t, float64(0), spent.RemainingCost())
+	assert.Equal(t, int64(0), unbounded.RemainingTokens())
+	assert.Equal(t, float64(0), unbounded.RemainingCost())
+}
+
+func TestNewTracker_ClientWithoutIdentity(t *testing.T) {
+	_, err := NewTracker(NewMemoryStore(), &config.BudgetsConfig{
+		Clients: []config.BudgetClientConfig{{Tenant: "partner"}},
+	})
+
+	assert.EqualError(t, err, `a client of the tenant "partner" has no API key, token subject, subject or address`)
+}
@@ -0,0 +1,116 @@
+package caller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"qd-image-analysis-api/internal/config"
+)
+
+// Kind tells how a client was identified
+type Kind string
+
+const (
+	// KindAPIKey identifies a client by the API key sent in its metadata
+	KindAPIKey Kind = "api_key"
+	// KindToken identifies a client by the subject of its validated bearer token
+	KindToken Kind = "token"
+	// KindSubject identifies a client by the subject of its verified mTLS certificate
+	KindSubject Kind = "subject"
+	// KindAddress identifies a client by the IP address it connects from
+	KindAddress Kind = "address"
+)
+
+// Identity identifies the client making a request
+type Identity struct {
+	Kind  Kind
+	Value string
+}
+
+// FromConfig returns the identity of a configured client, it returns false when the client has none
+func FromConfig(client config.ClientIdentityConfig) (Identity, bool) {
+	switch {
+	case client.APIKey != "":
+		return Identity{Kind: KindAPIKey, Value: client.APIKey}, true
+	case client.TokenSubject != "":
+		return Identity{Kind: KindToken, Value: client.TokenSubject}, true
+	case client.Subject != "":
+		return Identity{Kind: KindSubject, Value: client.Subject}, true
+	case client.Address != "":
+		return Identity{Kind: KindAddress, Value: client.Address}, true
+	}
+	return Identity{}, false
+}
+
+// APIKeys holds the API keys of the configured clients, indexed by the key of their identity
+type APIKeys map[string]struct{}
+
+// NewAPIKeys returns the API keys of the clients, the clients identified otherwise are ignored
+func NewAPIKeys(clients ...config.ClientIdentityConfig) APIKeys {
+	apiKeys := APIKeys{}
+	for _, client := range clients {
+		if identity, ok := FromConfig(client); ok && identity.Kind == KindAPIKey {
+			apiKeys[identity.Key()] = struct{}{}
+		}
+	}
+	return apiKeys
+}
+
+// Contains reports whether the API key belongs to a configured client
+func (apiKeys APIKeys) Contains(apiKey string) bool {
+	_, ok := apiKeys[Identity{Kind: KindAPIKey, Value: apiKey}.Key()]
+	return ok
+}
+
+// String returns the identity as logged, API keys are replaced with the prefix of their hash
+func (identity Identity) String() string {
+	key := identity.Key()
+	if identity.Kind == KindAPIKey {
+		return key[:len(KindAPIKey)+1+12]
+	}
+	return key
+}
+
+// Key indexes the state kept about the client, API keys are hashed so that they are not stored
+func (identity Identity) Key() string {
+	value := identity.Value
+	if identity.Kind == KindAPIKey {
+		hash := sha256.Sum256([]byte(value))
+		value = hex.EncodeToString(hash[:])
+	}
+	return fmt.Sprintf("%s:%s", identity.Kind, value)
+}
+
+// Principal is the authenticated client making a request
+type Principal struct {
+	Identity Identity
+	Scopes   []string
+	// Tenant and Tier override the budget tenant and the rate limit tier configured for the client when they are set
+	Tenant string
+	Tier   string
+}
+
+// HasScope reports whether the client was granted the scope
+func (principal *Principal) HasScope(scope string) bool {
+	for _, granted := range principal.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of the context carrying the authenticated client
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated client of the context, it returns false when the request was not authenticated
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
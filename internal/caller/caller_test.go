@@ -0,0 +1,92 @@
+package caller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/config"
+)
+
+func TestIdentityString(t *testing.T) {
+	assert.Equal(t, "address:10.0.0.1", Identity{Kind: KindAddress, Value: "10.0.0.1"}.String())
+	apiKey := Identity{Kind: KindAPIKey, Value: "secret"}.String()
+	assert.Equal(t, "api_key:2bb80d537b1d", apiKey)
+	assert.NotContains(t, apiKey, "secret")
+}
+
+func TestFromConfig(t *testing.T) {
+	testCases := []struct {
+		name       string
+		client     config.ClientIdentityConfig
+		expected   Identity
+		expectedOK bool
+	}{
+		{
+			name:       "Success_APIKey",
+			client:     config.ClientIdentityConfig{APIKey: "key", Subject: "CN=partner"},
+			expected:   Identity{Kind: KindAPIKey, Value: "key"},
+			expectedOK: true,
+		},
+		{
+			name:       "Success_TokenSubject",
+			client:     config.ClientIdentityConfig{TokenSubject: "batch-job", Subject: "CN=partner"},
+			expected:   Identity{Kind: KindToken, Value: "batch-job"},
+			expectedOK: true,
+		},
+		{
+			name:       "Success_Subject",
+			client:     config.ClientIdentityConfig{Subject: "CN=partner", Address: "10.0.0.1"},
+			expected:   Identity{Kind: KindSubject, Value: "CN=partner"},
+			expectedOK: true,
+		},
+		{
+			name:       "Success_Address",
+			client:     config.ClientIdentityConfig{Address: "10.0.0.1"},
+			expected:   Identity{Kind: KindAddress, Value: "10.0.0.1"},
+			expectedOK: true,
+		},
+		{
+			name: "Error_Empty",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			identity, ok := FromConfig(testCase.client)
+
+			assert.Equal(t, testCase.expectedOK, ok)
+			assert.Equal(t, testCase.expected, identity)
+		})
+	}
+}
+
+func TestPrincipalContext(t *testing.T) {
+	principal := &Principal{
+		Identity: Identity{Kind: KindToken, Value: "batch-job"},
+		Scopes:   []string{"images:analyze", "sessions:write"},
+	}
+
+	fromContext, ok := FromContext(NewContext(context.Background(), principal))
+
+	assert.True(t, ok)
+	assert.Same(t, principal, fromContext)
+	assert.True(t, fromContext.HasScope("sessions:write"))
+	assert.False(t, fromContext.HasScope("sessions:read"))
+	_, ok = FromContext(context.Background())
+	assert.False(t, ok)
+	_, ok = FromContext(NewContext(context.Background(), nil))
+	assert.False(t, ok)
+}
+
+func TestAPIKeys(t *testing.T) {
+	apiKeys := NewAPIKeys(
+		config.ClientIdentityConfig{APIKey: "key"},
+		config.ClientIdentityConfig{Subject: "CN=partner"},
+	)
+
+	assert.True(t, apiKeys.Contains("key"))
+	assert.False(t, apiKeys.Contains("other"))
+	assert.False(t, apiKeys.Contains("CN=partner"))
+	assert.False(t, APIKeys(nil).Contains("key"))
+}
@@ -8,22 +8,308 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// ModelPrice holds the price of a model per million tokens
+type ModelPrice struct {
+	InputPerMillionTokens  float64 `mapstructure:"input_per_million_tokens"`
+	OutputPerMillionTokens float64 `mapstructure:"output_per_million_tokens"`
+}
+
 // VertexAIConfig holds the Vertex AI configuration
 type VertexAIConfig struct {
-	ProjectID   string  `mapstructure:"project_id"`
-	Location    string  `mapstructure:"location"`
-	ModelName   string  `mapstructure:"model_name"`
-	ConfigPath  string  `mapstructure:"config_path"`
-	MaxTokens   int32   `mapstructure:"max_tokens"`
-	Temperature float32 `mapstructure:"temperature"`
+	ProjectID      string  `mapstructure:"project_id"`
+	Location       string  `mapstructure:"location"`
+	ModelName      string  `mapstructure:"model_name"`
+	ConfigPath     string  `mapstructure:"config_path"`
+	MaxTokens      int32   `mapstructure:"max_tokens"`
+	Temperature    float32 `mapstructure:"temperature"`
+	CandidateCount int32   `mapstructure:"candidate_count"`
+	// Prices is indexed by model name and used to estimate the cost of every request
+	Prices map[string]ModelPrice `mapstructure:"prices"`
+}
+
+// OpenAIConfig holds the configuration of an OpenAI-compatible chat completions endpoint
+type OpenAIConfig struct {
+	BaseURL        string  `mapstructure:"base_url"`
+	APIKey         string  `mapstructure:"api_key"`
+	ModelName      string  `mapstructure:"model_name"`
+	MaxTokens      int32   `mapstructure:"max_tokens"`
+	Temperature    float32 `mapstructure:"temperature"`
+	TimeoutSeconds int     `mapstructure:"timeout_seconds"`
+}
+
+// OllamaConfig holds the configuration of a local Ollama server
+type OllamaConfig struct {
+	BaseURL        string  `mapstructure:"base_url"`
+	ModelName      string  `mapstructure:"model_name"`
+	MaxTokens      int32   `mapstructure:"max_tokens"`
+	Temperature    float32 `mapstructure:"temperature"`
+	TimeoutSeconds int     `mapstructure:"timeout_seconds"`
+}
+
+// FakeConfig holds the configuration of the deterministic fixtures based analyzer
+type FakeConfig struct {
+	FixturesPath string `mapstructure:"fixtures_path"`
+}
+
+// UploadConfig holds the limits of the chunked image uploads
+type UploadConfig struct {
+	MaxImageSizeBytes int64 `mapstructure:"max_image_size_bytes"`
+}
+
+// ImagesConfig holds the per-request image limits, zero values leave them unbounded
+type ImagesConfig struct {
+	MaxCount      int   `mapstructure:"max_count"`
+	MaxTotalBytes int64 `mapstructure:"max_total_bytes"`
+	// MinDimension and MaxDimension bound the width and height of every image in pixels
+	MinDimension int `mapstructure:"min_dimension"`
+	MaxDimension int `mapstructure:"max_dimension"`
+	// CorrectMimeType replaces the declared mime type with the one detected from the content instead of rejecting the image
+	CorrectMimeType bool `mapstructure:"correct_mime_type"`
+	// NativeMimeTypes are passed to the analyzer as they are, image/jpeg and image/png when empty
+	NativeMimeTypes []string `mapstructure:"native_mime_types"`
+	// TranscodedMimeTypes are converted to TranscodeTo before the analysis, keeping the first frame of animations
+	TranscodedMimeTypes []string `mapstructure:"transcoded_mime_types"`
+	// TranscodeTo is image/png or image/jpeg, image/png when empty
+	TranscodeTo string `mapstructure:"transcode_to"`
+	// TranscodeQuality is the quality of the images transcoded to JPEG, the default quality when zero
+	TranscodeQuality int `mapstructure:"transcode_quality"`
+}
+
+// PreprocessingConfig toggles the steps applied to the images before they are analysed.
+// Images decoded by a step are encoded again without any metadata, JPEG images as JPEG and the others as PNG.
+type PreprocessingConfig struct {
+	// ApplyOrientation rotates and flips JPEG images as their EXIF orientation tells
+	ApplyOrientation bool `mapstructure:"apply_orientation"`
+	// MaxLongEdge downscales the images whose width or height is larger, zero disables downscaling
+	MaxLongEdge int `mapstructure:"max_long_edge"`
+	// Reencode encodes every image again even when no other step decoded it
+	Reencode bool `mapstructure:"reencode"`
+	// JPEGQuality is the quality of the encoded JPEG images, the default quality when zero
+	JPEGQuality int `mapstructure:"jpeg_quality"`
+	// StripMetadata removes the EXIF, XMP and text metadata of the images, including their GPS location
+	StripMetadata bool `mapstructure:"strip_metadata"`
+}
+
+// TilingConfig holds the settings of the tiling mode, in which an image is analysed as overlapping tiles
+type TilingConfig struct {
+	// TileSize is the width and height of the tiles in pixels when the request does not set the grid, 768 when zero
+	TileSize int `mapstructure:"tile_size"`
+	// OverlapPixels extends every tile over its neighbours
+	OverlapPixels int `mapstructure:"overlap_pixels"`
+	// MaxTiles is the maximum number of tiles of an image, 16 when zero
+	MaxTiles int `mapstructure:"max_tiles"`
+	// MaxParallelism is the maximum number of tiles analysed at once, zero leaves it unbounded
+	MaxParallelism int `mapstructure:"max_parallelism"`
+	// ThumbnailSize is the long edge in pixels of the thumbnail of the whole image, 768 when zero
+	ThumbnailSize int `mapstructure:"thumbnail_size"`
+}
+
+// DocumentsConfig holds the limits of the PDF documents analysed like images, zero values leave them unbounded.
+// Only the Vertex AI provider accepts documents, they are rejected with the other providers.
+type DocumentsConfig struct {
+	Enabled      bool  `mapstructure:"enabled"`
+	MaxPages     int   `mapstructure:"max_pages"`
+	MaxSizeBytes int64 `mapstructure:"max_size_bytes"`
+}
+
+// SessionsConfig holds the lifetime and size limits of the conversational sessions
+type SessionsConfig struct {
+	// TTLSeconds is how long a session is kept after its last message
+	TTLSeconds int `mapstructure:"ttl_seconds"`
+	// MaxMessages is the maximum number of prompts and answers of a session, zero leaves it unbounded
+	MaxMessages int `mapstructure:"max_messages"`
+	// MaxSessions is the maximum number of sessions kept at once, zero leaves it unbounded
+	MaxSessions int `mapstructure:"max_sessions"`
+	// MaxSessionsPerClient is the maximum number of sessions a client may keep at once, zero leaves it unbounded
+	MaxSessionsPerClient int `mapstructure:"max_sessions_per_client"`
+}
+
+// RateLimitTierConfig holds the request rate allowed to every client of a tier
+type RateLimitTierConfig struct {
+	// RequestsPerSecond is the sustained rate of requests, zero leaves the clients of the tier unlimited
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	// Burst is the number of requests allowed at once, 1 when zero
+	Burst int `mapstructure:"burst"`
+}
+
+// ClientIdentityConfig identifies a client by its API key, the subject of its bearer token,
+// the subject of its mTLS certificate or its address, the first of them set is used
+type ClientIdentityConfig struct {
+	APIKey       string `mapstructure:"api_key"`
+	TokenSubject string `mapstructure:"token_subject"`
+	Subject      string `mapstructure:"subject"`
+	Address      string `mapstructure:"address"`
+}
+
+// RateLimitClientConfig assigns a tier to a client
+type RateLimitClientConfig struct {
+	ClientIdentityConfig `mapstructure:",squash"`
+	Tier                 string `mapstructure:"tier"`
+}
+
+// RedisConfig holds the connection to a Redis server
+type RedisConfig struct {
+	Address  string `mapstructure:"address"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	// KeyPrefix prefixes the keys written by the service
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// RateLimitConfig holds the rate limits applied to every client separately.
+// Authenticated clients are identified first, by the subject of their bearer token or by their API key,
+// the tier of their API key overriding the tier configured for them. The other clients are identified
+// by their API key when it belongs to a configured client, the subject of their mTLS certificate
+// or their address, in this order.
+type RateLimitConfig struct {
+	// Backend is memory to count the requests of every instance on its own or redis to share the limits, memory when empty
+	Backend string      `mapstructure:"backend"`
+	Redis   RedisConfig `mapstructure:"redis"`
+	// DefaultTier applies to the clients without a tier, one request per second without burst when empty
+	DefaultTier string                         `mapstructure:"default_tier"`
+	Tiers       map[string]RateLimitTierConfig `mapstructure:"tiers"`
+	Clients     []RateLimitClientConfig        `mapstructure:"clients"`
+	// IdleTimeoutSeconds is how long the memory backend keeps the limiter of a client without requests, 10 minutes when zero
+	IdleTimeoutSeconds int `mapstructure:"idle_timeout_seconds"`
+}
+
+// BudgetLimitsConfig holds the tokens and the estimated cost a tenant may consume, zero values leave them unbounded
+type BudgetLimitsConfig struct {
+	DailyTokens   int64   `mapstructure:"daily_tokens"`
+	MonthlyTokens int64   `mapstructure:"monthly_tokens"`
+	DailyCost     float64 `mapstructure:"daily_cost"`
+	MonthlyCost   float64 `mapstructure:"monthly_cost"`
+}
+
+// BudgetClientConfig assigns a tenant to a client
+type BudgetClientConfig struct {
+	ClientIdentityConfig `mapstructure:",squash"`
+	Tenant               string `mapstructure:"tenant"`
+}
+
+// BudgetsConfig holds the daily and monthly budgets of the tenants, days and months start at midnight UTC.
+// Clients without a tenant are tenants on their own with the default limits.
+type BudgetsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store is memory or file to keep the usage across restarts, memory when empty
+	Store string `mapstructure:"store"`
+	// FilePath is the JSON file the file store keeps the usage in
+	FilePath string `mapstructure:"file_path"`
+	// Default holds the limits of the tenants missing from Tenants
+	Default BudgetLimitsConfig            `mapstructure:"default"`
+	Tenants map[string]BudgetLimitsConfig `mapstructure:"tenants"`
+	Clients []BudgetClientConfig          `mapstructure:"clients"`
+}
+
+// JWTConfig holds the public keys and the claims the bearer tokens of the callers are validated with,
+// bearer tokens are accepted when a JWKS file, a JWKS URL or public key files are set
+type JWTConfig struct {
+	// JWKSFile is a JSON Web Key Set file holding the public keys
+	JWKSFile string `mapstructure:"jwks_file"`
+	// JWKSURL is a JSON Web Key Set fetched at start, every refresh interval and when a token is signed by an unknown key
+	JWKSURL string `mapstructure:"jwks_url"`
+	// RefreshIntervalSeconds is how often the JWKS URL is fetched again, one hour when zero
+	RefreshIntervalSeconds int `mapstructure:"refresh_interval_seconds"`
+	// PublicKeyFiles are PEM public keys verifying the tokens without a known key ID
+	PublicKeyFiles []string `mapstructure:"public_key_files"`
+	// Issuer and Audience are required in the tokens when they are set
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// LeewaySeconds tolerates the clock skew with the issuer when checking the expiry of the tokens
+	LeewaySeconds int `mapstructure:"leeway_seconds"`
+}
+
+// APIKeyConfig is an API key registered by its salted hash, as printed by the apikey command
+type APIKeyConfig struct {
+	// ID is the public part of the key telling the keys apart
+	ID     string   `mapstructure:"id" json:"id"`
+	Hash   string   `mapstructure:"hash" json:"hash"`
+	Tenant string   `mapstructure:"tenant" json:"tenant"`
+	Scopes []string `mapstructure:"scopes" json:"scopes,omitempty"`
+	// Tier is the rate limit tier of the key, the tier of the client applies when it is empty
+	Tier string `mapstructure:"tier" json:"tier,omitempty"`
+}
+
+// APIKeysConfig holds the API keys accepted in the x-api-key metadata,
+// API keys are accepted when a file or keys are set
+type APIKeysConfig struct {
+	// File is a JSON file holding an array of API keys, loaded at start in addition to Keys
+	File string         `mapstructure:"file"`
+	Keys []APIKeyConfig `mapstructure:"keys"`
+}
+
+// AuthConfig holds how the callers are authenticated, every caller is accepted when it is disabled
+type AuthConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	JWT     JWTConfig     `mapstructure:"jwt"`
+	APIKeys APIKeysConfig `mapstructure:"api_keys"`
+}
+
+// SchemasConfig holds the location of the JSON Schemas clients can reference by name
+type SchemasConfig struct {
+	Directory string `mapstructure:"directory"`
+}
+
+// TemplatesConfig holds the location of the versioned prompt templates clients can reference by id
+type TemplatesConfig struct {
+	Directory string `mapstructure:"directory"`
+}
+
+// IntRange bounds an integer generation parameter, a zero Max leaves it unbounded above
+type IntRange struct {
+	Min int32 `mapstructure:"min"`
+	Max int32 `mapstructure:"max"`
+}
+
+// FloatRange bounds a decimal generation parameter, a zero Max leaves it unbounded above
+type FloatRange struct {
+	Min float32 `mapstructure:"min"`
+	Max float32 `mapstructure:"max"`
+}
+
+// GenerationConfig holds the bounds of the generation parameters clients can override per request
+type GenerationConfig struct {
+	MaxTokens        IntRange   `mapstructure:"max_tokens"`
+	Temperature      FloatRange `mapstructure:"temperature"`
+	TopP             FloatRange `mapstructure:"top_p"`
+	TopK             IntRange   `mapstructure:"top_k"`
+	CandidateCount   IntRange   `mapstructure:"candidate_count"`
+	MaxStopSequences int        `mapstructure:"max_stop_sequences"`
+}
+
+// ModelConfig maps a model alias of the catalog to a concrete model and its limits
+type ModelConfig struct {
+	ModelName string `mapstructure:"model_name"`
+	// MaxTokens is the default and maximum number of output tokens, zero keeps the provider setting
+	MaxTokens int32 `mapstructure:"max_tokens"`
+	// MaxCandidateCount is the maximum number of candidates, zero leaves it to the generation bounds
+	MaxCandidateCount int32 `mapstructure:"max_candidate_count"`
 }
 
 // Config is the configuration of the application
 type Config struct {
-	Verbose     bool
-	Environment string
-	AWS         commonAWS.Config
-	VertexAI    VertexAIConfig `mapstructure:"vertex_ai"`
+	Verbose       bool
+	Environment   string
+	Provider      string `mapstructure:"provider"`
+	AWS           commonAWS.Config
+	VertexAI      VertexAIConfig      `mapstructure:"vertex_ai"`
+	OpenAI        OpenAIConfig        `mapstructure:"openai"`
+	Ollama        OllamaConfig        `mapstructure:"ollama"`
+	Fake          FakeConfig          `mapstructure:"fake"`
+	Upload        UploadConfig        `mapstructure:"upload"`
+	Images        ImagesConfig        `mapstructure:"images"`
+	Documents     DocumentsConfig     `mapstructure:"documents"`
+	Preprocessing PreprocessingConfig `mapstructure:"preprocessing"`
+	Tiling        TilingConfig        `mapstructure:"tiling"`
+	Sessions      SessionsConfig      `mapstructure:"sessions"`
+	RateLimit     RateLimitConfig     `mapstructure:"rate_limit"`
+	Budgets       BudgetsConfig       `mapstructure:"budgets"`
+	Auth          AuthConfig          `mapstructure:"auth"`
+	Schemas       SchemasConfig       `mapstructure:"schemas"`
+	Templates     TemplatesConfig     `mapstructure:"templates"`
+	Generation    GenerationConfig    `mapstructure:"generation"`
+	// Models is the catalog of the models clients can select per request, indexed by alias
+	Models map[string]ModelConfig `mapstructure:"models"`
 }
 
 // Load reads and parses the configuration file from the specified location
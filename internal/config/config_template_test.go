@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// repositoryRoot is the directory the paths of the template are relative to
+const repositoryRoot = "../.."
+
+// templatePaths holds the paths of the files the template points to
+type templatePaths struct {
+	Fake struct {
+		FixturesPath string `yaml:"fixtures_path"`
+	} `yaml:"fake"`
+	Schemas struct {
+		Directory string `yaml:"directory"`
+	} `yaml:"schemas"`
+	Templates struct {
+		Directory string `yaml:"directory"`
+	} `yaml:"templates"`
+}
+
+func readTemplatePaths(t *testing.T) templatePaths {
+	t.Helper()
+	content, err := os.ReadFile("config.template.yml")
+	assert.NoError(t, err)
+	var paths templatePaths
+	assert.NoError(t, yaml.Unmarshal(content, &paths))
+	return paths
+}
+
+func TestConfigTemplate_FakeFixturesPath(t *testing.T) {
+	paths := readTemplatePaths(t)
+
+	assert.FileExists(t, filepath.Join(repositoryRoot, paths.Fake.FixturesPath))
+}
+
+func TestConfigTemplate_SchemasDirectory(t *testing.T) {
+	paths := readTemplatePaths(t)
+
+	assert.DirExists(t, filepath.Join(repositoryRoot, paths.Schemas.Directory))
+}
+
+func TestConfigTemplate_TemplatesDirectory(t *testing.T) {
+	paths := readTemplatePaths(t)
+
+	assert.DirExists(t, filepath.Join(repositoryRoot, paths.Templates.Directory))
+}
@@ -0,0 +1,68 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// MimeTypePDF is the mime type of PDF documents
+const MimeTypePDF = "application/pdf"
+
+// ErrCorrupt is returned when a PDF document cannot be parsed
+var ErrCorrupt = errors.New("corrupt PDF document")
+
+var pdfMagic = []byte("%PDF-")
+
+func init() {
+	// Keeps pdfcpu from writing its configuration to the user configuration directory
+	api.DisableConfigDir()
+}
+
+// newConfiguration returns a pdfcpu configuration tolerant of the minor defects of scanned documents
+func newConfiguration() *model.Configuration {
+	configuration := model.NewDefaultConfiguration()
+	configuration.ValidationMode = model.ValidationRelaxed
+	return configuration
+}
+
+// IsPDF tells whether the content starts with the PDF header
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, pdfMagic)
+}
+
+// recoverCorrupt turns a panic of pdfcpu on a malformed document into an error wrapping ErrCorrupt
+func recoverCorrupt(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = fmt.Errorf("%w: %v", ErrCorrupt, recovered)
+	}
+}
+
+// PageCount returns the number of pages of the PDF document.
+// It returns an error wrapping ErrCorrupt when the document cannot be parsed.
+func PageCount(data []byte) (count int, err error) {
+	defer recoverCorrupt(&err)
+	count, err = api.PageCount(bytes.NewReader(data), newConfiguration())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return count, nil
+}
+
+// ExtractPages returns a PDF document made of the pages first to last of the document, both included and starting at 1.
+// It returns an error wrapping ErrCorrupt when the document cannot be parsed.
+func ExtractPages(data []byte, first, last int) (extracted []byte, err error) {
+	defer recoverCorrupt(&err)
+	if first < 1 || last < first {
+		return nil, fmt.Errorf("invalid page range %d-%d", first, last)
+	}
+	var buffer bytes.Buffer
+	selection := []string{fmt.Sprintf("%d-%d", first, last)}
+	if err := api.Trim(bytes.NewReader(data), &buffer, selection, newConfiguration()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return buffer.Bytes(), nil
+}
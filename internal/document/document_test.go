@@ -0,0 +1,63 @@
+package document
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestDocument(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/three_pages.pdf")
+	assert.NoError(t, err)
+	return data
+}
+
+func TestIsPDF(t *testing.T) {
+	assert.True(t, IsPDF(readTestDocument(t)))
+	assert.False(t, IsPDF([]byte("\x89PNG\r\n\x1a\n")))
+	assert.False(t, IsPDF(nil))
+}
+
+func TestPageCount(t *testing.T) {
+	count, err := PageCount(readTestDocument(t))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestPageCount_Corrupt(t *testing.T) {
+	_, err := PageCount([]byte("%PDF-1.4\ngarbage"))
+
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestPageCount_Malformed(t *testing.T) {
+	// pdfcpu panics on this truncated header
+	_, err := PageCount(readTestDocument(t)[:63])
+
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestExtractPages(t *testing.T) {
+	extracted, err := ExtractPages(readTestDocument(t), 2, 3)
+
+	assert.NoError(t, err)
+	assert.True(t, IsPDF(extracted))
+	count, err := PageCount(extracted)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestExtractPages_InvalidRange(t *testing.T) {
+	_, err := ExtractPages(readTestDocument(t), 3, 2)
+
+	assert.EqualError(t, err, "invalid page range 3-2")
+}
+
+func TestExtractPages_Malformed(t *testing.T) {
+	_, err := ExtractPages(readTestDocument(t)[:63], 1, 1)
+
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
@@ -3,22 +3,42 @@ package grpcserver
 import (
 	"fmt"
 
-	"github.com/quadev-ltd/qd-common/pb/gen/go/pb_image_analysis"
 	"github.com/quadev-ltd/qd-common/pkg/grpcserver"
 	"github.com/quadev-ltd/qd-common/pkg/log"
 	commonTLS "github.com/quadev-ltd/qd-common/pkg/tls"
 	"google.golang.org/grpc"
 
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/budget"
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/ratelimit"
 	"qd-image-analysis-api/internal/service"
+	"qd-image-analysis-api/pb/gen/go/pb_image_analysis"
 )
 
+// Options holds the optional settings and dependencies of the gRPC server
+type Options struct {
+	// MaxUploadSize is the size in bytes above which uploads are rejected, DefaultMaxUploadSize when not positive
+	MaxUploadSize int64
+	// RateLimiter limits the requests of every client, a nil rate limiter allows every client one request per second
+	RateLimiter ratelimit.RateLimiter
+	// Budgets checks the usage of the tenants against their budgets, a nil tracker leaves it unbounded
+	Budgets *budget.Tracker
+	// APIKeys identify the unauthenticated clients configured by their API key
+	APIKeys caller.APIKeys
+	// Authenticator checks the credentials of the callers, every caller is accepted when it is nil
+	Authenticator auth.Authenticator
+}
+
 // Factoryer defines the interface for creating gRPC server instances
 type Factoryer interface {
 	Create(
 		grpcServerAddress string,
 		imageAnalysisService service.ImageAnalysisServicer,
+		sessionService service.SessionServicer,
 		logFactory log.Factoryer,
 		tlsEnabled bool,
+		options Options,
 	) (grpcserver.GRPCServicer, error)
 }
 
@@ -31,8 +51,10 @@ var _ Factoryer = &Factory{}
 func (grpcServerFactory *Factory) Create(
 	grpcServerAddress string,
 	imageAnalysisService service.ImageAnalysisServicer,
+	sessionService service.SessionServicer,
 	logFactory log.Factoryer,
 	tlsEnabled bool,
+	options Options,
 ) (grpcserver.GRPCServicer, error) {
 	const certFilePath = "certs/qd.image.analysis.api.crt"
 	const keyFilePath = "certs/qd.image.analysis.api.key"
@@ -47,9 +69,23 @@ func (grpcServerFactory *Factory) Create(
 		return nil, err
 	}
 
-	imageAnalysisServiceGRPCServer := NewImageAnalysisServiceServer(imageAnalysisService)
+	imageAnalysisServiceGRPCServer := NewImageAnalysisServiceServer(
+		imageAnalysisService,
+		sessionService,
+		options.MaxUploadSize,
+		options.RateLimiter,
+		options.Budgets,
+		options.APIKeys,
+	)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{log.CreateLoggerInterceptor(logFactory)}
+	streamInterceptors := []grpc.StreamServerInterceptor{createStreamLoggerInterceptor(logFactory)}
+	if options.Authenticator != nil {
+		unaryInterceptors = append(unaryInterceptors, createAuthInterceptor(options.Authenticator))
+		streamInterceptors = append(streamInterceptors, createStreamAuthInterceptor(options.Authenticator))
+	}
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(log.CreateLoggerInterceptor(logFactory)),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
 	pb_image_analysis.RegisterImageAnalysisServiceServer(grpcServer, imageAnalysisServiceGRPCServer)
 
@@ -2,59 +2,506 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
-	commonPB "github.com/quadev-ltd/qd-common/pb/gen/go/pb_image_analysis"
 	"github.com/quadev-ltd/qd-common/pkg/log"
-	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/budget"
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/ratelimit"
 	"qd-image-analysis-api/internal/service"
+	"qd-image-analysis-api/internal/session"
+	pb "qd-image-analysis-api/pb/gen/go/pb_image_analysis"
+)
+
+const (
+	// errorDomain is the domain of the error details returned to clients
+	errorDomain = "qd-image-analysis-api"
+	// retryAfterMetadataKey is the header telling throttled clients how many seconds to wait before retrying
+	retryAfterMetadataKey = "retry-after"
 )
 
 // ImageAnalysisServiceServer implements the gRPC service for image analysis
 type ImageAnalysisServiceServer struct {
-	commonPB.UnimplementedImageAnalysisServiceServer
+	pb.UnimplementedImageAnalysisServiceServer
 	imageAnalysisService service.ImageAnalysisServicer
-	limiter              *rate.Limiter
+	sessionService       service.SessionServicer
+	rateLimiter          ratelimit.RateLimiter
+	budgets              *budget.Tracker
+	apiKeys              caller.APIKeys
+	maxUploadSize        int64
 }
 
-// NewImageAnalysisServiceServer creates a new instance of the gRPC service server
-func NewImageAnalysisServiceServer(imageAnalysisService service.ImageAnalysisServicer) *ImageAnalysisServiceServer {
+// NewImageAnalysisServiceServer creates a new instance of the gRPC service server.
+// Uploads larger than maxUploadSize bytes are rejected, DefaultMaxUploadSize is used when it is not positive.
+// The requests of every client are limited separately by the rate limiter,
+// a nil rate limiter allows every client one request per second.
+// The usage of the tenants is checked against their budgets by the budget tracker, a nil tracker leaves it unbounded.
+// Unauthenticated clients are identified by their API key only when it is one of the configured apiKeys.
+func NewImageAnalysisServiceServer(
+	imageAnalysisService service.ImageAnalysisServicer,
+	sessionService service.SessionServicer,
+	maxUploadSize int64,
+	rateLimiter ratelimit.RateLimiter,
+	budgets *budget.Tracker,
+	apiKeys caller.APIKeys,
+) *ImageAnalysisServiceServer {
+	if maxUploadSize <= 0 {
+		maxUploadSize = DefaultMaxUploadSize
+	}
+	if rateLimiter == nil {
+		// a nil configuration cannot reference an unknown tier
+		rateLimiter, _ = ratelimit.NewMemoryLimiter(nil)
+	}
 	return &ImageAnalysisServiceServer{
 		imageAnalysisService: imageAnalysisService,
-		limiter:              rate.NewLimiter(rate.Limit(1), 1),
+		sessionService:       sessionService,
+		rateLimiter:          rateLimiter,
+		budgets:              budgets,
+		apiKeys:              apiKeys,
+		maxUploadSize:        maxUploadSize,
 	}
 }
 
 // ProcessImageAndPrompt handles the gRPC request to process an image with a prompt
-func (server *ImageAnalysisServiceServer) ProcessImageAndPrompt(ctx context.Context, request *commonPB.ImagePromptRequest) (*commonPB.ImagePromptResponse, error) {
+func (server *ImageAnalysisServiceServer) ProcessImageAndPrompt(ctx context.Context, request *pb.ImagePromptRequest) (*pb.ImagePromptResponse, error) {
 	logger, err := log.GetLoggerFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if !server.limiter.Allow() {
-		logger.Error(nil, "Too many requests")
-		return nil, status.Errorf(codes.ResourceExhausted, "Too many requests")
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return nil, err
+	}
+	tenant, err := server.checkBudget(ctx, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	response, err := server.imageAnalysisService.ProcessImageAndPrompt(
+	result, err := server.imageAnalysisService.ProcessImageAndPrompt(
 		ctx,
-		request.ImageData,
-		request.MimeType,
+		newImages(request),
 		request.Prompt,
+		newAnalysisOptions(request.Options),
 	)
 	if err != nil {
-		if serviceErr, ok := err.(*service.Error); ok {
-			return nil, status.Error(codes.InvalidArgument, serviceErr.Error())
-		}
-		logger.Error(err, "Error processing image and prompt")
-		return nil, status.Errorf(codes.Internal, "Error processing image and prompt")
+		return nil, toStatusError(ctx, logger, server.recordFailedUsage(ctx, logger, tenant, err))
+	}
+	server.recordUsage(ctx, logger, tenant, result)
+
+	logger.Info(fmt.Sprintf(
+		"Image and prompt processed successfully, finish reason %s, %d tokens used",
+		result.FinishReason(),
+		result.Usage.TotalTokens,
+	))
+	return newImagePromptResponse(result), nil
+}
+
+// ProcessImageAndPromptStream handles the gRPC request to process an image with a prompt,
+// streaming the response back as the model generates it. Cancelling the call cancels the model request.
+// The usage of the stream is recorded against the budgets of the tenant, even when it fails or is cancelled.
+func (server *ImageAnalysisServiceServer) ProcessImageAndPromptStream(
+	request *pb.ImagePromptRequest,
+	stream pb.ImageAnalysisService_ProcessImageAndPromptStreamServer,
+) error {
+	ctx := stream.Context()
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return err
+	}
+	tenant, err := server.checkBudget(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	result, err := server.imageAnalysisService.ProcessImageAndPromptStream(
+		ctx,
+		newImages(request),
+		request.Prompt,
+		newAnalysisOptions(request.Options),
+		func(chunk string) error {
+			return stream.Send(&pb.ImagePromptStreamResponse{
+				ResponseChunk: chunk,
+			})
+		},
+	)
+	if err != nil {
+		return toStatusError(ctx, logger, server.recordFailedUsage(ctx, logger, tenant, err))
+	}
+	server.recordUsage(ctx, logger, tenant, result)
+
+	logger.Info(fmt.Sprintf(
+		"Image and prompt streamed successfully, finish reason %s, %d tokens used",
+		result.FinishReason(),
+		result.Usage.TotalTokens,
+	))
+	return nil
+}
+
+// UploadImageAndPrompt handles the client-streaming gRPC request that uploads an image in chunks.
+// The image is reassembled and verified before being processed with the prompt sent in the metadata.
+func (server *ImageAnalysisServiceServer) UploadImageAndPrompt(stream pb.ImageAnalysisService_UploadImageAndPromptServer) error {
+	ctx := stream.Context()
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return err
+	}
+	tenant, err := server.checkBudget(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	upload, err := receiveImageUpload(stream, server.maxUploadSize)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Rejected image upload: %v", err))
+		return err
+	}
+
+	result, err := server.imageAnalysisService.ProcessImageAndPrompt(
+		ctx,
+		[]ai.Image{{Data: upload.imageData, MimeType: upload.mimeType}},
+		upload.prompt,
+		newAnalysisOptions(upload.options),
+	)
+	if err != nil {
+		return toStatusError(ctx, logger, server.recordFailedUsage(ctx, logger, tenant, err))
+	}
+	server.recordUsage(ctx, logger, tenant, result)
+
+	logger.Info(fmt.Sprintf(
+		"Uploaded image and prompt processed successfully, finish reason %s, %d tokens used",
+		result.FinishReason(),
+		result.Usage.TotalTokens,
+	))
+	return stream.SendAndClose(newImagePromptResponse(result))
+}
+
+// CreateSession handles the gRPC request to start a conversation about one or more images.
+// The session belongs to the caller, it is not found by the requests of other clients.
+func (server *ImageAnalysisServiceServer) CreateSession(
+	ctx context.Context,
+	request *pb.CreateSessionRequest,
+) (*pb.CreateSessionResponse, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return nil, err
+	}
+
+	images := make([]ai.Image, 0, len(request.Images))
+	for _, image := range request.Images {
+		images = append(images, ai.Image{Data: image.Data, MimeType: image.MimeType})
+	}
+	createdSession, err := server.sessionService.CreateSession(ctx, server.sessionOwner(ctx), images)
+	if err != nil {
+		return nil, toStatusError(ctx, logger, err)
+	}
+
+	return &pb.CreateSessionResponse{
+		SessionId: createdSession.ID,
+		ExpiresAt: createdSession.ExpiresAt.Unix(),
+	}, nil
+}
+
+// SendSessionMessage handles the gRPC request to ask a question about the images of a session
+func (server *ImageAnalysisServiceServer) SendSessionMessage(
+	ctx context.Context,
+	request *pb.SessionMessageRequest,
+) (*pb.ImagePromptResponse, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return nil, err
+	}
+	tenant, err := server.checkBudget(ctx, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := server.sessionService.SendMessage(
+		ctx,
+		server.sessionOwner(ctx),
+		request.SessionId,
+		request.Prompt,
+		newAnalysisOptions(request.Options),
+	)
+	if err != nil {
+		return nil, toStatusError(ctx, logger, server.recordFailedUsage(ctx, logger, tenant, err))
+	}
+	server.recordUsage(ctx, logger, tenant, result)
+
+	logger.Info(fmt.Sprintf(
+		"Session message processed successfully, finish reason %s, %d tokens used",
+		result.FinishReason(),
+		result.Usage.TotalTokens,
+	))
+	return newImagePromptResponse(result), nil
+}
+
+// GetSessionHistory handles the gRPC request to read the prompts and answers of a session
+func (server *ImageAnalysisServiceServer) GetSessionHistory(
+	ctx context.Context,
+	request *pb.GetSessionHistoryRequest,
+) (*pb.GetSessionHistoryResponse, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return nil, err
+	}
+
+	existingSession, err := server.sessionService.GetSession(ctx, server.sessionOwner(ctx), request.SessionId)
+	if err != nil {
+		return nil, toStatusError(ctx, logger, err)
 	}
 
-	logger.Info("Image and prompt processed successfully")
-	return &commonPB.ImagePromptResponse{
-		ResponseToPrompt: response,
+	messages := make([]*pb.SessionMessage, 0, len(existingSession.History))
+	for _, message := range existingSession.History {
+		messages = append(messages, &pb.SessionMessage{
+			Role: string(message.Role),
+			Text: message.Text,
+		})
+	}
+	return &pb.GetSessionHistoryResponse{
+		SessionId:  existingSession.ID,
+		Messages:   messages,
+		ImageCount: int32(len(existingSession.Images)),
+		CreatedAt:  existingSession.CreatedAt.Unix(),
+		ExpiresAt:  existingSession.ExpiresAt.Unix(),
 	}, nil
 }
+
+// DeleteSession handles the gRPC request to end a session and discard its images
+func (server *ImageAnalysisServiceServer) DeleteSession(
+	ctx context.Context,
+	request *pb.DeleteSessionRequest,
+) (*pb.DeleteSessionResponse, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.checkRateLimit(ctx, logger); err != nil {
+		return nil, err
+	}
+
+	if err := server.sessionService.DeleteSession(ctx, server.sessionOwner(ctx), request.SessionId); err != nil {
+		return nil, toStatusError(ctx, logger, err)
+	}
+
+	logger.Info("Session deleted")
+	return &pb.DeleteSessionResponse{}, nil
+}
+
+// GetUsage handles the gRPC request to read the usage and the remaining budgets of the tenant of the caller
+func (server *ImageAnalysisServiceServer) GetUsage(
+	ctx context.Context,
+	_ *pb.GetUsageRequest,
+) (*pb.GetUsageResponse, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if server.budgets == nil {
+		return nil, status.Error(codes.FailedPrecondition, "Budgets are not enabled")
+	}
+	usage, err := server.budgets.Status(ctx, server.tenantOf(ctx))
+	if err != nil {
+		logger.Error(err, "Error reading the usage")
+		return nil, status.Errorf(codes.Internal, "Error reading the usage")
+	}
+	return &pb.GetUsageResponse{
+		Tenant:  usage.Tenant,
+		Daily:   newBudgetUsage(usage.Daily),
+		Monthly: newBudgetUsage(usage.Monthly),
+	}, nil
+}
+
+// newImages gathers the single image fields and the repeated images of the request
+func newImages(request *pb.ImagePromptRequest) []ai.Image {
+	images := make([]ai.Image, 0, len(request.Images)+1)
+	if len(request.ImageData) > 0 {
+		images = append(images, ai.Image{Data: request.ImageData, MimeType: request.MimeType})
+	}
+	for _, image := range request.Images {
+		images = append(images, ai.Image{Data: image.Data, MimeType: image.MimeType})
+	}
+	return images
+}
+
+var outputFormats = map[pb.OutputFormat]ai.OutputFormat{
+	pb.OutputFormat_OUTPUT_FORMAT_MARKDOWN:   ai.OutputFormatMarkdown,
+	pb.OutputFormat_OUTPUT_FORMAT_PLAIN_TEXT: ai.OutputFormatPlainText,
+	pb.OutputFormat_OUTPUT_FORMAT_HTML:       ai.OutputFormatHTML,
+	pb.OutputFormat_OUTPUT_FORMAT_JSON:       ai.OutputFormatJSON,
+}
+
+func newBudgetUsage(period budget.Period) *pb.BudgetUsage {
+	return &pb.BudgetUsage{
+		UsedTokens:      period.Usage.Tokens,
+		UsedCost:        period.Usage.Cost,
+		TokenLimit:      period.TokenLimit,
+		CostLimit:       period.CostLimit,
+		RemainingTokens: period.RemainingTokens(),
+		RemainingCost:   period.RemainingCost(),
+		ResetsAt:        period.ResetsAt.Unix(),
+	}
+}
+
+func newAnalysisOptions(options *pb.AnalysisOptions) *service.AnalysisOptions {
+	if options == nil {
+		return nil
+	}
+	analysisOptions := &service.AnalysisOptions{
+		OutputFormat: outputFormats[options.OutputFormat],
+		ConvertTo:    outputFormats[options.ConvertTo],
+		Model:        options.Model,
+	}
+	if options.JsonOutput != nil {
+		analysisOptions.OutputFormat = ai.OutputFormatJSON
+		analysisOptions.JSONSchema = options.JsonOutput.JsonSchema
+		analysisOptions.SchemaName = options.JsonOutput.SchemaName
+	}
+	if generation := options.Generation; generation != nil {
+		analysisOptions.Generation = ai.GenerationParams{
+			MaxTokens:      generation.MaxTokens,
+			Temperature:    generation.Temperature,
+			TopP:           generation.TopP,
+			TopK:           generation.TopK,
+			StopSequences:  generation.StopSequences,
+			Seed:           generation.Seed,
+			CandidateCount: generation.CandidateCount,
+		}
+	}
+	if promptTemplate := options.Template; promptTemplate != nil {
+		analysisOptions.Template = &service.TemplateReference{
+			ID:        promptTemplate.Id,
+			Version:   int(promptTemplate.Version),
+			Variables: promptTemplate.Variables,
+		}
+	}
+	if pages := options.Pages; pages != nil {
+		analysisOptions.Pages = &service.PageRange{
+			First: int(pages.First),
+			Last:  int(pages.Last),
+		}
+	}
+	if tiling := options.Tiling; tiling != nil {
+		analysisOptions.Tiling = &service.TilingOptions{
+			Rows:    int(tiling.Rows),
+			Columns: int(tiling.Columns),
+		}
+	}
+	return analysisOptions
+}
+
+func newImagePromptResponse(result *ai.Result) *pb.ImagePromptResponse {
+	candidates := make([]*pb.ImagePromptCandidate, 0, len(result.Candidates))
+	for _, candidate := range result.Candidates {
+		candidates = append(candidates, &pb.ImagePromptCandidate{
+			Response:     candidate.Text,
+			FinishReason: string(candidate.FinishReason),
+		})
+	}
+	tiles := make([]*pb.TileAnswer, 0, len(result.Tiles))
+	for _, tile := range result.Tiles {
+		tiles = append(tiles, &pb.TileAnswer{
+			Label:    tile.Label,
+			Row:      int32(tile.Row),
+			Column:   int32(tile.Column),
+			X:        int32(tile.Bounds.Min.X),
+			Y:        int32(tile.Bounds.Min.Y),
+			Width:    int32(tile.Bounds.Dx()),
+			Height:   int32(tile.Bounds.Dy()),
+			Response: tile.Text,
+		})
+	}
+	return &pb.ImagePromptResponse{
+		ResponseToPrompt: result.Text(),
+		FinishReason:     string(result.FinishReason()),
+		Candidates:       candidates,
+		Model:            result.Model,
+		Usage: &pb.TokenUsage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CandidatesTokens: result.Usage.CandidatesTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+		EstimatedCost: result.EstimatedCost,
+		Tiles:         tiles,
+	}
+}
+
+func toStatusError(ctx context.Context, logger log.Loggerer, err error) error {
+	if serviceErr, ok := err.(*service.Error); ok {
+		return newInvalidArgumentError(logger, serviceErr)
+	}
+	if errors.Is(err, session.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, session.ErrLimitReached) || errors.Is(err, session.ErrOwnerLimitReached) {
+		logger.Warn(fmt.Sprintf("Rejected session: %v", err))
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if analyzerErr, ok := err.(*ai.Error); ok {
+		logger.Error(err, "Analyzer provider failed to process image and prompt")
+		return status.Error(analyzerErrorCode(analyzerErr), "Error processing image and prompt")
+	}
+	if ctx.Err() != nil {
+		logger.Warn("Image and prompt processing cancelled by the client")
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	logger.Error(err, "Error processing image and prompt")
+	return status.Errorf(codes.Internal, "Error processing image and prompt")
+}
+
+// newInvalidArgumentError attaches the reason of the service error to the status so clients can handle it specifically
+func newInvalidArgumentError(logger log.Loggerer, serviceErr *service.Error) error {
+	invalidArgument := status.New(codes.InvalidArgument, serviceErr.Error())
+	if serviceErr.Reason == "" {
+		return invalidArgument.Err()
+	}
+	detailed, err := invalidArgument.WithDetails(&errdetails.ErrorInfo{
+		Reason: string(serviceErr.Reason),
+		Domain: errorDomain,
+	})
+	if err != nil {
+		logger.Error(err, "Failed to attach the error reason to the status")
+		return invalidArgument.Err()
+	}
+	return detailed.Err()
+}
+
+func analyzerErrorCode(analyzerErr *ai.Error) codes.Code {
+	switch analyzerErr.Kind {
+	case ai.ErrorKindRateLimited:
+		return codes.ResourceExhausted
+	case ai.ErrorKindUnavailable:
+		return codes.Unavailable
+	case ai.ErrorKindInvalidRequest:
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
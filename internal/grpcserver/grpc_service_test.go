@@ -2,19 +2,35 @@ package grpcserver
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"image"
+	"io"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
-	commonPB "github.com/quadev-ltd/qd-common/pb/gen/go/pb_image_analysis"
 	commonLog "github.com/quadev-ltd/qd-common/pkg/log"
 	commonLogMock "github.com/quadev-ltd/qd-common/pkg/log/mock"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/ratelimit"
+	ratelimitMock "qd-image-analysis-api/internal/ratelimit/mock"
 	"qd-image-analysis-api/internal/service"
 	"qd-image-analysis-api/internal/service/mock"
+	"qd-image-analysis-api/internal/session"
+	pb "qd-image-analysis-api/pb/gen/go/pb_image_analysis"
 )
 
 func TestProcessImageAndPrompt_Success(t *testing.T) {
@@ -22,7 +38,7 @@ func TestProcessImageAndPrompt_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockService := mock.NewMockImageAnalysisServicer(ctrl)
-	server := NewImageAnalysisServiceServer(mockService)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
 
 	logFactory := commonLog.NewLogFactory("test")
 	logger := logFactory.NewLogger()
@@ -31,13 +47,21 @@ func TestProcessImageAndPrompt_Success(t *testing.T) {
 	testImageData := []byte("test-image-data")
 	testPrompt := "test prompt"
 	testMimeType := "image/png"
-	testResponse := "test response"
+	testResponse := &ai.Result{
+		Candidates: []ai.Candidate{
+			{Text: "test response", FinishReason: ai.FinishReasonStop},
+			{Text: "other response", FinishReason: ai.FinishReasonMaxTokens},
+		},
+		Model:         "gemini",
+		Usage:         ai.Usage{PromptTokens: 300, CandidatesTokens: 20, TotalTokens: 320},
+		EstimatedCost: 0.0005,
+	}
 
 	mockService.EXPECT().
-		ProcessImageAndPrompt(gomock.Any(), testImageData, testMimeType, testPrompt).
+		ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
 		Return(testResponse, nil)
 
-	request := &commonPB.ImagePromptRequest{
+	request := &pb.ImagePromptRequest{
 		ImageData: testImageData,
 		Prompt:    testPrompt,
 		MimeType:  testMimeType,
@@ -46,7 +70,16 @@ func TestProcessImageAndPrompt_Success(t *testing.T) {
 	response, err := server.ProcessImageAndPrompt(ctx, request)
 
 	assert.NoError(t, err)
-	assert.Equal(t, testResponse, response.ResponseToPrompt)
+	assert.Equal(t, "test response", response.ResponseToPrompt)
+	assert.Equal(t, "STOP", response.FinishReason)
+	assert.Len(t, response.Candidates, 2)
+	assert.Equal(t, "other response", response.Candidates[1].Response)
+	assert.Equal(t, "MAX_TOKENS", response.Candidates[1].FinishReason)
+	assert.Equal(t, "gemini", response.Model)
+	assert.Equal(t, int32(300), response.Usage.PromptTokens)
+	assert.Equal(t, int32(20), response.Usage.CandidatesTokens)
+	assert.Equal(t, int32(320), response.Usage.TotalTokens)
+	assert.Equal(t, 0.0005, response.EstimatedCost)
 }
 
 func TestProcessImageAndPrompt_RateLimit(t *testing.T) {
@@ -54,7 +87,7 @@ func TestProcessImageAndPrompt_RateLimit(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockService := mock.NewMockImageAnalysisServicer(ctrl)
-	server := NewImageAnalysisServiceServer(mockService)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
 
 	logFactory := commonLog.NewLogFactory("test")
 	logger := logFactory.NewLogger()
@@ -67,11 +100,11 @@ func TestProcessImageAndPrompt_RateLimit(t *testing.T) {
 	// First few calls should succeed
 	for i := 0; i < 1; i++ {
 		mockService.EXPECT().
-			ProcessImageAndPrompt(gomock.Any(), testImageData, testMimeType, testPrompt).
-			Return("success", nil)
+			ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
+			Return(&ai.Result{Candidates: []ai.Candidate{{Text: "success"}}}, nil)
 	}
 
-	request := &commonPB.ImagePromptRequest{
+	request := &pb.ImagePromptRequest{
 		ImageData: testImageData,
 		Prompt:    testPrompt,
 		MimeType:  testMimeType,
@@ -94,6 +127,93 @@ func TestProcessImageAndPrompt_RateLimit(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, codes.ResourceExhausted.String(), status.Code().String())
 	assert.Contains(t, status.Message(), "Too many requests")
+	assert.Len(t, status.Details(), 1)
+	retryInfo, ok := status.Details()[0].(*errdetails.RetryInfo)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Second, retryInfo.RetryDelay.AsDuration(), float64(100*time.Millisecond))
+}
+
+func TestProcessImageAndPrompt_RateLimiterError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	mockRateLimiter := ratelimitMock.NewMockRateLimiter(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, mockRateLimiter, nil, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockRateLimiter.EXPECT().
+		Allow(gomock.Any(), caller.Identity{Kind: caller.KindAddress}, "").
+		Return(false, time.Duration(0), errors.New("redis unavailable"))
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "success"}}}, nil)
+
+	response, err := server.ProcessImageAndPrompt(ctx, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "success", response.ResponseToPrompt)
+}
+
+// headerTransportStream records the headers set by the handlers
+type headerTransportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (stream *headerTransportStream) SetHeader(header metadata.MD) error {
+	stream.header = metadata.Join(stream.header, header)
+	return nil
+}
+
+func TestProcessImageAndPrompt_RateLimitPerClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	apiKeys := caller.NewAPIKeys(config.ClientIdentityConfig{APIKey: "key"})
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, apiKeys)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "success"}}}, nil).
+		Times(3)
+
+	firstClient := peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	_, err := server.ProcessImageAndPrompt(firstClient, request)
+	assert.NoError(t, err)
+	// a new connection of the same client shares its limit
+	transportStream := &headerTransportStream{}
+	_, err = server.ProcessImageAndPrompt(
+		grpc.NewContextWithServerTransportStream(
+			peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5001}}),
+			transportStream,
+		),
+		request,
+	)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"1"}, transportStream.header.Get("retry-after"))
+
+	_, err = server.ProcessImageAndPrompt(
+		peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 5000}}),
+		request,
+	)
+	assert.NoError(t, err)
+	// the API key identifies the client rather than its address
+	_, err = server.ProcessImageAndPrompt(metadata.NewIncomingContext(firstClient, metadata.Pairs("x-api-key", "key")), request)
+	assert.NoError(t, err)
+	// keys of no configured client do not escape the limit of the address
+	for _, apiKey := range []string{"rotated-1", "rotated-2", "rotated-3"} {
+		_, err = server.ProcessImageAndPrompt(metadata.NewIncomingContext(firstClient, metadata.Pairs("x-api-key", apiKey)), request)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
 }
 
 func TestProcessImageAndPrompt_ServiceError(t *testing.T) {
@@ -101,7 +221,7 @@ func TestProcessImageAndPrompt_ServiceError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockService := mock.NewMockImageAnalysisServicer(ctrl)
-	server := NewImageAnalysisServiceServer(mockService)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
 
 	logFactory := commonLog.NewLogFactory("test")
 	logger := logFactory.NewLogger()
@@ -115,10 +235,10 @@ func TestProcessImageAndPrompt_ServiceError(t *testing.T) {
 	}
 
 	mockService.EXPECT().
-		ProcessImageAndPrompt(gomock.Any(), testImageData, testMimeType, testPrompt).
-		Return("", serviceError)
+		ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
+		Return(nil, serviceError)
 
-	request := &commonPB.ImagePromptRequest{
+	request := &pb.ImagePromptRequest{
 		ImageData: testImageData,
 		Prompt:    testPrompt,
 		MimeType:  testMimeType,
@@ -135,12 +255,48 @@ func TestProcessImageAndPrompt_ServiceError(t *testing.T) {
 	assert.Contains(t, status.Message(), "Invalid argument")
 }
 
+func TestProcessImageAndPrompt_ServiceErrorReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
+
+	logFactory := commonLog.NewLogFactory("test")
+	logger := logFactory.NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), nil).
+		Return(nil, &service.Error{
+			Message: `image 0 is declared as "image/png" but its content is "image/jpeg"`,
+			Reason:  service.ErrorReasonMimeTypeMismatch,
+		})
+
+	response, err := server.ProcessImageAndPrompt(ctx, &pb.ImagePromptRequest{
+		ImageData: []byte("test-image-data"),
+		Prompt:    "test prompt",
+		MimeType:  "image/png",
+	})
+
+	assert.Nil(t, response)
+	status, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, status.Code())
+	details := status.Details()
+	assert.Len(t, details, 1)
+	errorInfo, ok := details[0].(*errdetails.ErrorInfo)
+	assert.True(t, ok)
+	assert.Equal(t, string(service.ErrorReasonMimeTypeMismatch), errorInfo.Reason)
+	assert.Equal(t, errorDomain, errorInfo.Domain)
+}
+
 func TestProcessImageAndPrompt_RegularError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockService := mock.NewMockImageAnalysisServicer(ctrl)
-	server := NewImageAnalysisServiceServer(mockService)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
 
 	logger := commonLogMock.NewMockLoggerer(ctrl)
 
@@ -151,11 +307,11 @@ func TestProcessImageAndPrompt_RegularError(t *testing.T) {
 	testMimeType := "image/png"
 
 	mockService.EXPECT().
-		ProcessImageAndPrompt(gomock.Any(), testImageData, testMimeType, testPrompt).
-		Return("", errors.New("unexpected error"))
+		ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
+		Return(nil, errors.New("unexpected error"))
 	logger.EXPECT().Error(errors.New("unexpected error"), "Error processing image and prompt")
 
-	request := &commonPB.ImagePromptRequest{
+	request := &pb.ImagePromptRequest{
 		ImageData: testImageData,
 		Prompt:    testPrompt,
 		MimeType:  testMimeType,
@@ -171,3 +327,444 @@ func TestProcessImageAndPrompt_RegularError(t *testing.T) {
 	assert.Equal(t, codes.Internal.String(), status.Code().String())
 	assert.Contains(t, status.Message(), "Error processing image and prompt")
 }
+
+func TestProcessImageAndPrompt_AnalyzerRateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
+
+	logFactory := commonLog.NewLogFactory("test")
+	logger := logFactory.NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+	testImageData := []byte("test-image-data")
+	testPrompt := "test prompt"
+	testMimeType := "image/png"
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: testMimeType}}, testPrompt, nil).
+		Return(nil, &ai.Error{Kind: ai.ErrorKindRateLimited, StatusCode: 429, Message: "slow down"})
+
+	request := &pb.ImagePromptRequest{
+		ImageData: testImageData,
+		Prompt:    testPrompt,
+		MimeType:  testMimeType,
+	}
+
+	response, err := server.ProcessImageAndPrompt(ctx, request)
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+
+	status, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted.String(), status.Code().String())
+}
+
+type fakeUploadStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*pb.ImageUploadRequest
+	response *pb.ImagePromptResponse
+}
+
+func (stream *fakeUploadStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *fakeUploadStream) Recv() (*pb.ImageUploadRequest, error) {
+	if len(stream.requests) == 0 {
+		return nil, io.EOF
+	}
+	request := stream.requests[0]
+	stream.requests = stream.requests[1:]
+	return request, nil
+}
+
+func (stream *fakeUploadStream) SendAndClose(response *pb.ImagePromptResponse) error {
+	stream.response = response
+	return nil
+}
+
+func newUploadRequests(imageData []byte, declaredSize int64, checksum string, chunkSize int) []*pb.ImageUploadRequest {
+	requests := []*pb.ImageUploadRequest{
+		{
+			Payload: &pb.ImageUploadRequest_Metadata{
+				Metadata: &pb.ImageUploadMetadata{
+					MimeType:  "image/png",
+					Prompt:    "test prompt",
+					TotalSize: declaredSize,
+					Sha256:    checksum,
+				},
+			},
+		},
+	}
+	for start := 0; start < len(imageData); start += chunkSize {
+		end := min(start+chunkSize, len(imageData))
+		requests = append(requests, &pb.ImageUploadRequest{
+			Payload: &pb.ImageUploadRequest_Chunk{Chunk: imageData[start:end]},
+		})
+	}
+	return requests
+}
+
+func TestUploadImageAndPrompt_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, nil, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+	testImageData := []byte("test-image-data-split-in-chunks")
+	checksum := sha256.Sum256(testImageData)
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), []ai.Image{{Data: testImageData, MimeType: "image/png"}}, "test prompt", nil).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "test response", FinishReason: ai.FinishReasonStop}}}, nil)
+
+	stream := &fakeUploadStream{
+		ctx:      ctx,
+		requests: newUploadRequests(testImageData, int64(len(testImageData)), hex.EncodeToString(checksum[:]), 4),
+	}
+
+	err := server.UploadImageAndPrompt(stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test response", stream.response.ResponseToPrompt)
+}
+
+func TestUploadImageAndPrompt_InvalidUploads(t *testing.T) {
+	testImageData := []byte("test-image-data")
+	checksum := sha256.Sum256(testImageData)
+	checksumHex := hex.EncodeToString(checksum[:])
+
+	testCases := []struct {
+		name          string
+		requests      []*pb.ImageUploadRequest
+		maxUploadSize int64
+		expectedError string
+	}{
+		{
+			name:          "missing metadata",
+			requests:      newUploadRequests(testImageData, int64(len(testImageData)), checksumHex, 4)[1:],
+			maxUploadSize: DefaultMaxUploadSize,
+			expectedError: "first upload message must contain the metadata",
+		},
+		{
+			name:          "too large",
+			requests:      newUploadRequests(testImageData, int64(len(testImageData)), checksumHex, 4),
+			maxUploadSize: 8,
+			expectedError: "upload total size 15 exceeds the limit of 8 bytes",
+		},
+		{
+			name:          "more data than declared",
+			requests:      newUploadRequests(testImageData, 10, checksumHex, 4),
+			maxUploadSize: DefaultMaxUploadSize,
+			expectedError: "upload exceeds the declared total size of 10 bytes",
+		},
+		{
+			name:          "less data than declared",
+			requests:      newUploadRequests(testImageData, 20, checksumHex, 4),
+			maxUploadSize: DefaultMaxUploadSize,
+			expectedError: "upload received 15 of 20 bytes",
+		},
+		{
+			name:          "checksum mismatch",
+			requests:      newUploadRequests(testImageData, int64(len(testImageData)), "deadbeef", 4),
+			maxUploadSize: DefaultMaxUploadSize,
+			expectedError: "upload sha256 checksum mismatch",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock.NewMockImageAnalysisServicer(ctrl)
+			server := NewImageAnalysisServiceServer(mockService, nil, testCase.maxUploadSize, nil, nil, nil)
+
+			logger := commonLog.NewLogFactory("test").NewLogger()
+			ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+			err := server.UploadImageAndPrompt(&fakeUploadStream{ctx: ctx, requests: testCase.requests})
+
+			status, ok := status.FromError(err)
+			assert.True(t, ok)
+			assert.Equal(t, codes.InvalidArgument.String(), status.Code().String())
+			assert.Equal(t, testCase.expectedError, status.Message())
+		})
+	}
+}
+
+func TestNewAnalysisOptions(t *testing.T) {
+	assert.Nil(t, newAnalysisOptions(nil))
+	assert.Equal(t, &service.AnalysisOptions{}, newAnalysisOptions(&pb.AnalysisOptions{}))
+	assert.Equal(
+		t,
+		&service.AnalysisOptions{OutputFormat: ai.OutputFormatJSON, SchemaName: "invoice"},
+		newAnalysisOptions(&pb.AnalysisOptions{JsonOutput: &pb.JSONOutputOptions{SchemaName: "invoice"}}),
+	)
+	assert.Equal(
+		t,
+		&service.AnalysisOptions{OutputFormat: ai.OutputFormatMarkdown, ConvertTo: ai.OutputFormatPlainText},
+		newAnalysisOptions(&pb.AnalysisOptions{
+			OutputFormat: pb.OutputFormat_OUTPUT_FORMAT_MARKDOWN,
+			ConvertTo:    pb.OutputFormat_OUTPUT_FORMAT_PLAIN_TEXT,
+		}),
+	)
+
+	temperature := float32(0.9)
+	options := newAnalysisOptions(&pb.AnalysisOptions{
+		Generation: &pb.GenerationParameters{Temperature: &temperature, StopSequences: []string{"END"}},
+	})
+	assert.Equal(t, &temperature, options.Generation.Temperature)
+	assert.Equal(t, []string{"END"}, options.Generation.StopSequences)
+	assert.Nil(t, options.Generation.MaxTokens)
+
+	assert.Equal(t, "fast", newAnalysisOptions(&pb.AnalysisOptions{Model: "fast"}).Model)
+
+	assert.Equal(
+		t,
+		&service.TemplateReference{ID: "caption", Version: 2, Variables: map[string]string{"tone": "funny"}},
+		newAnalysisOptions(&pb.AnalysisOptions{
+			Template: &pb.PromptTemplate{Id: "caption", Version: 2, Variables: map[string]string{"tone": "funny"}},
+		}).Template,
+	)
+
+	assert.Equal(
+		t,
+		&service.PageRange{First: 2, Last: 4},
+		newAnalysisOptions(&pb.AnalysisOptions{Pages: &pb.PageRange{First: 2, Last: 4}}).Pages,
+	)
+
+	assert.Equal(
+		t,
+		&service.TilingOptions{Rows: 2, Columns: 3},
+		newAnalysisOptions(&pb.AnalysisOptions{Tiling: &pb.TilingOptions{Rows: 2, Columns: 3}}).Tiling,
+	)
+}
+
+func TestNewImagePromptResponse_Tiles(t *testing.T) {
+	response := newImagePromptResponse(&ai.Result{
+		Candidates: []ai.Candidate{{Text: "merged", FinishReason: ai.FinishReasonStop}},
+		Tiles: []ai.TileAnswer{
+			{Label: "overview", Bounds: image.Rect(0, 0, 200, 100), Text: "a cat"},
+			{Label: "R1C2", Row: 1, Column: 2, Bounds: image.Rect(90, 0, 200, 100), Text: "a tail"},
+		},
+	})
+
+	assert.Equal(t, "merged", response.ResponseToPrompt)
+	assert.Len(t, response.Tiles, 2)
+	assert.Equal(t, "overview", response.Tiles[0].Label)
+	assert.Equal(t, &pb.TileAnswer{
+		Label:    "R1C2",
+		Row:      1,
+		Column:   2,
+		X:        90,
+		Y:        0,
+		Width:    110,
+		Height:   100,
+		Response: "a tail",
+	}, response.Tiles[1])
+}
+
+func TestNewImages(t *testing.T) {
+	assert.Empty(t, newImages(&pb.ImagePromptRequest{MimeType: "image/png"}))
+	assert.Equal(
+		t,
+		[]ai.Image{
+			{Data: []byte("before"), MimeType: "image/png"},
+			{Data: []byte("after"), MimeType: "image/jpeg"},
+		},
+		newImages(&pb.ImagePromptRequest{
+			ImageData: []byte("before"),
+			MimeType:  "image/png",
+			Images:    []*pb.Image{{Data: []byte("after"), MimeType: "image/jpeg"}},
+		}),
+	)
+}
+
+func newSessionTestServer(ctrl *gomock.Controller) (*ImageAnalysisServiceServer, *mock.MockSessionServicer, context.Context) {
+	mockSessionService := mock.NewMockSessionServicer(ctrl)
+	unlimited, _ := ratelimit.NewMemoryLimiter(&config.RateLimitConfig{
+		DefaultTier: "unlimited",
+		Tiers:       map[string]config.RateLimitTierConfig{"unlimited": {}},
+	})
+	server := NewImageAnalysisServiceServer(
+		mock.NewMockImageAnalysisServicer(ctrl),
+		mockSessionService,
+		DefaultMaxUploadSize,
+		unlimited,
+		nil,
+		nil,
+	)
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := peer.NewContext(
+		context.WithValue(context.Background(), commonLog.LoggerKey, logger),
+		&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}},
+	)
+	return server, mockSessionService, ctx
+}
+
+// testSessionOwner is the owner of the sessions of the clients connecting from the address of newSessionTestServer
+const testSessionOwner = "address:10.0.0.1"
+
+func TestCreateSession_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+	expiresAt := time.Unix(1700000000, 0)
+
+	mockSessionService.EXPECT().
+		CreateSession(gomock.Any(), testSessionOwner, []ai.Image{{Data: []byte("image"), MimeType: "image/png"}}).
+		Return(&session.Session{ID: "session-id", ExpiresAt: expiresAt}, nil)
+
+	response, err := server.CreateSession(ctx, &pb.CreateSessionRequest{
+		Images: []*pb.Image{{Data: []byte("image"), MimeType: "image/png"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "session-id", response.SessionId)
+	assert.Equal(t, int64(1700000000), response.ExpiresAt)
+}
+
+func TestCreateSession_LimitReached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+
+	mockSessionService.EXPECT().
+		CreateSession(gomock.Any(), testSessionOwner, gomock.Any()).
+		Return(nil, session.ErrOwnerLimitReached)
+
+	response, err := server.CreateSession(ctx, &pb.CreateSessionRequest{
+		Images: []*pb.Image{{Data: []byte("image"), MimeType: "image/png"}},
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "too many sessions for the client", status.Convert(err).Message())
+}
+
+func TestSendSessionMessage_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+
+	mockSessionService.EXPECT().
+		SendMessage(gomock.Any(), testSessionOwner, "session-id", "What colour is it?", nil).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "Black.", FinishReason: ai.FinishReasonStop}}}, nil)
+
+	response, err := server.SendSessionMessage(ctx, &pb.SessionMessageRequest{
+		SessionId: "session-id",
+		Prompt:    "What colour is it?",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Black.", response.ResponseToPrompt)
+	assert.Equal(t, "STOP", response.FinishReason)
+}
+
+func TestSendSessionMessage_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+
+	mockSessionService.EXPECT().
+		SendMessage(gomock.Any(), testSessionOwner, "unknown", "What colour is it?", nil).
+		Return(nil, session.ErrNotFound)
+
+	response, err := server.SendSessionMessage(ctx, &pb.SessionMessageRequest{
+		SessionId: "unknown",
+		Prompt:    "What colour is it?",
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGetSessionHistory_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+
+	mockSessionService.EXPECT().
+		GetSession(gomock.Any(), testSessionOwner, "session-id").
+		Return(&session.Session{
+			ID:     "session-id",
+			Images: []ai.Image{{Data: []byte("image"), MimeType: "image/png"}},
+			History: []ai.Message{
+				{Role: ai.RoleUser, Text: "What is this?"},
+				{Role: ai.RoleModel, Text: "A cat."},
+			},
+			CreatedAt: time.Unix(1700000000, 0),
+			ExpiresAt: time.Unix(1700001800, 0),
+		}, nil)
+
+	response, err := server.GetSessionHistory(ctx, &pb.GetSessionHistoryRequest{SessionId: "session-id"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "session-id", response.SessionId)
+	assert.Equal(t, int32(1), response.ImageCount)
+	assert.Len(t, response.Messages, 2)
+	assert.Equal(t, "user", response.Messages[0].Role)
+	assert.Equal(t, "What is this?", response.Messages[0].Text)
+	assert.Equal(t, "model", response.Messages[1].Role)
+	assert.Equal(t, "A cat.", response.Messages[1].Text)
+	assert.Equal(t, int64(1700000000), response.CreatedAt)
+	assert.Equal(t, int64(1700001800), response.ExpiresAt)
+}
+
+func TestDeleteSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server, mockSessionService, ctx := newSessionTestServer(ctrl)
+
+	gomock.InOrder(
+		mockSessionService.EXPECT().DeleteSession(gomock.Any(), testSessionOwner, "session-id").Return(nil),
+		mockSessionService.EXPECT().DeleteSession(gomock.Any(), testSessionOwner, "session-id").Return(session.ErrNotFound),
+	)
+
+	response, err := server.DeleteSession(ctx, &pb.DeleteSessionRequest{SessionId: "session-id"})
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+
+	response, err = server.DeleteSession(ctx, &pb.DeleteSessionRequest{SessionId: "session-id"})
+	assert.Nil(t, response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSessionRequests_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSessionService := mock.NewMockSessionServicer(ctrl)
+	mockRateLimiter := ratelimitMock.NewMockRateLimiter(ctrl)
+	server := NewImageAnalysisServiceServer(
+		mock.NewMockImageAnalysisServicer(ctrl),
+		mockSessionService,
+		DefaultMaxUploadSize,
+		mockRateLimiter,
+		nil,
+		nil,
+	)
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+	mockRateLimiter.EXPECT().Allow(gomock.Any(), gomock.Any(), "").Return(false, time.Second, nil).Times(4)
+
+	_, err := server.CreateSession(ctx, &pb.CreateSessionRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = server.SendSessionMessage(ctx, &pb.SessionMessageRequest{SessionId: "session-id"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = server.GetSessionHistory(ctx, &pb.GetSessionHistoryRequest{SessionId: "session-id"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = server.DeleteSession(ctx, &pb.DeleteSessionRequest{SessionId: "session-id"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
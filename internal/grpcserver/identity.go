@@ -0,0 +1,48 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/caller"
+)
+
+// clientIdentity identifies the caller by its authenticated identity, then by its API key when it belongs to a configured client,
+// then by the subject of its verified mTLS certificate and finally by the IP address it connects from.
+// Other API keys are ignored, a client could otherwise escape its limits by sending a new key with every request.
+func clientIdentity(ctx context.Context, apiKeys caller.APIKeys) caller.Identity {
+	if principal, ok := caller.FromContext(ctx); ok {
+		return principal.Identity
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, auth.APIKeyMetadataKey); len(values) > 0 && apiKeys.Contains(values[0]) {
+		return caller.Identity{Kind: caller.KindAPIKey, Value: values[0]}
+	}
+	callerPeer, ok := peer.FromContext(ctx)
+	if !ok {
+		return caller.Identity{Kind: caller.KindAddress}
+	}
+	if tlsInfo, ok := callerPeer.AuthInfo.(credentials.TLSInfo); ok {
+		if chains := tlsInfo.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+			return caller.Identity{Kind: caller.KindSubject, Value: chains[0][0].Subject.String()}
+		}
+	}
+	address := ""
+	if callerPeer.Addr != nil {
+		address = callerPeer.Addr.String()
+		// the port changes with every connection of the client
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+	}
+	return caller.Identity{Kind: caller.KindAddress, Value: address}
+}
+
+// sessionOwner returns the owner of the sessions created by the caller, the key of its identity
+func (server *ImageAnalysisServiceServer) sessionOwner(ctx context.Context) string {
+	return clientIdentity(ctx, server.apiKeys).Key()
+}
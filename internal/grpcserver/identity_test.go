@@ -0,0 +1,84 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+func TestClientIdentity(t *testing.T) {
+	address := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}
+	certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "qd-partner"}}
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected caller.Identity
+	}{
+		{
+			name: "Success_Authenticated",
+			ctx: caller.NewContext(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key")),
+				&caller.Principal{Identity: caller.Identity{Kind: caller.KindToken, Value: "batch-job"}},
+			),
+			expected: caller.Identity{Kind: caller.KindToken, Value: "batch-job"},
+		},
+		{
+			name:     "Success_APIKey",
+			ctx:      metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: address}), metadata.Pairs("x-api-key", "key")),
+			expected: caller.Identity{Kind: caller.KindAPIKey, Value: "key"},
+		},
+		{
+			name: "Success_UnknownAPIKey",
+			ctx: metadata.NewIncomingContext(
+				peer.NewContext(context.Background(), &peer.Peer{Addr: address}),
+				metadata.Pairs("x-api-key", "unknown"),
+			),
+			expected: caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"},
+		},
+		{
+			name: "Success_Subject",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr: address,
+				AuthInfo: credentials.TLSInfo{
+					State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}},
+				},
+			}),
+			expected: caller.Identity{Kind: caller.KindSubject, Value: "CN=qd-partner"},
+		},
+		{
+			name: "Success_UnverifiedCertificate",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr:     address,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}},
+			}),
+			expected: caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"},
+		},
+		{
+			name:     "Success_Address",
+			ctx:      peer.NewContext(context.Background(), &peer.Peer{Addr: address}),
+			expected: caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"},
+		},
+		{
+			name:     "Success_Unknown",
+			ctx:      context.Background(),
+			expected: caller.Identity{Kind: caller.KindAddress},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			apiKeys := caller.NewAPIKeys(config.ClientIdentityConfig{APIKey: "key"})
+			assert.Equal(t, testCase.expected, clientIdentity(testCase.ctx, apiKeys))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quadev-ltd/qd-common/pkg/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"qd-image-analysis-api/internal/auth"
+	"qd-image-analysis-api/internal/caller"
+)
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the values added by the stream interceptors
+func (stream *contextServerStream) Context() context.Context {
+	return stream.ctx
+}
+
+// createStreamLoggerInterceptor is the streaming counterpart of log.CreateLoggerInterceptor.
+// It adds a logger with the correlation ID of the call to the stream context.
+func createStreamLoggerInterceptor(logFactory log.Factoryer) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		logger, err := logFactory.NewLoggerWithCorrelationID(stream.Context())
+		if err != nil {
+			return err
+		}
+		newCtx := context.WithValue(stream.Context(), log.LoggerKey, logger)
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: newCtx})
+	}
+}
+
+// createAuthInterceptor rejects the calls without valid credentials and adds the authenticated caller to the context.
+// It runs after the logger interceptor so that the rejections are logged with the correlation ID of the call.
+func createAuthInterceptor(authenticator auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		newCtx, err := authenticate(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// createStreamAuthInterceptor is the streaming counterpart of createAuthInterceptor
+func createStreamAuthInterceptor(authenticator auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		newCtx, err := authenticate(stream.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: newCtx})
+	}
+}
+
+// authenticate returns the context carrying the authenticated caller, the reason of a rejection is only logged
+func authenticate(ctx context.Context, authenticator auth.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := authenticator.Authenticate(ctx, md)
+	if err == nil {
+		return caller.NewContext(ctx, principal), nil
+	}
+	message := "Invalid credentials"
+	if errors.Is(err, auth.ErrNoCredentials) {
+		message = "Missing credentials"
+	}
+	if logger, loggerErr := log.GetLoggerFromContext(ctx); loggerErr == nil {
+		logger.Warn(fmt.Sprintf("Rejected unauthenticated call to %s: %v", method, err))
+	}
+	return nil, status.Error(codes.Unauthenticated, message)
+}
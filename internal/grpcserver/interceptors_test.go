@@ -0,0 +1,106 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	commonLog "github.com/quadev-ltd/qd-common/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"qd-image-analysis-api/internal/auth"
+	authMock "qd-image-analysis-api/internal/auth/mock"
+	"qd-image-analysis-api/internal/caller"
+)
+
+// contextStream is a server stream only carrying a context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	principal := &caller.Principal{
+		Identity: caller.Identity{Kind: caller.KindToken, Value: "batch-job"},
+		Scopes:   []string{"images:analyze"},
+	}
+	testCases := []struct {
+		name              string
+		authenticateError error
+		expectedCode      codes.Code
+		expectedMessage   string
+	}{
+		{
+			name:         "Success",
+			expectedCode: codes.OK,
+		},
+		{
+			name:              "Error_NoCredentials",
+			authenticateError: auth.ErrNoCredentials,
+			expectedCode:      codes.Unauthenticated,
+			expectedMessage:   "Missing credentials",
+		},
+		{
+			name:              "Error_InvalidCredentials",
+			authenticateError: errors.New("invalid bearer token: token is expired"),
+			expectedCode:      codes.Unauthenticated,
+			expectedMessage:   "Invalid credentials",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			md := metadata.Pairs("authorization", "Bearer token")
+			logger := commonLog.NewLogFactory("test").NewLogger()
+			ctx := metadata.NewIncomingContext(context.WithValue(context.Background(), commonLog.LoggerKey, logger), md)
+			mockAuthenticator := authMock.NewMockAuthenticator(ctrl)
+			authenticated := principal
+			if testCase.authenticateError != nil {
+				authenticated = nil
+			}
+			mockAuthenticator.EXPECT().Authenticate(gomock.Any(), md).Return(authenticated, testCase.authenticateError).Times(2)
+
+			var handledIdentity caller.Identity
+			_, err := createAuthInterceptor(mockAuthenticator)(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: "/image_analysis.ImageAnalysisService/ProcessImageAndPrompt"},
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					handledIdentity = clientIdentity(ctx, nil)
+					return nil, nil
+				},
+			)
+			assert.Equal(t, testCase.expectedCode, status.Code(err))
+			streamErr := createStreamAuthInterceptor(mockAuthenticator)(
+				nil,
+				&contextStream{ctx: ctx},
+				&grpc.StreamServerInfo{FullMethod: "/image_analysis.ImageAnalysisService/ProcessImageAndPromptStream"},
+				func(_ interface{}, stream grpc.ServerStream) error {
+					streamPrincipal, ok := caller.FromContext(stream.Context())
+					assert.True(t, ok)
+					assert.Same(t, principal, streamPrincipal)
+					return nil
+				},
+			)
+			assert.Equal(t, testCase.expectedCode, status.Code(streamErr))
+
+			if testCase.expectedCode != codes.OK {
+				assert.Equal(t, testCase.expectedMessage, status.Convert(err).Message())
+				assert.Equal(t, testCase.expectedMessage, status.Convert(streamErr).Message())
+				return
+			}
+			assert.Equal(t, principal.Identity, handledIdentity)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/quadev-ltd/qd-common/pkg/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/budget"
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/service"
+)
+
+// errorReasonBudgetExhausted is the reason of the errors returned once a budget of the tenant is spent
+const errorReasonBudgetExhausted = "BUDGET_EXHAUSTED"
+
+// checkRateLimit counts the request against the rate limit of the caller.
+// Throttled callers are told when to retry by the retry-after header and the retry info of the status.
+// Requests are allowed when the rate limiter fails so that its outage does not take the service down.
+func (server *ImageAnalysisServiceServer) checkRateLimit(ctx context.Context, logger log.Loggerer) error {
+	identity := clientIdentity(ctx, server.apiKeys)
+	tier := ""
+	if principal, ok := caller.FromContext(ctx); ok {
+		tier = principal.Tier
+	}
+	allowed, retryAfter, err := server.rateLimiter.Allow(ctx, identity, tier)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Failed to check the rate limit of %s, allowing the request", identity))
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+	logger.Error(nil, fmt.Sprintf("Too many requests from %s", identity))
+	return newResourceExhaustedError(ctx, logger, "Too many requests", retryAfter, nil)
+}
+
+// checkBudget returns the tenant of the caller once checked that none of its budgets is spent,
+// it returns an empty tenant when there are no budgets.
+// Requests are allowed when the usage cannot be read.
+func (server *ImageAnalysisServiceServer) checkBudget(ctx context.Context, logger log.Loggerer) (string, error) {
+	if server.budgets == nil {
+		return "", nil
+	}
+	tenant := server.tenantOf(ctx)
+	err := server.budgets.Check(ctx, tenant)
+	var exhausted *budget.ExhaustedError
+	switch {
+	case errors.As(err, &exhausted):
+		logger.Warn(fmt.Sprintf("Rejected request: %v", exhausted))
+		return "", newResourceExhaustedError(
+			ctx,
+			logger,
+			fmt.Sprintf("The %s budget is spent", exhausted.Budget),
+			time.Until(exhausted.ResetsAt),
+			&errdetails.ErrorInfo{
+				Reason:   errorReasonBudgetExhausted,
+				Domain:   errorDomain,
+				Metadata: map[string]string{"tenant": tenant, "budget": exhausted.Budget},
+			},
+		)
+	case err != nil:
+		logger.Error(err, fmt.Sprintf("Failed to check the budget of tenant %s, allowing the request", tenant))
+	}
+	return tenant, nil
+}
+
+// tenantOf returns the tenant of the authenticated caller, otherwise the tenant configured for the client
+func (server *ImageAnalysisServiceServer) tenantOf(ctx context.Context) string {
+	if principal, ok := caller.FromContext(ctx); ok && principal.Tenant != "" {
+		return principal.Tenant
+	}
+	return server.budgets.TenantOf(clientIdentity(ctx, server.apiKeys))
+}
+
+// recordUsage adds the tokens and the estimated cost of the result to the usage of the tenant,
+// the usage is recorded even when the caller has cancelled the request
+func (server *ImageAnalysisServiceServer) recordUsage(ctx context.Context, logger log.Loggerer, tenant string, result *ai.Result) {
+	if server.budgets == nil {
+		return
+	}
+	usage := budget.Usage{Tokens: int64(result.Usage.TotalTokens), Cost: result.EstimatedCost}
+	if err := server.budgets.Record(context.WithoutCancel(ctx), tenant, usage); err != nil {
+		logger.Error(err, fmt.Sprintf("Failed to record the usage of tenant %s", tenant))
+	}
+}
+
+// recordFailedUsage records the usage of the analyses made before the request failed
+// and returns the error the request failed with
+func (server *ImageAnalysisServiceServer) recordFailedUsage(ctx context.Context, logger log.Loggerer, tenant string, err error) error {
+	usageErr, ok := err.(*service.UsageError)
+	if !ok {
+		return err
+	}
+	server.recordUsage(ctx, logger, tenant, &ai.Result{Usage: usageErr.Usage, EstimatedCost: usageErr.EstimatedCost})
+	return usageErr.Err
+}
+
+// newResourceExhaustedError tells the caller when to retry by the retry-after header and the retry info of the status,
+// the error info is attached to the status as well when it is set
+func newResourceExhaustedError(
+	ctx context.Context,
+	logger log.Loggerer,
+	message string,
+	retryAfter time.Duration,
+	errorInfo *errdetails.ErrorInfo,
+) error {
+	retryAfterSeconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadataKey, retryAfterSeconds)); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to set the retry-after header: %v", err))
+	}
+	resourceExhausted := status.New(codes.ResourceExhausted, message)
+	details := []protoadapt.MessageV1{&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}}
+	if errorInfo != nil {
+		details = append(details, errorInfo)
+	}
+	detailed, err := resourceExhausted.WithDetails(details...)
+	if err != nil {
+		logger.Error(err, "Failed to attach the retry delay to the status")
+		return resourceExhausted.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,312 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	commonLog "github.com/quadev-ltd/qd-common/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/budget"
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/ratelimit"
+	ratelimitMock "qd-image-analysis-api/internal/ratelimit/mock"
+	"qd-image-analysis-api/internal/service"
+	"qd-image-analysis-api/internal/service/mock"
+	pb "qd-image-analysis-api/pb/gen/go/pb_image_analysis"
+)
+
+func TestProcessImageAndPrompt_Budget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{Default: config.BudgetLimitsConfig{DailyTokens: 500}})
+	assert.NoError(t, err)
+	unlimited, err := ratelimit.NewMemoryLimiter(&config.RateLimitConfig{
+		DefaultTier: "unlimited",
+		Tiers:       map[string]config.RateLimitTierConfig{"unlimited": {}},
+	})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, unlimited, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{
+			Candidates:    []ai.Candidate{{Text: "success"}},
+			Usage:         ai.Usage{TotalTokens: 500},
+			EstimatedCost: 0.25,
+		}, nil)
+
+	_, err = server.ProcessImageAndPrompt(ctx, request)
+	assert.NoError(t, err)
+
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "address:", usage.Tenant)
+	assert.Equal(t, int64(500), usage.Daily.UsedTokens)
+	assert.Equal(t, 0.25, usage.Daily.UsedCost)
+	assert.Equal(t, int64(500), usage.Daily.TokenLimit)
+	assert.Equal(t, int64(0), usage.Daily.RemainingTokens)
+	assert.Equal(t, int64(500), usage.Monthly.UsedTokens)
+	assert.Equal(t, int64(0), usage.Monthly.TokenLimit)
+
+	transportStream := &headerTransportStream{}
+	response, err := server.ProcessImageAndPrompt(grpc.NewContextWithServerTransportStream(ctx, transportStream), request)
+
+	assert.Nil(t, response)
+	rejection, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, rejection.Code())
+	assert.Equal(t, "The daily token budget is spent", rejection.Message())
+	assert.Len(t, rejection.Details(), 2)
+	retryInfo, ok := rejection.Details()[0].(*errdetails.RetryInfo)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Until(time.Unix(usage.Daily.ResetsAt, 0)), retryInfo.RetryDelay.AsDuration(), float64(time.Second))
+	errorInfo, ok := rejection.Details()[1].(*errdetails.ErrorInfo)
+	assert.True(t, ok)
+	assert.Equal(t, errorReasonBudgetExhausted, errorInfo.Reason)
+	assert.Equal(t, errorDomain, errorInfo.Domain)
+	assert.Equal(t, map[string]string{"tenant": "address:", "budget": "daily token"}, errorInfo.Metadata)
+	assert.Len(t, transportStream.header.Get("retry-after"), 1)
+}
+
+func TestProcessImageAndPrompt_UnknownAPIKeysShareTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{Default: config.BudgetLimitsConfig{DailyTokens: 100}})
+	assert.NoError(t, err)
+	unlimited, err := ratelimit.NewMemoryLimiter(&config.RateLimitConfig{
+		DefaultTier: "unlimited",
+		Tiers:       map[string]config.RateLimitTierConfig{"unlimited": {}},
+	})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, unlimited, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := peer.NewContext(
+		context.WithValue(context.Background(), commonLog.LoggerKey, logger),
+		&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}},
+	)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "success"}}, Usage: ai.Usage{TotalTokens: 100}}, nil)
+
+	_, err = server.ProcessImageAndPrompt(metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "first")), request)
+	assert.NoError(t, err)
+	_, err = server.ProcessImageAndPrompt(metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "second")), request)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "address:10.0.0.1", usage.Tenant)
+}
+
+func TestProcessImageAndPromptStream_RecordsUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{})
+	assert.NoError(t, err)
+	unlimited, err := ratelimit.NewMemoryLimiter(&config.RateLimitConfig{
+		DefaultTier: "unlimited",
+		Tiers:       map[string]config.RateLimitTierConfig{"unlimited": {}},
+	})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, unlimited, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPromptStream(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ []ai.Image,
+			_ string,
+			_ *service.AnalysisOptions,
+			send func(chunk string) error,
+		) (*ai.Result, error) {
+			if err := send("streamed"); err != nil {
+				return nil, err
+			}
+			return &ai.Result{
+				Candidates:    []ai.Candidate{{Text: "streamed"}},
+				Usage:         ai.Usage{TotalTokens: 120},
+				EstimatedCost: 0.05,
+			}, nil
+		})
+
+	stream := &fakeStream{ctx: ctx}
+	err = server.ProcessImageAndPromptStream(request, stream)
+	assert.NoError(t, err)
+	assert.Len(t, stream.responses, 1)
+
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120), usage.Daily.UsedTokens)
+	assert.Equal(t, 0.05, usage.Daily.UsedCost)
+	assert.Equal(t, int64(120), usage.Monthly.UsedTokens)
+}
+
+func TestProcessImageAndPromptStream_RecordsUsageWhenCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{})
+	assert.NoError(t, err)
+	unlimited, err := ratelimit.NewMemoryLimiter(&config.RateLimitConfig{
+		DefaultTier: "unlimited",
+		Tiers:       map[string]config.RateLimitTierConfig{"unlimited": {}},
+	})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, unlimited, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPromptStream(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ []ai.Image,
+			_ string,
+			_ *service.AnalysisOptions,
+			send func(chunk string) error,
+		) (*ai.Result, error) {
+			if err := send("streamed"); err != nil {
+				return nil, err
+			}
+			// the client cancels the call before the last chunk
+			cancel()
+			return nil, &service.UsageError{Err: context.Canceled, Usage: ai.Usage{TotalTokens: 80}, EstimatedCost: 0.03}
+		})
+
+	stream := &fakeStream{ctx: streamCtx}
+	err = server.ProcessImageAndPromptStream(request, stream)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Len(t, stream.responses, 1)
+
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(80), usage.Daily.UsedTokens)
+	assert.Equal(t, 0.03, usage.Daily.UsedCost)
+}
+
+func TestProcessImageAndPrompt_RecordsUsageOfFailedAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, nil, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &service.UsageError{
+			Err:           errors.New("response does not satisfy schema invoice after retry"),
+			Usage:         ai.Usage{TotalTokens: 220},
+			EstimatedCost: 0.1,
+		})
+
+	_, err = server.ProcessImageAndPrompt(ctx, request)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(220), usage.Daily.UsedTokens)
+	assert.Equal(t, 0.1, usage.Daily.UsedCost)
+}
+
+// fakeStream collects the responses streamed by ProcessImageAndPromptStream
+type fakeStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*pb.ImagePromptStreamResponse
+}
+
+func (stream *fakeStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *fakeStream) Send(response *pb.ImagePromptStreamResponse) error {
+	stream.responses = append(stream.responses, response)
+	return nil
+}
+
+func TestGetUsage_BudgetsNotEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := NewImageAnalysisServiceServer(mock.NewMockImageAnalysisServicer(ctrl), nil, DefaultMaxUploadSize, nil, nil, nil)
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	ctx := context.WithValue(context.Background(), commonLog.LoggerKey, logger)
+
+	response, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestProcessImageAndPrompt_PrincipalTenantAndTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tracker, err := budget.New(&config.BudgetsConfig{})
+	assert.NoError(t, err)
+	mockService := mock.NewMockImageAnalysisServicer(ctrl)
+	mockRateLimiter := ratelimitMock.NewMockRateLimiter(ctrl)
+	server := NewImageAnalysisServiceServer(mockService, nil, DefaultMaxUploadSize, mockRateLimiter, tracker, nil)
+
+	logger := commonLog.NewLogFactory("test").NewLogger()
+	identity := caller.Identity{Kind: caller.KindAPIKey, Value: "qdk_0123456789abcdef_secret"}
+	ctx := caller.NewContext(
+		context.WithValue(context.Background(), commonLog.LoggerKey, logger),
+		&caller.Principal{Identity: identity, Tenant: "batch", Tier: "premium"},
+	)
+	request := &pb.ImagePromptRequest{ImageData: []byte("test-image-data"), Prompt: "test prompt", MimeType: "image/png"}
+
+	mockRateLimiter.EXPECT().Allow(gomock.Any(), identity, "premium").Return(true, time.Duration(0), nil)
+	mockService.EXPECT().
+		ProcessImageAndPrompt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "success"}}, Usage: ai.Usage{TotalTokens: 42}}, nil)
+
+	_, err = server.ProcessImageAndPrompt(ctx, request)
+	assert.NoError(t, err)
+
+	usage, err := server.GetUsage(ctx, &pb.GetUsageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "batch", usage.Tenant)
+	assert.Equal(t, int64(42), usage.Daily.UsedTokens)
+}
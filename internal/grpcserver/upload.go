@@ -0,0 +1,81 @@
+package grpcserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "qd-image-analysis-api/pb/gen/go/pb_image_analysis"
+)
+
+// DefaultMaxUploadSize is the image size limit used when none is configured
+const DefaultMaxUploadSize = 20 * 1024 * 1024
+
+type imageUpload struct {
+	imageData []byte
+	mimeType  string
+	prompt    string
+	options   *pb.AnalysisOptions
+}
+
+// receiveImageUpload reads the metadata message followed by the image chunks of an upload stream.
+// It enforces the declared and maximum sizes and verifies the SHA-256 checksum of the reassembled image.
+func receiveImageUpload(stream pb.ImageAnalysisService_UploadImageAndPromptServer, maxSize int64) (*imageUpload, error) {
+	request, err := stream.Recv()
+	if err == io.EOF {
+		return nil, status.Error(codes.InvalidArgument, "no upload metadata provided")
+	}
+	if err != nil {
+		return nil, err
+	}
+	metadata := request.GetMetadata()
+	switch {
+	case metadata == nil:
+		return nil, status.Error(codes.InvalidArgument, "first upload message must contain the metadata")
+	case metadata.TotalSize <= 0:
+		return nil, status.Error(codes.InvalidArgument, "upload total size must be positive")
+	case metadata.TotalSize > maxSize:
+		return nil, status.Errorf(codes.InvalidArgument, "upload total size %d exceeds the limit of %d bytes", metadata.TotalSize, maxSize)
+	case metadata.Sha256 == "":
+		return nil, status.Error(codes.InvalidArgument, "upload sha256 checksum is required")
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, metadata.TotalSize))
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if request.GetMetadata() != nil {
+			return nil, status.Error(codes.InvalidArgument, "upload metadata can only be sent once")
+		}
+		chunk := request.GetChunk()
+		if int64(buffer.Len()+len(chunk)) > metadata.TotalSize {
+			return nil, status.Errorf(codes.InvalidArgument, "upload exceeds the declared total size of %d bytes", metadata.TotalSize)
+		}
+		buffer.Write(chunk)
+	}
+
+	if int64(buffer.Len()) != metadata.TotalSize {
+		return nil, status.Errorf(codes.InvalidArgument, "upload received %d of %d bytes", buffer.Len(), metadata.TotalSize)
+	}
+	checksum := sha256.Sum256(buffer.Bytes())
+	if hex.EncodeToString(checksum[:]) != strings.ToLower(metadata.Sha256) {
+		return nil, status.Error(codes.InvalidArgument, "upload sha256 checksum mismatch")
+	}
+
+	return &imageUpload{
+		imageData: buffer.Bytes(),
+		mimeType:  metadata.MimeType,
+		prompt:    metadata.Prompt,
+		options:   metadata.Options,
+	}, nil
+}
@@ -0,0 +1,455 @@
+package imageformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	heicBrands = []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs"}
+	heifBrands = []string{"mif1", "msf1"}
+	// avifBrands are HEIF compatible but hold AV1 images, which are not supported
+	avifBrands = []string{"avif", "avis"}
+)
+
+// box is an ISO base media file format box
+type box struct {
+	kind    string
+	payload []byte
+	// offset is the position of the payload in the data the box was read from
+	offset int
+}
+
+// readBoxes splits the data into the boxes it is made of
+func readBoxes(data []byte) ([]box, error) {
+	var boxes []box
+	offset := 0
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("truncated box header")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		kind := string(data[4:8])
+		headerSize := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("truncated box header")
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			headerSize = 16
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid size %d of box %q", size, kind)
+		}
+		boxes = append(boxes, box{kind: kind, payload: data[headerSize:size], offset: offset + int(headerSize)})
+		data = data[size:]
+		offset += int(size)
+	}
+	return boxes, nil
+}
+
+func findBox(boxes []box, kind string) (box, bool) {
+	for _, candidate := range boxes {
+		if candidate.kind == kind {
+			return candidate, true
+		}
+	}
+	return box{}, false
+}
+
+// detectHEIF recognises HEIF files from the brands of their leading ftyp box
+func detectHEIF(data []byte) (Format, bool) {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return Format{}, false
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 16 || size > len(data) {
+		return Format{}, false
+	}
+	majorBrand := string(data[8:12])
+	if containsBrand(avifBrands, majorBrand) {
+		return Format{}, false
+	}
+	brands := []string{majorBrand}
+	for offset := 16; offset+4 <= size; offset += 4 {
+		brands = append(brands, string(data[offset:offset+4]))
+	}
+	for _, brand := range brands {
+		if containsBrand(heicBrands, brand) {
+			return HEIC, true
+		}
+	}
+	for _, brand := range brands {
+		if containsBrand(heifBrands, brand) {
+			return HEIF, true
+		}
+	}
+	return Format{}, false
+}
+
+func containsBrand(brands []string, brand string) bool {
+	for _, candidate := range brands {
+		if candidate == brand {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeHEIFDimensions returns the dimensions of the primary item of a HEIF file,
+// found in the image spatial extents property associated with it
+func decodeHEIFDimensions(data []byte) (int, int, error) {
+	boxes, err := readBoxes(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	meta, ok := findBox(boxes, "meta")
+	if !ok || len(meta.payload) < 4 {
+		return 0, 0, errors.New("missing meta box")
+	}
+	// meta is a full box, its children follow the version and flags
+	metaBoxes, err := readBoxes(meta.payload[4:])
+	if err != nil {
+		return 0, 0, err
+	}
+	primaryItemID, err := readPrimaryItemID(metaBoxes)
+	if err != nil {
+		return 0, 0, err
+	}
+	itemProperties, ok := findBox(metaBoxes, "iprp")
+	if !ok {
+		return 0, 0, errors.New("missing iprp box")
+	}
+	propertyBoxes, err := readBoxes(itemProperties.payload)
+	if err != nil {
+		return 0, 0, err
+	}
+	container, ok := findBox(propertyBoxes, "ipco")
+	if !ok {
+		return 0, 0, errors.New("missing ipco box")
+	}
+	properties, err := readBoxes(container.payload)
+	if err != nil {
+		return 0, 0, err
+	}
+	associations, ok := findBox(propertyBoxes, "ipma")
+	if !ok {
+		return 0, 0, errors.New("missing ipma box")
+	}
+	propertyIndexes, err := readItemPropertyIndexes(associations.payload, primaryItemID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, index := range propertyIndexes {
+		// property indexes start at 1, 0 means no property
+		if index == 0 || index > len(properties) || properties[index-1].kind != "ispe" {
+			continue
+		}
+		extents := properties[index-1].payload
+		if len(extents) < 12 {
+			return 0, 0, errors.New("truncated ispe box")
+		}
+		return int(binary.BigEndian.Uint32(extents[4:])), int(binary.BigEndian.Uint32(extents[8:])), nil
+	}
+	return 0, 0, fmt.Errorf("no spatial extents for primary item %d", primaryItemID)
+}
+
+func readPrimaryItemID(metaBoxes []box) (uint32, error) {
+	primaryItem, ok := findBox(metaBoxes, "pitm")
+	if !ok || len(primaryItem.payload) < 6 {
+		return 0, errors.New("missing pitm box")
+	}
+	if primaryItem.payload[0] == 0 {
+		return uint32(binary.BigEndian.Uint16(primaryItem.payload[4:])), nil
+	}
+	if len(primaryItem.payload) < 8 {
+		return 0, errors.New("truncated pitm box")
+	}
+	return binary.BigEndian.Uint32(primaryItem.payload[4:]), nil
+}
+
+// readItemPropertyIndexes returns the indexes of the properties the ipma box associates with the item
+func readItemPropertyIndexes(payload []byte, itemID uint32) ([]int, error) {
+	if len(payload) < 8 {
+		return nil, errors.New("truncated ipma box")
+	}
+	version := payload[0]
+	largeIndexes := payload[3]&1 == 1
+	entryCount := binary.BigEndian.Uint32(payload[4:])
+	reader := payload[8:]
+	for entry := uint32(0); entry < entryCount; entry++ {
+		var entryItemID uint32
+		if version < 1 {
+			if len(reader) < 3 {
+				return nil, errors.New("truncated ipma box")
+			}
+			entryItemID = uint32(binary.BigEndian.Uint16(reader))
+			reader = reader[2:]
+		} else {
+			if len(reader) < 5 {
+				return nil, errors.New("truncated ipma box")
+			}
+			entryItemID = binary.BigEndian.Uint32(reader)
+			reader = reader[4:]
+		}
+		associationCount := int(reader[0])
+		reader = reader[1:]
+		indexes := make([]int, 0, associationCount)
+		for association := 0; association < associationCount; association++ {
+			// the most significant bit of every association flags the property as essential
+			if largeIndexes {
+				if len(reader) < 2 {
+					return nil, errors.New("truncated ipma box")
+				}
+				indexes = append(indexes, int(binary.BigEndian.Uint16(reader)&0x7FFF))
+				reader = reader[2:]
+			} else {
+				if len(reader) < 1 {
+					return nil, errors.New("truncated ipma box")
+				}
+				indexes = append(indexes, int(reader[0]&0x7F))
+				reader = reader[1:]
+			}
+		}
+		if entryItemID == itemID {
+			return indexes, nil
+		}
+	}
+	return nil, fmt.Errorf("no properties for item %d", itemID)
+}
+
+// heifXMPContentType is the content type of the mime items holding XMP metadata
+const heifXMPContentType = "application/rdf+xml"
+
+// stripHEIFMetadata overwrites the EXIF and XMP items of a HEIF file with zeros,
+// keeping the size of the file so that the locations of the other items remain valid
+func stripHEIFMetadata(data []byte) ([]byte, error) {
+	boxes, err := readBoxes(data)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := findBox(boxes, "meta")
+	if !ok || len(meta.payload) < 4 {
+		return nil, errors.New("missing meta box")
+	}
+	metaBoxes, err := readBoxes(meta.payload[4:])
+	if err != nil {
+		return nil, err
+	}
+	itemInfo, ok := findBox(metaBoxes, "iinf")
+	if !ok {
+		return data, nil
+	}
+	metadataItemIDs, err := readMetadataItemIDs(itemInfo.payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadataItemIDs) == 0 {
+		return data, nil
+	}
+	itemLocation, ok := findBox(metaBoxes, "iloc")
+	if !ok {
+		return nil, errors.New("missing iloc box")
+	}
+	extents, err := readItemExtents(itemLocation.payload, metadataItemIDs)
+	if err != nil {
+		return nil, err
+	}
+	stripped := bytes.Clone(data)
+	for _, extent := range extents {
+		// the items are stored in the file or in the idat box of the meta box
+		target := stripped
+		if extent.constructionMethod == 1 {
+			itemData, ok := findBox(metaBoxes, "idat")
+			if !ok {
+				return nil, errors.New("missing idat box")
+			}
+			start := meta.offset + 4 + itemData.offset
+			target = stripped[start : start+len(itemData.payload)]
+		}
+		end := extent.offset + extent.length
+		// a length of 0 extends the item to the end of its data
+		if extent.length == 0 {
+			end = uint64(len(target))
+		}
+		if extent.offset > uint64(len(target)) || end > uint64(len(target)) || end < extent.offset {
+			return nil, fmt.Errorf("invalid extent of item %d", extent.itemID)
+		}
+		clear(target[extent.offset:end])
+	}
+	return stripped, nil
+}
+
+// readMetadataItemIDs returns the identifiers of the EXIF and XMP items the iinf box describes
+func readMetadataItemIDs(payload []byte) (map[uint32]bool, error) {
+	reader := &boxReader{data: payload}
+	version := reader.uint(1)
+	// the flags follow the version of full boxes
+	reader.bytes(3)
+	if version == 0 {
+		reader.uint(2)
+	} else {
+		reader.uint(4)
+	}
+	if reader.err != nil {
+		return nil, errors.New("truncated iinf box")
+	}
+	itemInfos, err := readBoxes(reader.data)
+	if err != nil {
+		return nil, err
+	}
+	itemIDs := make(map[uint32]bool)
+	for _, itemInfo := range itemInfos {
+		if itemInfo.kind != "infe" {
+			continue
+		}
+		reader := &boxReader{data: itemInfo.payload}
+		version := reader.uint(1)
+		reader.bytes(3)
+		if reader.err != nil {
+			return nil, errors.New("truncated infe box")
+		}
+		// only the item info entries of version 2 and 3 have a type
+		if version < 2 {
+			continue
+		}
+		idSize := 2
+		if version == 3 {
+			idSize = 4
+		}
+		itemID := uint32(reader.uint(idSize))
+		// the protection index precedes the type
+		reader.uint(2)
+		itemType := string(reader.bytes(4))
+		if reader.err != nil {
+			return nil, errors.New("truncated infe box")
+		}
+		switch itemType {
+		case "Exif":
+			itemIDs[itemID] = true
+		case "mime":
+			// the name of the item precedes its content type, both are null terminated
+			_, contentType, _ := bytes.Cut(reader.data, []byte{0})
+			contentType, _, _ = bytes.Cut(contentType, []byte{0})
+			if string(contentType) == heifXMPContentType {
+				itemIDs[itemID] = true
+			}
+		}
+	}
+	return itemIDs, nil
+}
+
+// itemExtent is a part of the data of an item
+type itemExtent struct {
+	itemID             uint32
+	constructionMethod int
+	offset             uint64
+	length             uint64
+}
+
+// readItemExtents returns the extents of the items the iloc box locates
+func readItemExtents(payload []byte, itemIDs map[uint32]bool) ([]itemExtent, error) {
+	reader := &boxReader{data: payload}
+	version := reader.uint(1)
+	reader.bytes(3)
+	// the sizes of the offsets, lengths, base offsets and indexes are packed in four bits each
+	sizes := reader.uint(2)
+	offsetSize := int(sizes >> 12)
+	lengthSize := int(sizes >> 8 & 0x0F)
+	baseOffsetSize := int(sizes >> 4 & 0x0F)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0F)
+	}
+	var itemCount uint64
+	if version < 2 {
+		itemCount = reader.uint(2)
+	} else {
+		itemCount = reader.uint(4)
+	}
+	var extents []itemExtent
+	for item := uint64(0); item < itemCount && reader.err == nil; item++ {
+		var itemID uint32
+		if version < 2 {
+			itemID = uint32(reader.uint(2))
+		} else {
+			itemID = uint32(reader.uint(4))
+		}
+		constructionMethod := 0
+		if version == 1 || version == 2 {
+			constructionMethod = int(reader.uint(2) & 0x0F)
+		}
+		dataReferenceIndex := reader.uint(2)
+		baseOffset := reader.uint(baseOffsetSize)
+		extentCount := reader.uint(2)
+		if indexSize+offsetSize+lengthSize == 0 {
+			// the extents take no space and all locate the whole data
+			extentCount = min(extentCount, 1)
+		}
+		for extent := uint64(0); extent < extentCount && reader.err == nil; extent++ {
+			reader.uint(indexSize)
+			offset := reader.uint(offsetSize)
+			length := reader.uint(lengthSize)
+			// items of other files are not part of the data
+			if !itemIDs[itemID] || dataReferenceIndex != 0 {
+				continue
+			}
+			if constructionMethod > 1 {
+				return nil, fmt.Errorf("unsupported construction method %d of item %d", constructionMethod, itemID)
+			}
+			extents = append(extents, itemExtent{
+				itemID:             itemID,
+				constructionMethod: constructionMethod,
+				offset:             baseOffset + offset,
+				length:             length,
+			})
+		}
+	}
+	if reader.err != nil {
+		return nil, errors.New("truncated iloc box")
+	}
+	return extents, nil
+}
+
+// boxReader reads the big endian fields of a box payload, remembering whether it was truncated
+type boxReader struct {
+	data []byte
+	err  error
+}
+
+// uint reads an unsigned integer of 0, 1, 2, 4 or 8 bytes, the fields of 0 bytes are absent and read as 0
+func (reader *boxReader) uint(size int) uint64 {
+	field := reader.bytes(size)
+	switch {
+	case reader.err != nil || size == 0:
+		return 0
+	case size == 1:
+		return uint64(field[0])
+	case size == 2:
+		return uint64(binary.BigEndian.Uint16(field))
+	case size == 4:
+		return uint64(binary.BigEndian.Uint32(field))
+	case size == 8:
+		return binary.BigEndian.Uint64(field)
+	}
+	reader.err = fmt.Errorf("unsupported field size %d", size)
+	return 0
+}
+
+func (reader *boxReader) bytes(size int) []byte {
+	if reader.err != nil {
+		return nil
+	}
+	if size > len(reader.data) {
+		reader.err = errors.New("truncated box")
+		return nil
+	}
+	field := reader.data[:size]
+	reader.data = reader.data[size:]
+	return field
+}
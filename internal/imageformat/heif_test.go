@@ -0,0 +1,84 @@
+package imageformat
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBox(kind string, payload ...[]byte) []byte {
+	size := 8
+	for _, part := range payload {
+		size += len(part)
+	}
+	data := binary.BigEndian.AppendUint32(nil, uint32(size))
+	data = append(data, kind...)
+	for _, part := range payload {
+		data = append(data, part...)
+	}
+	return data
+}
+
+func newFullBox(kind string, payload ...[]byte) []byte {
+	return newBox(kind, append([][]byte{{0, 0, 0, 0}}, payload...)...)
+}
+
+func newSpatialExtents(width, height int) []byte {
+	extents := binary.BigEndian.AppendUint32(nil, uint32(width))
+	return newFullBox("ispe", binary.BigEndian.AppendUint32(extents, uint32(height)))
+}
+
+// newHEIF returns a HEIF file whose primary item 1 has the given dimensions
+// and whose thumbnail item 2 is smaller
+func newHEIF(majorBrand string, width, height int) []byte {
+	fileType := newBox("ftyp", []byte(majorBrand), []byte{0, 0, 0, 0}, []byte("mif1"))
+	associations := []byte{
+		0, 0, 0, 2, // entry count
+		0, 2, 1, 0x01, // item 2 has property 1
+		0, 1, 1, 0x82, // item 1 has the essential property 2
+	}
+	meta := newFullBox(
+		"meta",
+		newFullBox("pitm", []byte{0, 1}),
+		newBox(
+			"iprp",
+			newBox("ipco", newSpatialExtents(320, 240), newSpatialExtents(width, height)),
+			newFullBox("ipma", associations),
+		),
+	)
+	return append(append(fileType, meta...), newBox("mdat", []byte{1, 2, 3})...)
+}
+
+func TestDecodeHEIFDimensions(t *testing.T) {
+	t.Run("PrimaryItem", func(t *testing.T) {
+		width, height, err := decodeHEIFDimensions(newHEIF("heic", 4032, 3024))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4032, width)
+		assert.Equal(t, 3024, height)
+	})
+
+	t.Run("MissingMeta", func(t *testing.T) {
+		_, _, err := decodeHEIFDimensions(newBox("ftyp", []byte("heic"), []byte{0, 0, 0, 0}))
+
+		assert.EqualError(t, err, "missing meta box")
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data := newHEIF("heic", 10, 10)
+
+		_, _, err := decodeHEIFDimensions(data[:len(data)-2])
+
+		assert.EqualError(t, err, `invalid size 11 of box "mdat"`)
+	})
+}
+
+func TestInspect_CorruptHEIF(t *testing.T) {
+	data := newHEIF("heic", 10, 10)
+
+	info, err := Inspect(data[:40])
+
+	assert.Nil(t, info)
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
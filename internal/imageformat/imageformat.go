@@ -0,0 +1,145 @@
+package imageformat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	// Registers the decoders of the detected formats with image.DecodeConfig and image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnknownFormat is returned when the content does not start with the signature of a known format
+var ErrUnknownFormat = errors.New("unknown image format")
+
+// ErrCorrupt is returned when the header of a recognised format cannot be decoded
+var ErrCorrupt = errors.New("corrupt image")
+
+// ErrNotDecodable is returned when transcoding an image of a format there is no decoder for
+var ErrNotDecodable = errors.New("image format cannot be decoded")
+
+// Format is an image format recognised from its magic bytes
+type Format struct {
+	Name     string
+	MimeType string
+	// decodable is false for the formats whose header is parsed but whose pixels cannot be decoded
+	decodable bool
+}
+
+// Decodable tells whether images of the format can be decoded and therefore transcoded
+func (format Format) Decodable() bool {
+	return format.decodable
+}
+
+var (
+	// JPEG is the JPEG/JFIF format
+	JPEG = Format{Name: "jpeg", MimeType: "image/jpeg", decodable: true}
+	// PNG is the Portable Network Graphics format
+	PNG = Format{Name: "png", MimeType: "image/png", decodable: true}
+	// WebP is the RIFF based WebP format
+	WebP = Format{Name: "webp", MimeType: "image/webp", decodable: true}
+	// GIF is the Graphics Interchange Format, only the first frame of animations is decoded
+	GIF = Format{Name: "gif", MimeType: "image/gif", decodable: true}
+	// BMP is the Windows bitmap format
+	BMP = Format{Name: "bmp", MimeType: "image/bmp", decodable: true}
+	// TIFF is the Tagged Image File Format
+	TIFF = Format{Name: "tiff", MimeType: "image/tiff", decodable: true}
+	// HEIC is the HEIF container holding HEVC encoded images
+	HEIC = Format{Name: "heic", MimeType: "image/heic"}
+	// HEIF is the HEIF container holding images of other codecs
+	HEIF = Format{Name: "heif", MimeType: "image/heif"}
+)
+
+var formats = []Format{JPEG, PNG, WebP, GIF, BMP, TIFF, HEIC, HEIF}
+
+// ByMimeType returns the format of the mime type
+func ByMimeType(mimeType string) (Format, bool) {
+	for _, format := range formats {
+		if format.MimeType == mimeType {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+type signature struct {
+	magic  []byte
+	format Format
+}
+
+var signatures = []signature{
+	{magic: []byte("\xFF\xD8\xFF"), format: JPEG},
+	{magic: []byte("\x89PNG\r\n\x1a\n"), format: PNG},
+	{magic: []byte("GIF87a"), format: GIF},
+	{magic: []byte("GIF89a"), format: GIF},
+	{magic: []byte("BM"), format: BMP},
+	{magic: []byte("II*\x00"), format: TIFF},
+	{magic: []byte("MM\x00*"), format: TIFF},
+}
+
+// Info describes an image as found in its content
+type Info struct {
+	Format Format
+	Width  int
+	Height int
+}
+
+// Detect returns the format whose magic bytes the content starts with
+func Detect(data []byte) (Format, bool) {
+	for _, signature := range signatures {
+		if bytes.HasPrefix(data, signature.magic) {
+			return signature.format, true
+		}
+	}
+	if len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")) {
+		return WebP, true
+	}
+	return detectHEIF(data)
+}
+
+// Inspect detects the format of the content and decodes its header to find the dimensions of the image.
+// It returns ErrUnknownFormat or an error wrapping ErrCorrupt when the content is not a valid image.
+func Inspect(data []byte) (*Info, error) {
+	format, ok := Detect(data)
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	var width, height int
+	if format.Decodable() {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		width, height = config.Width, config.Height
+	} else {
+		var err error
+		width, height, err = decodeHEIFDimensions(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%w: invalid dimensions %dx%d", ErrCorrupt, width, height)
+	}
+	return &Info{
+		Format: format,
+		Width:  width,
+		Height: height,
+	}, nil
+}
+
+// Transcode decodes the image and encodes it again as a PNG or a JPEG of the given quality,
+// the default JPEG quality is used when it is zero. Only the first frame of animated images is kept.
+func Transcode(data []byte, target Format, quality int) ([]byte, error) {
+	decoded, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(decoded, target, quality)
+}
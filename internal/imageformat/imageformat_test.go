@@ -0,0 +1,207 @@
+package imageformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buffer.Bytes()
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	return buffer.Bytes()
+}
+
+func encode(t *testing.T, encoder func(io.Writer, image.Image) error, width, height int) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	assert.NoError(t, encoder(&buffer, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buffer.Bytes()
+}
+
+func encodeGIF(t *testing.T, width, height int) []byte {
+	t.Helper()
+	return encode(t, func(writer io.Writer, img image.Image) error { return gif.Encode(writer, img, nil) }, width, height)
+}
+
+func encodeTIFF(t *testing.T, width, height int) []byte {
+	t.Helper()
+	return encode(t, func(writer io.Writer, img image.Image) error { return tiff.Encode(writer, img, nil) }, width, height)
+}
+
+// encodeWebPHeader returns the header of a lossless WebP image, enough to decode its dimensions
+func encodeWebPHeader(width, height int) []byte {
+	bitstream := make([]byte, 5)
+	bitstream[0] = 0x2f
+	binary.LittleEndian.PutUint32(bitstream[1:], uint32(width-1)|uint32(height-1)<<14)
+	chunk := append([]byte("VP8L"), binary.LittleEndian.AppendUint32(nil, uint32(len(bitstream)))...)
+	chunk = append(chunk, bitstream...)
+	chunk = append(chunk, 0)
+	riff := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(chunk)))...)
+	riff = append(riff, []byte("WEBP")...)
+	return append(riff, chunk...)
+}
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected Format
+		ok       bool
+	}{
+		{name: "PNG", data: encodePNG(t, 1, 1), expected: PNG, ok: true},
+		{name: "JPEG", data: encodeJPEG(t, 1, 1), expected: JPEG, ok: true},
+		{name: "WebP", data: encodeWebPHeader(1, 1), expected: WebP, ok: true},
+		{name: "GIF", data: encodeGIF(t, 1, 1), expected: GIF, ok: true},
+		{name: "BMP", data: encode(t, bmp.Encode, 1, 1), expected: BMP, ok: true},
+		{name: "TIFF", data: encodeTIFF(t, 1, 1), expected: TIFF, ok: true},
+		{name: "HEIC", data: newHEIF("heic", 1, 1), expected: HEIC, ok: true},
+		{name: "HEIF", data: newHEIF("mif1", 1, 1), expected: HEIF, ok: true},
+		{name: "AVIF", data: newHEIF("avif", 1, 1), ok: false},
+		{name: "Text", data: []byte("not an image"), ok: false},
+		{name: "Empty", data: nil, ok: false},
+		{name: "TruncatedSignature", data: []byte("\x89PN"), ok: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			format, ok := Detect(testCase.data)
+
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.expected, format)
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("PNG", func(t *testing.T) {
+		info, err := Inspect(encodePNG(t, 32, 16))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Info{Format: PNG, Width: 32, Height: 16}, info)
+	})
+
+	t.Run("JPEG", func(t *testing.T) {
+		info, err := Inspect(encodeJPEG(t, 8, 24))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Info{Format: JPEG, Width: 8, Height: 24}, info)
+	})
+
+	t.Run("OtherFormats", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			data     []byte
+			expected *Info
+		}{
+			{name: "WebP", data: encodeWebPHeader(40, 30), expected: &Info{Format: WebP, Width: 40, Height: 30}},
+			{name: "GIF", data: encodeGIF(t, 12, 6), expected: &Info{Format: GIF, Width: 12, Height: 6}},
+			{name: "BMP", data: encode(t, bmp.Encode, 5, 7), expected: &Info{Format: BMP, Width: 5, Height: 7}},
+			{name: "TIFF", data: encodeTIFF(t, 9, 3), expected: &Info{Format: TIFF, Width: 9, Height: 3}},
+			{name: "HEIC", data: newHEIF("heic", 4032, 3024), expected: &Info{Format: HEIC, Width: 4032, Height: 3024}},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				info, err := Inspect(testCase.data)
+
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, info)
+			})
+		}
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		info, err := Inspect([]byte("<svg></svg>"))
+
+		assert.Nil(t, info)
+		assert.Equal(t, ErrUnknownFormat, err)
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		info, err := Inspect([]byte("\x89PNG\r\n\x1a\ngarbage"))
+
+		assert.Nil(t, info)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+}
+
+func TestTranscode(t *testing.T) {
+	t.Run("GIFToPNG", func(t *testing.T) {
+		palette := color.Palette{color.Black, color.White}
+		animation := &gif.GIF{
+			Image: []*image.Paletted{
+				image.NewPaletted(image.Rect(0, 0, 4, 2), palette),
+				image.NewPaletted(image.Rect(0, 0, 4, 2), palette),
+			},
+			Delay: []int{10, 10},
+		}
+		animation.Image[0].SetColorIndex(0, 0, 1)
+		var buffer bytes.Buffer
+		assert.NoError(t, gif.EncodeAll(&buffer, animation))
+
+		transcoded, err := Transcode(buffer.Bytes(), PNG, 0)
+
+		assert.NoError(t, err)
+		decoded, err := png.Decode(bytes.NewReader(transcoded))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 4, 2), decoded.Bounds())
+		red, green, blue, _ := decoded.At(0, 0).RGBA()
+		assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{red, green, blue})
+	})
+
+	t.Run("BMPToJPEG", func(t *testing.T) {
+		transcoded, err := Transcode(encode(t, bmp.Encode, 8, 8), JPEG, 80)
+
+		assert.NoError(t, err)
+		info, err := Inspect(transcoded)
+		assert.NoError(t, err)
+		assert.Equal(t, &Info{Format: JPEG, Width: 8, Height: 8}, info)
+	})
+
+	t.Run("NotDecodable", func(t *testing.T) {
+		transcoded, err := Transcode(newHEIF("heic", 8, 8), PNG, 0)
+
+		assert.Nil(t, transcoded)
+		assert.ErrorIs(t, err, ErrNotDecodable)
+	})
+
+	t.Run("UnsupportedTarget", func(t *testing.T) {
+		transcoded, err := Transcode(encodeTIFF(t, 8, 8), WebP, 0)
+
+		assert.Nil(t, transcoded)
+		assert.EqualError(t, err, "cannot encode images as webp")
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		transcoded, err := Transcode([]byte("GIF89a"), PNG, 0)
+
+		assert.Nil(t, transcoded)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+}
+
+func TestByMimeType(t *testing.T) {
+	format, ok := ByMimeType("image/heic")
+	assert.True(t, ok)
+	assert.Equal(t, HEIC, format)
+
+	_, ok = ByMimeType("application/pdf")
+	assert.False(t, ok)
+}
@@ -0,0 +1,244 @@
+package imageformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Orientation is the EXIF orientation of an image, telling how to transform the stored pixels to display them upright
+type Orientation int
+
+const (
+	// OrientationNormal is the orientation of images stored upright, it is assumed when there is no EXIF orientation
+	OrientationNormal Orientation = 1
+	// OrientationFlipHorizontal is a mirror image
+	OrientationFlipHorizontal Orientation = 2
+	// OrientationRotate180 is an upside down image
+	OrientationRotate180 Orientation = 3
+	// OrientationFlipVertical is an upside down mirror image
+	OrientationFlipVertical Orientation = 4
+	// OrientationTranspose is a mirror image rotated 90 degrees counterclockwise
+	OrientationTranspose Orientation = 5
+	// OrientationRotate90 is an image to rotate 90 degrees clockwise
+	OrientationRotate90 Orientation = 6
+	// OrientationTransverse is a mirror image rotated 90 degrees clockwise
+	OrientationTransverse Orientation = 7
+	// OrientationRotate270 is an image to rotate 90 degrees counterclockwise
+	OrientationRotate270 Orientation = 8
+)
+
+const (
+	jpegMarkerSOI   = 0xD8
+	jpegMarkerSOS   = 0xDA
+	jpegMarkerAPP0  = 0xE0
+	jpegMarkerAPP1  = 0xE1
+	jpegMarkerAPP2  = 0xE2
+	jpegMarkerAPP14 = 0xEE
+	jpegMarkerAPP15 = 0xEF
+	jpegMarkerCOM   = 0xFE
+
+	exifTagOrientation = 0x0112
+
+	// webpFlagEXIF and webpFlagXMP tell in the extended header of WebP images that the metadata chunks are present
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	// pngMetadataChunks hold the EXIF data, the text annotations and the modification time of PNG images
+	pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+	// webpMetadataChunks hold the EXIF and XMP metadata of WebP images
+	webpMetadataChunks = map[string]bool{"EXIF": true, "XMP ": true}
+)
+
+type jpegSegment struct {
+	marker  byte
+	payload []byte
+	// raw is the marker, the length and the payload of the segment
+	raw []byte
+}
+
+// readJPEGSegments returns the segments of a JPEG image preceding its scan data and the offset of the scan
+func readJPEGSegments(data []byte) ([]jpegSegment, int, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != jpegMarkerSOI {
+		return nil, 0, errors.New("missing JPEG start of image")
+	}
+	var segments []jpegSegment
+	offset := 2
+	for {
+		if offset+4 > len(data) || data[offset] != 0xFF {
+			return nil, 0, errors.New("truncated JPEG segment")
+		}
+		marker := data[offset+1]
+		if marker == 0xFF {
+			// fill bytes may precede a marker
+			offset++
+			continue
+		}
+		if marker == jpegMarkerSOS {
+			return segments, offset, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, errors.New("invalid JPEG segment length")
+		}
+		segments = append(segments, jpegSegment{
+			marker:  marker,
+			payload: data[offset+4 : end],
+			raw:     data[offset:end],
+		})
+		offset = end
+	}
+}
+
+// ReadOrientation returns the EXIF orientation of a JPEG image, OrientationNormal when it has none
+func ReadOrientation(data []byte) Orientation {
+	segments, _, err := readJPEGSegments(data)
+	if err != nil {
+		return OrientationNormal
+	}
+	for _, segment := range segments {
+		if segment.marker != jpegMarkerAPP1 || !bytes.HasPrefix(segment.payload, exifHeader) {
+			continue
+		}
+		if orientation, ok := readExifOrientation(segment.payload[len(exifHeader):]); ok {
+			return orientation
+		}
+	}
+	return OrientationNormal
+}
+
+// readExifOrientation reads the orientation tag of the first image file directory of the EXIF TIFF structure
+func readExifOrientation(tiff []byte) (Orientation, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var byteOrder binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return 0, false
+	}
+	offset := int(byteOrder.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 0, false
+	}
+	entryCount := int(byteOrder.Uint16(tiff[offset:]))
+	for entry := 0; entry < entryCount; entry++ {
+		entryOffset := offset + 2 + entry*12
+		if entryOffset+12 > len(tiff) {
+			return 0, false
+		}
+		if byteOrder.Uint16(tiff[entryOffset:]) != exifTagOrientation {
+			continue
+		}
+		orientation := Orientation(byteOrder.Uint16(tiff[entryOffset+8:]))
+		if orientation < OrientationNormal || orientation > OrientationRotate270 {
+			return 0, false
+		}
+		return orientation, true
+	}
+	return 0, false
+}
+
+// StripMetadata removes the EXIF and XMP metadata, including the GPS location, the comments
+// and the text annotations of JPEG, PNG, WebP and HEIF images without decoding them.
+// The metadata items of HEIF images are overwritten with zeros rather than removed.
+// Colour profiles are kept and images of other formats are returned as they are.
+func StripMetadata(data []byte) ([]byte, error) {
+	format, _ := Detect(data)
+	switch format {
+	case JPEG:
+		return stripJPEGMetadata(data)
+	case PNG:
+		return stripPNGMetadata(data)
+	case WebP:
+		return stripWebPMetadata(data)
+	case HEIC, HEIF:
+		return stripHEIFMetadata(data)
+	}
+	return data, nil
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	segments, scanOffset, err := readJPEGSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, data[:2]...)
+	for _, segment := range segments {
+		// APP0 holds the JFIF header, APP2 the ICC profile and APP14 the Adobe colour transform
+		isMetadata := segment.marker == jpegMarkerCOM ||
+			(segment.marker >= jpegMarkerAPP0 && segment.marker <= jpegMarkerAPP15 &&
+				segment.marker != jpegMarkerAPP0 && segment.marker != jpegMarkerAPP2 && segment.marker != jpegMarkerAPP14)
+		if !isMetadata {
+			stripped = append(stripped, segment.raw...)
+		}
+	}
+	return append(stripped, data[scanOffset:]...), nil
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const signatureLength = 8
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, data[:signatureLength]...)
+	offset := signatureLength
+	for offset < len(data) {
+		if offset+12 > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		// a chunk is made of its length, its type, its data and a checksum
+		end := offset + 12 + int(binary.BigEndian.Uint32(data[offset:]))
+		if end > len(data) || end < offset {
+			return nil, errors.New("invalid PNG chunk length")
+		}
+		if !pngMetadataChunks[string(data[offset+4:offset+8])] {
+			stripped = append(stripped, data[offset:end]...)
+		}
+		offset = end
+	}
+	return stripped, nil
+}
+
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	// the RIFF header is made of its type, the length of the file and the WebP form type
+	const headerLength = 12
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, data[:headerLength]...)
+	offset := headerLength
+	for offset < len(data) {
+		if offset+8 > len(data) {
+			return nil, errors.New("truncated WebP chunk")
+		}
+		// a chunk is made of its type, its length and its data padded to an even length
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		end := offset + 8 + length + length%2
+		if end == len(data)+1 {
+			// the padding of the last chunk is sometimes omitted
+			end--
+		}
+		if end > len(data) || end < offset {
+			return nil, errors.New("invalid WebP chunk length")
+		}
+		kind := string(data[offset : offset+4])
+		if !webpMetadataChunks[kind] {
+			if kind == "VP8X" && length > 0 {
+				stripped = append(stripped, data[offset:offset+8]...)
+				stripped = append(stripped, data[offset+8]&^(webpFlagEXIF|webpFlagXMP))
+				stripped = append(stripped, data[offset+9:end]...)
+			} else {
+				stripped = append(stripped, data[offset:end]...)
+			}
+		}
+		offset = end
+	}
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
@@ -0,0 +1,166 @@
+package imageformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newExifSegment returns an APP1 segment holding the orientation and a GPS latitude in big endian EXIF
+func newExifSegment(orientation Orientation) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	// orientation is a short stored in the value field
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0x00, 0x00)
+	// the GPS directory pointer refers to the directory following this one
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x26)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = append(tiff, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02, 'N', 0x00, 0x00, 0x00)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, jpegMarkerAPP1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withExif inserts an EXIF segment with the orientation and a comment after the start of the JPEG image
+func withExif(jpegData []byte, orientation Orientation) []byte {
+	comment := []byte{0xFF, jpegMarkerCOM, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}
+	data := append([]byte{}, jpegData[:2]...)
+	data = append(data, newExifSegment(orientation)...)
+	data = append(data, comment...)
+	return append(data, jpegData[2:]...)
+}
+
+func TestReadOrientation(t *testing.T) {
+	jpegData := encodeJPEG(t, 4, 2)
+
+	assert.Equal(t, OrientationRotate90, ReadOrientation(withExif(jpegData, OrientationRotate90)))
+	assert.Equal(t, OrientationFlipVertical, ReadOrientation(withExif(jpegData, OrientationFlipVertical)))
+	assert.Equal(t, OrientationNormal, ReadOrientation(jpegData))
+	assert.Equal(t, OrientationNormal, ReadOrientation(withExif(jpegData, Orientation(42))))
+	assert.Equal(t, OrientationNormal, ReadOrientation(encodePNG(t, 4, 2)))
+}
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	jpegData := encodeJPEG(t, 4, 2)
+
+	stripped, err := StripMetadata(withExif(jpegData, OrientationRotate90))
+
+	assert.NoError(t, err)
+	assert.Equal(t, jpegData, stripped)
+	assert.NotContains(t, string(stripped), "Exif")
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	pngData := encodePNG(t, 4, 2)
+	// the text chunk is inserted after the 33 bytes of the signature and the header chunk
+	text := []byte("\x00\x00\x00\x0atEXtComment\x00hi")
+	text = binary.BigEndian.AppendUint32(text, 0)
+	withText := append(append(append([]byte{}, pngData[:33]...), text...), pngData[33:]...)
+
+	stripped, err := StripMetadata(withText)
+
+	assert.NoError(t, err)
+	assert.Equal(t, pngData, stripped)
+	_, err = png.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestStripMetadata_WebP(t *testing.T) {
+	webpData := encodeWebPHeader(4, 2)
+	// the EXIF chunk has an odd length and is therefore padded
+	withExif := append(append([]byte{}, webpData...), "EXIF\x03\x00\x00\x00MM\x00\x00"...)
+	binary.LittleEndian.PutUint32(withExif[4:], uint32(len(withExif)-8))
+
+	stripped, err := StripMetadata(withExif)
+
+	assert.NoError(t, err)
+	assert.Equal(t, webpData, stripped)
+}
+
+func TestStripMetadata_HEIF(t *testing.T) {
+	t.Run("Success_WithoutMetadataItems", func(t *testing.T) {
+		heifData := newHEIF("heic", 8, 8)
+
+		stripped, err := StripMetadata(heifData)
+
+		assert.NoError(t, err)
+		assert.Equal(t, heifData, stripped)
+	})
+
+	exifItemInfo := newFullBox("iinf", []byte{0, 1}, newBox("infe", []byte{2, 0, 0, 0, 0, 2, 0, 0}, []byte("Exif\x00")))
+	// itemLocations locates the 16 bytes of item 2 at the start of the idat box
+	itemLocations := newBox("iloc", []byte{1, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 2, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 16})
+	testCases := []struct {
+		name          string
+		metaBoxes     [][]byte
+		expectedError string
+	}{
+		{
+			name:          "Error_TruncatedItemInfoEntry",
+			metaBoxes:     [][]byte{newFullBox("iinf", []byte{0, 1}, newBox("infe", []byte{2}))},
+			expectedError: "truncated infe box",
+		},
+		{
+			name:          "Error_TruncatedItemType",
+			metaBoxes:     [][]byte{newFullBox("iinf", []byte{0, 1}, newBox("infe", []byte{2, 0, 0, 0, 0, 2}))},
+			expectedError: "truncated infe box",
+		},
+		{
+			name:          "Error_TruncatedItemInfo",
+			metaBoxes:     [][]byte{newBox("iinf", []byte{1, 0, 0, 0, 0})},
+			expectedError: "truncated iinf box",
+		},
+		{
+			name:          "Error_MissingItemLocations",
+			metaBoxes:     [][]byte{exifItemInfo},
+			expectedError: "missing iloc box",
+		},
+		{
+			name:          "Error_TruncatedItemLocations",
+			metaBoxes:     [][]byte{exifItemInfo, newBox("iloc", []byte{1, 0, 0, 0, 0x44})},
+			expectedError: "truncated iloc box",
+		},
+		{
+			name:          "Error_MissingItemData",
+			metaBoxes:     [][]byte{exifItemInfo, itemLocations},
+			expectedError: "missing idat box",
+		},
+		{
+			name:          "Error_TruncatedItemData",
+			metaBoxes:     [][]byte{exifItemInfo, itemLocations, newBox("idat", []byte{1, 2, 3, 4})},
+			expectedError: "invalid extent of item 2",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			heifData := append(newBox("ftyp", []byte("heic"), []byte{0, 0, 0, 0}), newFullBox("meta", testCase.metaBoxes...)...)
+
+			_, err := StripMetadata(heifData)
+
+			assert.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestStripMetadata_OtherFormats(t *testing.T) {
+	gifData := encodeGIF(t, 4, 2)
+
+	stripped, err := StripMetadata(gifData)
+
+	assert.NoError(t, err)
+	assert.Equal(t, gifData, stripped)
+}
+
+func TestStripMetadata_Truncated(t *testing.T) {
+	_, err := StripMetadata([]byte("\xFF\xD8\xFF\xE1\x00\x40Exif"))
+
+	assert.EqualError(t, err, "invalid JPEG segment length")
+}
@@ -0,0 +1,120 @@
+package imageformat
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// Decode decodes the image, only the first frame of animated images is decoded.
+// It returns an error wrapping ErrNotDecodable or ErrCorrupt when the image cannot be decoded.
+func Decode(data []byte) (image.Image, error) {
+	format, ok := Detect(data)
+	switch {
+	case !ok:
+		return nil, ErrUnknownFormat
+	case !format.Decodable():
+		return nil, fmt.Errorf("%w: %s", ErrNotDecodable, format.Name)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return decoded, nil
+}
+
+// Encode encodes the image as a PNG or a JPEG of the given quality, the default JPEG quality is used when it is zero.
+// Encoded images carry no metadata.
+func Encode(img image.Image, target Format, quality int) ([]byte, error) {
+	if quality <= 0 {
+		quality = jpeg.DefaultQuality
+	}
+	var buffer bytes.Buffer
+	var err error
+	switch target {
+	case PNG:
+		err = png.Encode(&buffer, img)
+	case JPEG:
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: quality})
+	default:
+		return nil, fmt.Errorf("cannot encode images as %s", target.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the image as %s: %v", target.Name, err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Downscale resizes the image so that neither its width nor its height exceeds maxLongEdge, keeping its aspect ratio.
+// Images already small enough are returned as they are.
+func Downscale(img image.Image, maxLongEdge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxLongEdge <= 0 || (width <= maxLongEdge && height <= maxLongEdge) {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*maxLongEdge/width)
+		width = maxLongEdge
+	} else {
+		width = max(1, width*maxLongEdge/height)
+		height = maxLongEdge
+	}
+	downscaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(downscaled, downscaled.Bounds(), img, bounds, draw.Src, nil)
+	return downscaled
+}
+
+// Orient transforms the pixels of an image stored with the orientation so that it is upright
+func Orient(img image.Image, orientation Orientation) image.Image {
+	if orientation <= OrientationNormal || orientation > OrientationRotate270 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// the orientations from transpose onwards swap the width and the height
+	orientedWidth, orientedHeight := width, height
+	if orientation >= OrientationTranspose {
+		orientedWidth, orientedHeight = height, width
+	}
+	oriented := image.NewNRGBA(image.Rect(0, 0, orientedWidth, orientedHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var orientedX, orientedY int
+			switch orientation {
+			case OrientationFlipHorizontal:
+				orientedX, orientedY = width-1-x, y
+			case OrientationRotate180:
+				orientedX, orientedY = width-1-x, height-1-y
+			case OrientationFlipVertical:
+				orientedX, orientedY = x, height-1-y
+			case OrientationTranspose:
+				orientedX, orientedY = y, x
+			case OrientationRotate90:
+				orientedX, orientedY = height-1-y, x
+			case OrientationTransverse:
+				orientedX, orientedY = height-1-y, width-1-x
+			case OrientationRotate270:
+				orientedX, orientedY = y, width-1-x
+			}
+			oriented.Set(orientedX, orientedY, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return oriented
+}
+
+// Crop returns the part of the image within the rectangle
+func Crop(img image.Image, rectangle image.Rectangle) image.Image {
+	if subImager, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return subImager.SubImage(rectangle)
+	}
+	cropped := image.NewNRGBA(rectangle)
+	draw.Draw(cropped, rectangle, img, rectangle.Min, draw.Src)
+	return cropped
+}
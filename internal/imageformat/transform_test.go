@@ -0,0 +1,92 @@
+package imageformat
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newMarkedImage returns a 3x2 image whose top left pixel is red and the others are white
+func newMarkedImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	return img
+}
+
+func redPixel(t *testing.T, img image.Image) image.Point {
+	t.Helper()
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, green, _, _ := img.At(x, y).RGBA(); green == 0 {
+				return image.Pt(x, y)
+			}
+		}
+	}
+	t.Fatal("no red pixel")
+	return image.Point{}
+}
+
+func TestOrient(t *testing.T) {
+	testCases := []struct {
+		orientation Orientation
+		size        image.Point
+		red         image.Point
+	}{
+		{orientation: OrientationNormal, size: image.Pt(3, 2), red: image.Pt(0, 0)},
+		{orientation: OrientationFlipHorizontal, size: image.Pt(3, 2), red: image.Pt(2, 0)},
+		{orientation: OrientationRotate180, size: image.Pt(3, 2), red: image.Pt(2, 1)},
+		{orientation: OrientationFlipVertical, size: image.Pt(3, 2), red: image.Pt(0, 1)},
+		{orientation: OrientationTranspose, size: image.Pt(2, 3), red: image.Pt(0, 0)},
+		{orientation: OrientationRotate90, size: image.Pt(2, 3), red: image.Pt(1, 0)},
+		{orientation: OrientationTransverse, size: image.Pt(2, 3), red: image.Pt(1, 2)},
+		{orientation: OrientationRotate270, size: image.Pt(2, 3), red: image.Pt(0, 2)},
+	}
+	for _, testCase := range testCases {
+		oriented := Orient(newMarkedImage(), testCase.orientation)
+
+		assert.Equal(t, testCase.size, oriented.Bounds().Size(), "orientation %d", testCase.orientation)
+		assert.Equal(t, testCase.red, redPixel(t, oriented), "orientation %d", testCase.orientation)
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	landscape := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	portrait := image.NewRGBA(image.Rect(0, 0, 100, 400))
+
+	assert.Equal(t, image.Pt(200, 50), Downscale(landscape, 200).Bounds().Size())
+	assert.Equal(t, image.Pt(50, 200), Downscale(portrait, 200).Bounds().Size())
+	assert.Same(t, landscape, Downscale(landscape, 400))
+	assert.Same(t, landscape, Downscale(landscape, 0))
+}
+
+func TestEncode(t *testing.T) {
+	encoded, err := Encode(newMarkedImage(), JPEG, 0)
+	assert.NoError(t, err)
+	info, err := Inspect(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, &Info{Format: JPEG, Width: 3, Height: 2}, info)
+
+	_, err = Encode(newMarkedImage(), GIF, 0)
+	assert.EqualError(t, err, "cannot encode images as gif")
+}
+
+func TestDecode_NotDecodable(t *testing.T) {
+	_, err := Decode(newHEIF("heic", 8, 8))
+
+	assert.ErrorIs(t, err, ErrNotDecodable)
+}
+
+func TestCrop(t *testing.T) {
+	cropped := Crop(newMarkedImage(), image.Rect(0, 0, 2, 1))
+
+	assert.Equal(t, image.Rect(0, 0, 2, 1), cropped.Bounds())
+	assert.Equal(t, image.Pt(0, 0), redPixel(t, cropped))
+}
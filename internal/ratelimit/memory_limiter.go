@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// DefaultIdleTimeout is how long the limiter of a client without requests is kept when none is configured
+const DefaultIdleTimeout = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryLimiter is an in-process implementation of RateLimiter keeping a token bucket per client.
+// The limiters of the clients idle for longer than the idle timeout are evicted whenever a request is counted,
+// at most once per idle timeout.
+// Every instance of the service counts the requests it receives on its own.
+type MemoryLimiter struct {
+	mutex        sync.Mutex
+	limiters     map[string]*clientLimiter
+	tiers        *tiers
+	idleTimeout  time.Duration
+	lastEviction time.Time
+	now          func() time.Time
+}
+
+var _ RateLimiter = &MemoryLimiter{}
+
+// NewMemoryLimiter creates a new instance of MemoryLimiter with the tiers of the configuration,
+// a nil configuration allows every client one request per second.
+// It returns an error when the default tier or the tier of a client is not configured.
+func NewMemoryLimiter(rateLimits *config.RateLimitConfig) (*MemoryLimiter, error) {
+	if rateLimits == nil {
+		rateLimits = &config.RateLimitConfig{}
+	}
+	resolver, err := newTiers(rateLimits)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout := time.Duration(rateLimits.IdleTimeoutSeconds) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	return &MemoryLimiter{
+		limiters:    map[string]*clientLimiter{},
+		tiers:       resolver,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+	}, nil
+}
+
+// Allow reports whether the client may make a request now and, when it may not, how long it should wait,
+// in the tier when it is configured
+func (memoryLimiter *MemoryLimiter) Allow(ctx context.Context, identity caller.Identity, tier string) (bool, time.Duration, error) {
+	memoryLimiter.mutex.Lock()
+	defer memoryLimiter.mutex.Unlock()
+
+	now := memoryLimiter.now()
+	if now.Sub(memoryLimiter.lastEviction) >= memoryLimiter.idleTimeout {
+		memoryLimiter.evictIdle(now)
+		memoryLimiter.lastEviction = now
+	}
+
+	key := identity.Key()
+	client, ok := memoryLimiter.limiters[key]
+	if !ok {
+		client = &clientLimiter{limiter: newLimiter(memoryLimiter.tiers.tierOf(key, tier))}
+		memoryLimiter.limiters[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+// Close does nothing, the limiters are only kept in memory
+func (memoryLimiter *MemoryLimiter) Close() error {
+	return nil
+}
+
+// evictIdle removes the limiters of the clients idle for longer than the idle timeout,
+// their buckets have usually refilled by then
+func (memoryLimiter *MemoryLimiter) evictIdle(now time.Time) {
+	for key, client := range memoryLimiter.limiters {
+		if now.Sub(client.lastSeen) >= memoryLimiter.idleTimeout {
+			delete(memoryLimiter.limiters, key)
+		}
+	}
+}
+
+func newLimiter(tier config.RateLimitTierConfig) *rate.Limiter {
+	burst := max(tier.Burst, 1)
+	if tier.RequestsPerSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, burst)
+	}
+	return rate.NewLimiter(rate.Limit(tier.RequestsPerSecond), burst)
+}
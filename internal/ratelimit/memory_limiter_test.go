@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+func newTestLimiter(t *testing.T, rateLimits *config.RateLimitConfig) (*MemoryLimiter, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter, err := NewMemoryLimiter(rateLimits)
+	assert.NoError(t, err)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+var testRateLimits = &config.RateLimitConfig{
+	DefaultTier: "standard",
+	Tiers: map[string]config.RateLimitTierConfig{
+		"standard":  {RequestsPerSecond: 1, Burst: 2},
+		"premium":   {RequestsPerSecond: 10, Burst: 5},
+		"unlimited": {},
+	},
+	Clients: []config.RateLimitClientConfig{
+		{ClientIdentityConfig: config.ClientIdentityConfig{APIKey: "premium-key"}, Tier: "premium"},
+		{ClientIdentityConfig: config.ClientIdentityConfig{Subject: "CN=internal"}, Tier: "unlimited"},
+	},
+	IdleTimeoutSeconds: 60,
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	address := caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"}
+
+	t.Run("Allow_DefaultTier", func(t *testing.T) {
+		limiter, now := newTestLimiter(t, testRateLimits)
+
+		for i := 0; i < 2; i++ {
+			allowed, _, _ := limiter.Allow(ctx, address, "")
+			assert.True(t, allowed)
+		}
+		allowed, retryAfter, _ := limiter.Allow(ctx, address, "")
+		assert.False(t, allowed)
+		assert.Equal(t, time.Second, retryAfter)
+
+		*now = now.Add(time.Second)
+		allowed, _, _ = limiter.Allow(ctx, address, "")
+		assert.True(t, allowed)
+	})
+
+	t.Run("Allow_PerClient", func(t *testing.T) {
+		limiter, _ := newTestLimiter(t, testRateLimits)
+
+		for i := 0; i < 2; i++ {
+			allowed, _, _ := limiter.Allow(ctx, address, "")
+			assert.True(t, allowed)
+		}
+		allowed, _, _ := limiter.Allow(ctx, caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.2"}, "")
+		assert.True(t, allowed)
+		allowed, _, _ = limiter.Allow(ctx, caller.Identity{Kind: caller.KindAPIKey, Value: "10.0.0.1"}, "")
+		assert.True(t, allowed)
+	})
+
+	t.Run("Allow_ClientTiers", func(t *testing.T) {
+		limiter, _ := newTestLimiter(t, testRateLimits)
+		premium := caller.Identity{Kind: caller.KindAPIKey, Value: "premium-key"}
+		internal := caller.Identity{Kind: caller.KindSubject, Value: "CN=internal"}
+
+		for i := 0; i < 5; i++ {
+			allowed, _, _ := limiter.Allow(ctx, premium, "")
+			assert.True(t, allowed)
+		}
+		allowed, retryAfter, _ := limiter.Allow(ctx, premium, "")
+		assert.False(t, allowed)
+		assert.Equal(t, 100*time.Millisecond, retryAfter)
+
+		for i := 0; i < 100; i++ {
+			allowed, _, _ := limiter.Allow(ctx, internal, "")
+			assert.True(t, allowed)
+		}
+	})
+
+	t.Run("Allow_TierOverride", func(t *testing.T) {
+		limiter, _ := newTestLimiter(t, testRateLimits)
+
+		for i := 0; i < 100; i++ {
+			allowed, _, _ := limiter.Allow(ctx, address, "unlimited")
+			assert.True(t, allowed)
+		}
+		premium := caller.Identity{Kind: caller.KindAPIKey, Value: "premium-key"}
+		for i := 0; i < 5; i++ {
+			allowed, _, _ := limiter.Allow(ctx, premium, "unknown")
+			assert.True(t, allowed)
+		}
+		allowed, _, _ := limiter.Allow(ctx, premium, "unknown")
+		assert.False(t, allowed)
+	})
+
+	t.Run("Allow_NilConfig", func(t *testing.T) {
+		limiter, _ := newTestLimiter(t, nil)
+
+		allowed, _, _ := limiter.Allow(ctx, address, "")
+		assert.True(t, allowed)
+		allowed, retryAfter, _ := limiter.Allow(ctx, address, "")
+		assert.False(t, allowed)
+		assert.Equal(t, time.Second, retryAfter)
+	})
+
+	t.Run("Allow_EvictsIdleClients", func(t *testing.T) {
+		limiter, now := newTestLimiter(t, testRateLimits)
+
+		limiter.Allow(ctx, address, "")
+		*now = now.Add(30 * time.Second)
+		limiter.Allow(ctx, caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.2"}, "")
+		assert.Len(t, limiter.limiters, 2)
+
+		*now = now.Add(40 * time.Second)
+		limiter.Allow(ctx, caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.3"}, "")
+		assert.Len(t, limiter.limiters, 2)
+		assert.NotContains(t, limiter.limiters, address.Key())
+	})
+}
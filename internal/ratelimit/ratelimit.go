@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+const (
+	// BackendMemory keeps the rate limits in the memory of every instance, it is the default
+	BackendMemory = "memory"
+	// BackendRedis shares the rate limits of all the instances through Redis
+	BackendRedis = "redis"
+)
+
+// defaultTier keeps one request per second without burst when no default tier is configured
+var defaultTier = config.RateLimitTierConfig{RequestsPerSecond: 1, Burst: 1}
+
+// RateLimiter counts the requests of every client against the rate limit of its tier
+type RateLimiter interface {
+	// Allow reports whether the client may make a request now and, when it may not, how long it should wait.
+	// A configured tier overrides the tier of the client, the tier of the client applies when it is empty or unknown.
+	Allow(ctx context.Context, identity caller.Identity, tier string) (bool, time.Duration, error)
+	Close() error
+}
+
+// New creates the rate limiter of the configured backend
+func New(rateLimits *config.RateLimitConfig) (RateLimiter, error) {
+	switch rateLimits.Backend {
+	case "", BackendMemory:
+		return NewMemoryLimiter(rateLimits)
+	case BackendRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     rateLimits.Redis.Address,
+			Password: rateLimits.Redis.Password,
+			DB:       rateLimits.Redis.DB,
+		})
+		redisLimiter, err := NewRedisLimiter(client, rateLimits)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		return redisLimiter, nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", rateLimits.Backend)
+}
+
+// tiers resolves the tier of the clients
+type tiers struct {
+	tiers       map[string]config.RateLimitTierConfig
+	clientTiers map[string]string
+	defaultTier config.RateLimitTierConfig
+}
+
+// newTiers returns an error when the default tier or the tier of a client is not configured
+func newTiers(rateLimits *config.RateLimitConfig) (*tiers, error) {
+	resolver := &tiers{
+		tiers:       rateLimits.Tiers,
+		clientTiers: map[string]string{},
+		defaultTier: defaultTier,
+	}
+	if rateLimits.DefaultTier != "" {
+		tier, ok := rateLimits.Tiers[rateLimits.DefaultTier]
+		if !ok {
+			return nil, fmt.Errorf("the default rate limit tier %q is not configured", rateLimits.DefaultTier)
+		}
+		resolver.defaultTier = tier
+	}
+	for _, client := range rateLimits.Clients {
+		if _, ok := rateLimits.Tiers[client.Tier]; !ok {
+			return nil, fmt.Errorf("the rate limit tier %q of a client is not configured", client.Tier)
+		}
+		identity, ok := caller.FromConfig(client.ClientIdentityConfig)
+		if !ok {
+			return nil, fmt.Errorf("a client of the rate limit tier %q has no API key, token subject, subject or address", client.Tier)
+		}
+		resolver.clientTiers[identity.Key()] = client.Tier
+	}
+	return resolver, nil
+}
+
+// tierOf returns the named tier when it is configured, otherwise the tier of the client or the default tier
+func (resolver *tiers) tierOf(key string, name string) config.RateLimitTierConfig {
+	if tier, ok := resolver.tiers[name]; ok {
+		return tier
+	}
+	if clientTier, ok := resolver.clientTiers[key]; ok {
+		return resolver.tiers[clientTier]
+	}
+	return resolver.defaultTier
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/config"
+)
+
+func TestNewTiers_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rateLimits    *config.RateLimitConfig
+		expectedError string
+	}{
+		{
+			name:          "Error_UnknownDefaultTier",
+			rateLimits:    &config.RateLimitConfig{DefaultTier: "gold"},
+			expectedError: `the default rate limit tier "gold" is not configured`,
+		},
+		{
+			name: "Error_UnknownClientTier",
+			rateLimits: &config.RateLimitConfig{
+				Clients: []config.RateLimitClientConfig{{ClientIdentityConfig: config.ClientIdentityConfig{Address: "10.0.0.1"}, Tier: "gold"}},
+			},
+			expectedError: `the rate limit tier "gold" of a client is not configured`,
+		},
+		{
+			name: "Error_ClientWithoutIdentity",
+			rateLimits: &config.RateLimitConfig{
+				Tiers:   map[string]config.RateLimitTierConfig{"gold": {}},
+				Clients: []config.RateLimitClientConfig{{Tier: "gold"}},
+			},
+			expectedError: `a client of the rate limit tier "gold" has no API key, token subject, subject or address`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := newTiers(testCase.rateLimits)
+
+			assert.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	memoryLimiter, err := New(&config.RateLimitConfig{})
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryLimiter{}, memoryLimiter)
+
+	redisLimiter, err := New(&config.RateLimitConfig{Backend: BackendRedis, Redis: config.RedisConfig{Address: "localhost:0"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &RedisLimiter{}, redisLimiter)
+	assert.NoError(t, redisLimiter.Close())
+
+	_, err = New(&config.RateLimitConfig{Backend: "memcached"})
+	assert.EqualError(t, err, `unknown rate limit backend "memcached"`)
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// DefaultRedisKeyPrefix prefixes the Redis keys of the rate limits when no prefix is configured
+const DefaultRedisKeyPrefix = "qd-image-analysis-api:rate-limit:"
+
+// slidingWindowScript keeps the times of the requests of a client within the window in a sorted set.
+// The request is counted when there are fewer than the limit, otherwise the script returns
+// the microseconds until the oldest request leaves the window.
+// The clock of Redis is shared by all the instances of the service.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, now .. ':' .. ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return math.max(1, tonumber(oldest[2]) + window - now)
+`)
+
+// RedisLimiter is an implementation of RateLimiter sharing the rate limits of all the instances of the service
+// through Redis. Every client may make as many requests as the burst of its tier within the time the tier
+// takes to allow them again, such as 5 requests within any 5 seconds for 1 request per second with a burst of 5.
+// The requests of idle clients expire with their window.
+type RedisLimiter struct {
+	client    redis.UniversalClient
+	tiers     *tiers
+	keyPrefix string
+}
+
+var _ RateLimiter = &RedisLimiter{}
+
+// NewRedisLimiter creates a new instance of RedisLimiter with the tiers of the configuration,
+// closing the limiter closes the client.
+// It returns an error when the default tier or the tier of a client is not configured.
+func NewRedisLimiter(client redis.UniversalClient, rateLimits *config.RateLimitConfig) (*RedisLimiter, error) {
+	resolver, err := newTiers(rateLimits)
+	if err != nil {
+		return nil, err
+	}
+	keyPrefix := rateLimits.Redis.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = DefaultRedisKeyPrefix
+	}
+	return &RedisLimiter{
+		client:    client,
+		tiers:     resolver,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+// Allow reports whether the client may make a request now and, when it may not, how long it should wait,
+// in the tier when it is configured
+func (redisLimiter *RedisLimiter) Allow(ctx context.Context, identity caller.Identity, tier string) (bool, time.Duration, error) {
+	key := identity.Key()
+	tierConfig := redisLimiter.tiers.tierOf(key, tier)
+	if tierConfig.RequestsPerSecond <= 0 {
+		return true, 0, nil
+	}
+	limit := max(tierConfig.Burst, 1)
+	window := time.Duration(float64(limit) / tierConfig.RequestsPerSecond * float64(time.Second))
+
+	// the random suffix tells apart the requests counted in the same microsecond
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return false, 0, err
+	}
+	retryAfter, err := slidingWindowScript.Run(
+		ctx,
+		redisLimiter.client,
+		[]string{redisLimiter.keyPrefix + key},
+		limit,
+		window.Microseconds(),
+		hex.EncodeToString(suffix),
+	).Int64()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to count the request in Redis: %w", err)
+	}
+	if retryAfter > 0 {
+		return false, time.Duration(retryAfter) * time.Microsecond, nil
+	}
+	return true, 0, nil
+}
+
+// Close closes the Redis client
+func (redisLimiter *RedisLimiter) Close() error {
+	return redisLimiter.client.Close()
+}
@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/caller"
+	"qd-image-analysis-api/internal/config"
+)
+
+// newTestRedisLimiters returns limiters of two instances of the service sharing the same Redis
+func newTestRedisLimiters(t *testing.T) (*miniredis.Miniredis, *RedisLimiter, *RedisLimiter) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	newLimiter := func() *RedisLimiter {
+		limiter, err := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: server.Addr()}), testRateLimits)
+		assert.NoError(t, err)
+		t.Cleanup(func() { limiter.Close() })
+		return limiter
+	}
+	return server, newLimiter(), newLimiter()
+}
+
+func TestRedisLimiter(t *testing.T) {
+	ctx := context.Background()
+	address := caller.Identity{Kind: caller.KindAddress, Value: "10.0.0.1"}
+
+	t.Run("Allow_SharedAcrossInstances", func(t *testing.T) {
+		_, firstInstance, secondInstance := newTestRedisLimiters(t)
+
+		allowed, _, err := firstInstance.Allow(ctx, address, "")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		allowed, _, err = secondInstance.Allow(ctx, address, "")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, retryAfter, err := firstInstance.Allow(ctx, address, "")
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 2*time.Second, retryAfter)
+		allowed, _, err = secondInstance.Allow(ctx, address, "")
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("Allow_SlidingWindow", func(t *testing.T) {
+		server, limiter, _ := newTestRedisLimiters(t)
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		allowed, _, _ := limiter.Allow(ctx, address, "")
+		assert.True(t, allowed)
+		server.SetTime(start.Add(1500 * time.Millisecond))
+		allowed, _, _ = limiter.Allow(ctx, address, "")
+		assert.True(t, allowed)
+
+		server.SetTime(start.Add(1900 * time.Millisecond))
+		allowed, retryAfter, _ := limiter.Allow(ctx, address, "")
+		assert.False(t, allowed)
+		assert.Equal(t, 100*time.Millisecond, retryAfter)
+
+		// the first request leaves the window while the second one is still counted
+		server.SetTime(start.Add(2 * time.Second))
+		allowed, _, _ = limiter.Allow(ctx, address, "")
+		assert.True(t, allowed)
+		allowed, retryAfter, _ = limiter.Allow(ctx, address, "")
+		assert.False(t, allowed)
+		assert.Equal(t, 1500*time.Millisecond, retryAfter)
+	})
+
+	t.Run("Allow_ClientTiers", func(t *testing.T) {
+		_, limiter, _ := newTestRedisLimiters(t)
+		premium := caller.Identity{Kind: caller.KindAPIKey, Value: "premium-key"}
+
+		for i := 0; i < 5; i++ {
+			allowed, _, err := limiter.Allow(ctx, premium, "")
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+		}
+		allowed, retryAfter, _ := limiter.Allow(ctx, premium, "")
+		assert.False(t, allowed)
+		assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+		for i := 0; i < 100; i++ {
+			allowed, _, _ := limiter.Allow(ctx, caller.Identity{Kind: caller.KindSubject, Value: "CN=internal"}, "")
+			assert.True(t, allowed)
+		}
+	})
+
+	t.Run("Allow_TierOverride", func(t *testing.T) {
+		_, limiter, _ := newTestRedisLimiters(t)
+		premium := caller.Identity{Kind: caller.KindAPIKey, Value: "premium-key"}
+
+		for i := 0; i < 100; i++ {
+			allowed, _, _ := limiter.Allow(ctx, premium, "unlimited")
+			assert.True(t, allowed)
+		}
+	})
+
+	t.Run("Allow_ExpiresIdleClients", func(t *testing.T) {
+		server, limiter, _ := newTestRedisLimiters(t)
+
+		limiter.Allow(ctx, address, "")
+		key := DefaultRedisKeyPrefix + address.Key()
+		assert.True(t, server.Exists(key))
+		assert.Equal(t, 2*time.Second, server.TTL(key))
+
+		server.FastForward(2 * time.Second)
+		assert.False(t, server.Exists(key))
+	})
+
+	t.Run("Allow_HashesAPIKeys", func(t *testing.T) {
+		server, limiter, _ := newTestRedisLimiters(t)
+
+		limiter.Allow(ctx, caller.Identity{Kind: caller.KindAPIKey, Value: "secret"}, "")
+		for _, key := range server.Keys() {
+			assert.NotContains(t, key, "secret")
+		}
+	})
+
+	t.Run("Error_Unavailable", func(t *testing.T) {
+		server, limiter, _ := newTestRedisLimiters(t)
+		server.Close()
+
+		_, _, err := limiter.Allow(ctx, address, "")
+		assert.ErrorContains(t, err, "failed to count the request in Redis")
+	})
+
+	t.Run("Success_KeyPrefix", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		rateLimits := &config.RateLimitConfig{Redis: config.RedisConfig{KeyPrefix: "limits:"}}
+		limiter, err := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: server.Addr()}), rateLimits)
+		assert.NoError(t, err)
+		defer limiter.Close()
+
+		limiter.Allow(ctx, address, "")
+		assert.Equal(t, []string{"limits:address:10.0.0.1"}, server.Keys())
+	})
+}
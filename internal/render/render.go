@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// htmlPolicy allows the formatting elements of user generated content, bluemonday policies are safe for concurrent use
+var htmlPolicy = bluemonday.UGCPolicy()
+
+// SanitizeHTML removes scripts, styles, event handlers and any other unsafe markup from the HTML
+func SanitizeHTML(html string) string {
+	return htmlPolicy.Sanitize(html)
+}
+
+// MarkdownToHTML converts markdown into sanitized HTML
+func MarkdownToHTML(markdown string) (string, error) {
+	var buffer bytes.Buffer
+	if err := goldmark.Convert([]byte(markdown), &buffer); err != nil {
+		return "", fmt.Errorf("failed to convert markdown to HTML: %v", err)
+	}
+	return SanitizeHTML(buffer.String()), nil
+}
+
+// MarkdownToPlainText strips the markdown syntax keeping the text, the list markers and the link destinations.
+// Blocks are separated by a blank line.
+func MarkdownToPlainText(markdown string) string {
+	source := []byte(markdown)
+	document := goldmark.DefaultParser().Parse(text.NewReader(source))
+	var buffer bytes.Buffer
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			return writePlainTextNode(&buffer, node, source), nil
+		}
+		switch node := node.(type) {
+		case *ast.Link:
+			buffer.WriteString(" (" + string(node.Destination) + ")")
+		case *ast.Paragraph, *ast.Heading, *ast.List, *ast.FencedCodeBlock, *ast.CodeBlock, *ast.ThematicBreak:
+			if isInListItem(node) {
+				endLine(&buffer, 1)
+			} else {
+				endLine(&buffer, 2)
+			}
+		case *ast.TextBlock, *ast.ListItem:
+			endLine(&buffer, 1)
+		}
+		return ast.WalkContinue, nil
+	})
+	return string(bytes.TrimSpace(buffer.Bytes()))
+}
+
+func writePlainTextNode(buffer *bytes.Buffer, node ast.Node, source []byte) ast.WalkStatus {
+	switch node := node.(type) {
+	case *ast.Text:
+		buffer.Write(node.Segment.Value(source))
+		if node.HardLineBreak() || node.SoftLineBreak() {
+			buffer.WriteByte('\n')
+		}
+	case *ast.String:
+		buffer.Write(node.Value)
+	case *ast.AutoLink:
+		buffer.Write(node.URL(source))
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		lines := node.Lines()
+		for index := 0; index < lines.Len(); index++ {
+			segment := lines.At(index)
+			buffer.Write(segment.Value(source))
+		}
+	case *ast.ListItem:
+		list := node.Parent().(*ast.List)
+		if list.IsOrdered() {
+			buffer.WriteString(strconv.Itoa(list.Start+listItemIndex(node)) + ". ")
+		} else {
+			buffer.WriteString("- ")
+		}
+	case *ast.RawHTML, *ast.HTMLBlock:
+		return ast.WalkSkipChildren
+	}
+	return ast.WalkContinue
+}
+
+func listItemIndex(node ast.Node) int {
+	index := 0
+	for sibling := node.PreviousSibling(); sibling != nil; sibling = sibling.PreviousSibling() {
+		index++
+	}
+	return index
+}
+
+func isInListItem(node ast.Node) bool {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Kind() == ast.KindListItem {
+			return true
+		}
+	}
+	return false
+}
+
+// endLine appends line breaks until the buffer ends with count of them
+func endLine(buffer *bytes.Buffer, count int) {
+	if buffer.Len() == 0 {
+		return
+	}
+	trailing := len(buffer.Bytes()) - len(bytes.TrimRight(buffer.Bytes(), "\n"))
+	for ; trailing < count; trailing++ {
+		buffer.WriteByte('\n')
+	}
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMarkdown = "# Receipt\n\nThe **total** is _12.50 EUR_, see [the shop](https://shop.example).\n\n" +
+	"- one coffee\n- one `croissant`\n\n1. paid\n2. kept\n\n```\ncode line\n```\n\n<script>alert(1)</script>\n"
+
+func TestMarkdownToHTML(t *testing.T) {
+	html, err := MarkdownToHTML(testMarkdown)
+
+	assert.NoError(t, err)
+	assert.Contains(t, html, "<h1>Receipt</h1>")
+	assert.Contains(t, html, "<strong>total</strong>")
+	assert.Contains(t, html, `<a href="https://shop.example" rel="nofollow">the shop</a>`)
+	assert.Contains(t, html, "<li>one <code>croissant</code></li>")
+	assert.NotContains(t, html, "script")
+}
+
+func TestMarkdownToPlainText(t *testing.T) {
+	plainText := MarkdownToPlainText(testMarkdown)
+
+	assert.Equal(
+		t,
+		"Receipt\n\nThe total is 12.50 EUR, see the shop (https://shop.example).\n\n"+
+			"- one coffee\n- one croissant\n\n1. paid\n2. kept\n\ncode line",
+		plainText,
+	)
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	sanitized := SanitizeHTML(`<p onclick="steal()">Hello <b>world</b></p><iframe src="https://evil.example"></iframe>`)
+
+	assert.Equal(t, "<p>Hello <b>world</b></p>", sanitized)
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Schema is a compiled JSON Schema used to instruct the model and to validate its responses
+type Schema struct {
+	Name     string
+	Document map[string]interface{}
+	compiled *jsonschema.Schema
+}
+
+// Compile parses and compiles a JSON Schema document
+func Compile(name, document string) (*Schema, error) {
+	var parsedDocument map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &parsedDocument); err != nil {
+		return nil, fmt.Errorf("schema %q is not a JSON object: %v", name, err)
+	}
+	compiled, err := jsonschema.CompileString(name+".json", document)
+	if err != nil {
+		return nil, fmt.Errorf("schema %q is not a valid JSON Schema: %v", name, err)
+	}
+	return &Schema{
+		Name:     name,
+		Document: parsedDocument,
+		compiled: compiled,
+	}, nil
+}
+
+// ValidateResponse checks that the model response is a JSON document satisfying the schema
+func (schema *Schema) ValidateResponse(response string) error {
+	var document interface{}
+	if err := json.Unmarshal([]byte(TrimCodeFence(response)), &document); err != nil {
+		return fmt.Errorf("response is not valid JSON: %v", err)
+	}
+	if err := schema.compiled.Validate(document); err != nil {
+		return fmt.Errorf("response does not match the schema: %v", err)
+	}
+	return nil
+}
+
+// TrimCodeFence removes the markdown code fence some models wrap around JSON responses
+func TrimCodeFence(response string) string {
+	trimmed := strings.TrimSpace(response)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+	if newLine := strings.Index(trimmed, "\n"); newLine >= 0 {
+		trimmed = trimmed[newLine+1:]
+	} else {
+		trimmed = strings.TrimPrefix(trimmed, "```")
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(trimmed), "```"))
+}
+
+// Registry keeps the server-side schemas clients can reference by name
+type Registry struct {
+	schemas map[string]*Schema
+}
+
+// NewRegistry creates an empty schema registry
+func NewRegistry() *Registry {
+	return &Registry{schemas: make(map[string]*Schema)}
+}
+
+// LoadRegistry compiles every *.json file of the directory, naming each schema after its file.
+// An empty directory path results in an empty registry.
+func LoadRegistry(directory string) (*Registry, error) {
+	registry := NewRegistry()
+	if directory == "" {
+		return registry, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(directory, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %v", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %v", path, err)
+		}
+		schema, err := Compile(strings.TrimSuffix(filepath.Base(path), ".json"), string(content))
+		if err != nil {
+			return nil, err
+		}
+		registry.Add(schema)
+	}
+	return registry, nil
+}
+
+// Add registers the schema under its name, replacing any previous one
+func (registry *Registry) Add(schema *Schema) {
+	registry.schemas[schema.Name] = schema
+}
+
+// Get returns the schema registered under the given name
+func (registry *Registry) Get(name string) (*Schema, bool) {
+	schema, ok := registry.schemas[name]
+	return schema, ok
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	registry, err := LoadRegistry("testdata")
+	assert.NoError(t, err)
+
+	invoice, ok := registry.Get("invoice")
+	assert.True(t, ok)
+	assert.Equal(t, "object", invoice.Document["type"])
+
+	_, ok = registry.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestLoadRegistry_ConfigExample(t *testing.T) {
+	registry, err := LoadRegistry("../config/schemas")
+	assert.NoError(t, err)
+
+	_, ok := registry.Get("invoice")
+	assert.True(t, ok)
+}
+
+func TestSchema_ValidateResponse(t *testing.T) {
+	registry, err := LoadRegistry("testdata")
+	assert.NoError(t, err)
+	invoice, _ := registry.Get("invoice")
+
+	assert.NoError(t, invoice.ValidateResponse(`{"total": 12.5, "currency": "EUR"}`))
+	assert.NoError(t, invoice.ValidateResponse("```json\n{\"total\": 12.5, \"currency\": \"EUR\"}\n```"))
+
+	err = invoice.ValidateResponse(`{"total": "12.5"}`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "response does not match the schema")
+
+	err = invoice.ValidateResponse("# Invoice")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "response is not valid JSON")
+}
+
+func TestCompile_InvalidSchema(t *testing.T) {
+	_, err := Compile("broken", `{"type": 12}`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema \"broken\" is not a valid JSON Schema")
+
+	_, err = Compile("broken", `[]`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema \"broken\" is not a JSON object")
+}
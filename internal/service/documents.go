@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/document"
+)
+
+// validateDocument checks a PDF document against the limits and keeps only the selected pages
+func validateDocument(
+	index int,
+	image ai.Image,
+	pages *PageRange,
+	limits *config.DocumentsConfig,
+	imageLimits *config.ImagesConfig,
+) (ai.Image, error) {
+	switch {
+	case !limits.Enabled:
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("document %d is a PDF document, which is not supported", index),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	case image.MimeType != document.MimeTypePDF && !imageLimits.CorrectMimeType:
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("document %d is declared as %q but its content is %q", index, image.MimeType, document.MimeTypePDF),
+			Reason:  ErrorReasonMimeTypeMismatch,
+		}
+	case limits.MaxSizeBytes > 0 && int64(len(image.Data)) > limits.MaxSizeBytes:
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("document %d of %d bytes exceeds the limit of %d bytes", index, len(image.Data), limits.MaxSizeBytes),
+			Reason:  ErrorReasonDocumentLimits,
+		}
+	}
+	pageCount, err := document.PageCount(image.Data)
+	if err != nil {
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("document %d is invalid: %v", index, err),
+			Reason:  ErrorReasonCorruptDocument,
+		}
+	}
+	data := image.Data
+	selectedPages := pageCount
+	if pages != nil {
+		last := pages.Last
+		if last == 0 {
+			last = pageCount
+		}
+		if pages.First < 1 || last < pages.First || last > pageCount {
+			return ai.Image{}, &Error{
+				Message: fmt.Sprintf("pages %d to %d are not within the %d pages of document %d", pages.First, last, pageCount, index),
+				Reason:  ErrorReasonDocumentLimits,
+			}
+		}
+		selectedPages = last - pages.First + 1
+		if selectedPages < pageCount {
+			data, err = document.ExtractPages(image.Data, pages.First, last)
+			if err != nil {
+				return ai.Image{}, &Error{
+					Message: fmt.Sprintf("document %d is invalid: %v", index, err),
+					Reason:  ErrorReasonCorruptDocument,
+				}
+			}
+		}
+	}
+	if limits.MaxPages > 0 && selectedPages > limits.MaxPages {
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("%d pages of document %d exceed the limit of %d pages", selectedPages, index, limits.MaxPages),
+			Reason:  ErrorReasonDocumentLimits,
+		}
+	}
+	return ai.Image{Data: data, MimeType: document.MimeTypePDF}, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/document"
+)
+
+func readTestDocument(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/three_pages.pdf")
+	assert.NoError(t, err)
+	return data
+}
+
+func TestValidateImages_Documents(t *testing.T) {
+	testDocument := readTestDocument(t)
+	testCases := []struct {
+		name                 string
+		config               config.Config
+		documentsUnsupported bool
+		images               []ai.Image
+		pages                *PageRange
+		expectedPages        int
+		expectedReason       ErrorReason
+		expectedError        string
+	}{
+		{
+			name:          "Success",
+			config:        config.Config{Documents: config.DocumentsConfig{Enabled: true, MaxPages: 3}},
+			images:        []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			expectedPages: 3,
+		},
+		{
+			name:          "Success_PageRange",
+			config:        config.Config{Documents: config.DocumentsConfig{Enabled: true, MaxPages: 2}},
+			images:        []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			pages:         &PageRange{First: 2},
+			expectedPages: 2,
+		},
+		{
+			name: "Success_CorrectedMimeType",
+			config: config.Config{
+				Images:    config.ImagesConfig{CorrectMimeType: true},
+				Documents: config.DocumentsConfig{Enabled: true},
+			},
+			images:        []ai.Image{{Data: testDocument, MimeType: "application/octet-stream"}},
+			expectedPages: 3,
+		},
+		{
+			name:           "Error_Disabled",
+			images:         []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  "document 0 is a PDF document, which is not supported",
+		},
+		{
+			name:                 "Error_AnalyzerCannotRead",
+			config:               config.Config{Documents: config.DocumentsConfig{Enabled: true}},
+			documentsUnsupported: true,
+			images:               []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			expectedReason:       ErrorReasonUnsupportedImage,
+			expectedError:        "document 0 is a PDF document, which the configured model provider cannot read",
+		},
+		{
+			name:           "Error_MimeTypeMismatch",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true}},
+			images:         []ai.Image{{Data: testDocument, MimeType: "image/png"}},
+			expectedReason: ErrorReasonMimeTypeMismatch,
+			expectedError:  `document 0 is declared as "image/png" but its content is "application/pdf"`,
+		},
+		{
+			name:           "Error_DeclaredDocumentIsImage",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true}},
+			images:         []ai.Image{{Data: testPNG, MimeType: "application/pdf"}},
+			expectedReason: ErrorReasonMimeTypeMismatch,
+			expectedError:  `image 0 is declared as "application/pdf" but its content is "image/png"`,
+		},
+		{
+			name:           "Error_TooLarge",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true, MaxSizeBytes: 100}},
+			images:         []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			expectedReason: ErrorReasonDocumentLimits,
+			expectedError:  "document 0 of 1101 bytes exceeds the limit of 100 bytes",
+		},
+		{
+			name:           "Error_TooManyPages",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true, MaxPages: 2}},
+			images:         []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			expectedReason: ErrorReasonDocumentLimits,
+			expectedError:  "3 pages of document 0 exceed the limit of 2 pages",
+		},
+		{
+			name:           "Error_PageRangeOutsideDocument",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true}},
+			images:         []ai.Image{{Data: testDocument, MimeType: "application/pdf"}},
+			pages:          &PageRange{First: 2, Last: 5},
+			expectedReason: ErrorReasonDocumentLimits,
+			expectedError:  "pages 2 to 5 are not within the 3 pages of document 0",
+		},
+		{
+			name:           "Error_Corrupt",
+			config:         config.Config{Documents: config.DocumentsConfig{Enabled: true}},
+			images:         []ai.Image{{Data: []byte("%PDF-1.4\ngarbage"), MimeType: "application/pdf"}},
+			expectedReason: ErrorReasonCorruptDocument,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockAnalyzer := mock.NewMockAnalyzer(controller)
+			mockAnalyzer.EXPECT().SupportsDocuments().Return(!testCase.documentsUnsupported).AnyTimes()
+			service := NewImageAnalysisService(mockAnalyzer, nil, nil, &testCase.config)
+
+			images, _, err := service.validateImages(testCase.images, &AnalysisOptions{Pages: testCase.pages})
+
+			if testCase.expectedReason == "" {
+				assert.NoError(t, err)
+				assert.Len(t, images, 1)
+				assert.Equal(t, document.MimeTypePDF, images[0].MimeType)
+				pageCount, err := document.PageCount(images[0].Data)
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedPages, pageCount)
+				return
+			}
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expectedReason, serviceError.Reason)
+			if testCase.expectedError != "" {
+				assert.Equal(t, testCase.expectedError, serviceError.Message)
+			}
+			assert.Nil(t, images)
+		})
+	}
+}
@@ -1,11 +1,66 @@
 package service
 
+import "qd-image-analysis-api/internal/ai"
+
+// ErrorReason identifies why a request was rejected when the message alone is not enough for clients
+type ErrorReason string
+
+const (
+	// ErrorReasonUnsupportedImage is set when an image is not in a supported format
+	ErrorReasonUnsupportedImage ErrorReason = "UNSUPPORTED_IMAGE_FORMAT"
+	// ErrorReasonCorruptImage is set when the header of an image cannot be decoded
+	ErrorReasonCorruptImage ErrorReason = "CORRUPT_IMAGE"
+	// ErrorReasonMimeTypeMismatch is set when the declared mime type of an image does not match its content
+	ErrorReasonMimeTypeMismatch ErrorReason = "MIME_TYPE_MISMATCH"
+	// ErrorReasonImageDimensions is set when the width or height of an image is outside the configured limits
+	ErrorReasonImageDimensions ErrorReason = "IMAGE_DIMENSIONS_OUT_OF_RANGE"
+	// ErrorReasonCorruptDocument is set when a PDF document cannot be parsed
+	ErrorReasonCorruptDocument ErrorReason = "CORRUPT_DOCUMENT"
+	// ErrorReasonDocumentLimits is set when the size or the selected pages of a PDF document are outside the configured limits
+	ErrorReasonDocumentLimits ErrorReason = "DOCUMENT_LIMITS_EXCEEDED"
+)
+
 // Error is the error type for the service
 type Error struct {
 	Message string
+	// Reason is empty unless the error is one clients are expected to handle specifically
+	Reason ErrorReason
 }
 
 // Error returns the error message
 func (e *Error) Error() string {
 	return e.Message
 }
+
+// UsageError is returned when a request fails after the analyzer consumed tokens, so that they are still charged
+type UsageError struct {
+	Err error
+	// Usage and EstimatedCost add up the analyses made before the failure
+	Usage         ai.Usage
+	EstimatedCost float64
+}
+
+// Error returns the message of the wrapped error
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// withUsage wraps the error with the usage of the results, the nil results are skipped
+func withUsage(err error, results ...*ai.Result) error {
+	usageError := &UsageError{Err: err}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		usageError.Usage.PromptTokens += result.Usage.PromptTokens
+		usageError.Usage.CandidatesTokens += result.Usage.CandidatesTokens
+		usageError.Usage.TotalTokens += result.Usage.TotalTokens
+		usageError.EstimatedCost += result.EstimatedCost
+	}
+	return usageError
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+)
+
+// validateGenerationParams checks the per-request generation parameters against the configured bounds
+func validateGenerationParams(bounds *config.GenerationConfig, params *ai.GenerationParams) error {
+	if err := validateIntParam("max_tokens", params.MaxTokens, bounds.MaxTokens); err != nil {
+		return err
+	}
+	if err := validateFloatParam("temperature", params.Temperature, bounds.Temperature); err != nil {
+		return err
+	}
+	if err := validateFloatParam("top_p", params.TopP, bounds.TopP); err != nil {
+		return err
+	}
+	if err := validateIntParam("top_k", params.TopK, bounds.TopK); err != nil {
+		return err
+	}
+	if err := validateIntParam("candidate_count", params.CandidateCount, bounds.CandidateCount); err != nil {
+		return err
+	}
+	if bounds.MaxStopSequences > 0 && len(params.StopSequences) > bounds.MaxStopSequences {
+		return &Error{
+			Message: fmt.Sprintf("at most %d stop sequences are allowed", bounds.MaxStopSequences),
+		}
+	}
+	return nil
+}
+
+func validateIntParam(name string, value *int32, bounds config.IntRange) error {
+	if value == nil {
+		return nil
+	}
+	if *value < bounds.Min || (bounds.Max > 0 && *value > bounds.Max) {
+		return &Error{
+			Message: fmt.Sprintf("%s %d is outside the allowed range %s", name, *value, formatRange(bounds.Min, bounds.Max, bounds.Max > 0)),
+		}
+	}
+	return nil
+}
+
+func validateFloatParam(name string, value *float32, bounds config.FloatRange) error {
+	if value == nil {
+		return nil
+	}
+	if *value < bounds.Min || (bounds.Max > 0 && *value > bounds.Max) {
+		return &Error{
+			Message: fmt.Sprintf("%s %g is outside the allowed range %s", name, *value, formatRange(bounds.Min, bounds.Max, bounds.Max > 0)),
+		}
+	}
+	return nil
+}
+
+func formatRange(lower, upper interface{}, bounded bool) string {
+	if !bounded {
+		return fmt.Sprintf("[%v, +inf)", lower)
+	}
+	return fmt.Sprintf("[%v, %v]", lower, upper)
+}
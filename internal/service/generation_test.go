@@ -0,0 +1,126 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+)
+
+func int32Pointer(value int32) *int32 {
+	return &value
+}
+
+func float32Pointer(value float32) *float32 {
+	return &value
+}
+
+func TestValidateGenerationParams(t *testing.T) {
+	bounds := &config.GenerationConfig{
+		MaxTokens:        config.IntRange{Min: 1, Max: 4096},
+		Temperature:      config.FloatRange{Min: 0, Max: 1},
+		TopP:             config.FloatRange{Min: 0, Max: 1},
+		TopK:             config.IntRange{Min: 1},
+		CandidateCount:   config.IntRange{Min: 1, Max: 2},
+		MaxStopSequences: 1,
+	}
+	testCases := []struct {
+		name     string
+		params   ai.GenerationParams
+		expected string
+	}{
+		{
+			name: "within bounds",
+			params: ai.GenerationParams{
+				MaxTokens:      int32Pointer(4096),
+				Temperature:    float32Pointer(0.7),
+				TopK:           int32Pointer(1000),
+				StopSequences:  []string{"END"},
+				Seed:           int32Pointer(42),
+				CandidateCount: int32Pointer(2),
+			},
+		},
+		{
+			name:     "max tokens above maximum",
+			params:   ai.GenerationParams{MaxTokens: int32Pointer(5000)},
+			expected: "max_tokens 5000 is outside the allowed range [1, 4096]",
+		},
+		{
+			name:     "temperature above maximum",
+			params:   ai.GenerationParams{Temperature: float32Pointer(1.5)},
+			expected: "temperature 1.5 is outside the allowed range [0, 1]",
+		},
+		{
+			name:     "top k below minimum",
+			params:   ai.GenerationParams{TopK: int32Pointer(0)},
+			expected: "top_k 0 is outside the allowed range [1, +inf)",
+		},
+		{
+			name:     "too many candidates",
+			params:   ai.GenerationParams{CandidateCount: int32Pointer(3)},
+			expected: "candidate_count 3 is outside the allowed range [1, 2]",
+		},
+		{
+			name:     "too many stop sequences",
+			params:   ai.GenerationParams{StopSequences: []string{"END", "STOP"}},
+			expected: "at most 1 stop sequences are allowed",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validateGenerationParams(bounds, &testCase.params)
+
+			if testCase.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expected, serviceError.Message)
+		})
+	}
+}
+
+func TestResolveOptions_Seed(t *testing.T) {
+	testCases := []struct {
+		name         string
+		supportsSeed bool
+		expected     string
+	}{
+		{
+			name:         "Success_Supported",
+			supportsSeed: true,
+		},
+		{
+			name:     "Error_NotSupported",
+			expected: "seed is not supported by the configured model provider",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockAnalyzer := mock.NewMockAnalyzer(controller)
+			mockAnalyzer.EXPECT().SupportsSeed().Return(testCase.supportsSeed)
+			service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+			analyzerOptions, _, err := service.resolveOptions(&AnalysisOptions{
+				Generation: ai.GenerationParams{Seed: int32Pointer(42)},
+			})
+
+			if testCase.expected == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, int32(42), *analyzerOptions.Generation.Seed)
+				return
+			}
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expected, serviceError.Message)
+			assert.Nil(t, analyzerOptions)
+		})
+	}
+}
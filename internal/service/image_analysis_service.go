@@ -7,51 +7,476 @@ import (
 	"github.com/quadev-ltd/qd-common/pkg/log"
 
 	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/document"
+	"qd-image-analysis-api/internal/render"
+	"qd-image-analysis-api/internal/schema"
+	"qd-image-analysis-api/internal/templates"
 )
 
+// AnalysisOptions holds the optional settings of an analysis request
+type AnalysisOptions struct {
+	// OutputFormat is the format the model writes the response in, markdown when empty
+	OutputFormat ai.OutputFormat
+	// ConvertTo converts a markdown response into sanitized HTML or plain text before returning it
+	ConvertTo ai.OutputFormat
+	// JSONSchema is an inline JSON Schema the response must satisfy, it takes precedence over SchemaName
+	JSONSchema string
+	// SchemaName references a JSON Schema of the server-side registry
+	SchemaName string
+	// Generation overrides the configured generation parameters within the configured bounds
+	Generation ai.GenerationParams
+	// Model is an alias or a model name of the model catalog, the provider model is used when empty
+	Model string
+	// Template renders the prompt from a server-side prompt template, the request prompt must then be empty
+	Template *TemplateReference
+	// Pages selects the pages of the PDF documents to analyse, all of them when nil
+	Pages *PageRange
+	// Tiling analyses the image in tiling mode when set
+	Tiling *TilingOptions
+}
+
+// PageRange selects the pages First to Last of a PDF document, both included and starting at 1.
+// A zero Last selects the pages up to the end of the document.
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// TemplateReference selects a version of a server-side prompt template and the values of its variables
+type TemplateReference struct {
+	ID string
+	// Version of the template, the latest version is used when zero
+	Version   int
+	Variables map[string]string
+}
+
+func (options *AnalysisOptions) pages() *PageRange {
+	if options == nil {
+		return nil
+	}
+	return options.Pages
+}
+
+func (options *AnalysisOptions) tiling() *TilingOptions {
+	if options == nil {
+		return nil
+	}
+	return options.Tiling
+}
+
+func (options *AnalysisOptions) outputFormat() ai.OutputFormat {
+	switch {
+	case options.OutputFormat != "":
+		return options.OutputFormat
+	case options.JSONSchema != "" || options.SchemaName != "":
+		return ai.OutputFormatJSON
+	}
+	return ai.OutputFormatMarkdown
+}
+
 // ImageAnalysisServicer defines the interface for image analysis operations
 type ImageAnalysisServicer interface {
-	ProcessImageAndPrompt(ctx context.Context, imageData []byte, mimeType string, prompt string) (string, error)
+	ProcessImageAndPrompt(
+		ctx context.Context,
+		images []ai.Image,
+		prompt string,
+		options *AnalysisOptions,
+	) (*ai.Result, error)
+	ProcessImageAndPromptStream(
+		ctx context.Context,
+		images []ai.Image,
+		prompt string,
+		options *AnalysisOptions,
+		send func(chunk string) error,
+	) (*ai.Result, error)
 	Close() error
 }
 
 // ImageAnalysisService implements the ImageAnalysisServicer interface
 type ImageAnalysisService struct {
-	analyzer ai.Analyzer
+	analyzer  ai.Analyzer
+	schemas   *schema.Registry
+	templates *templates.Registry
+	config    *config.Config
 }
 
 var _ ImageAnalysisServicer = &ImageAnalysisService{}
 
-// NewImageAnalysisService creates a new instance of the image analysis service
-func NewImageAnalysisService(analyzer ai.Analyzer) *ImageAnalysisService {
-	return &ImageAnalysisService{analyzer: analyzer}
+// NewImageAnalysisService creates a new instance of the image analysis service.
+// The schema and prompt template registries resolve the schemas and templates referenced by requests,
+// either may be nil when there is none.
+// The configuration provides the image limits, the generation parameter bounds and the model catalog,
+// a nil configuration leaves the requests unbounded.
+func NewImageAnalysisService(
+	analyzer ai.Analyzer,
+	schemas *schema.Registry,
+	promptTemplates *templates.Registry,
+	serviceConfig *config.Config,
+) *ImageAnalysisService {
+	if schemas == nil {
+		schemas = schema.NewRegistry()
+	}
+	if promptTemplates == nil {
+		promptTemplates = templates.NewRegistry()
+	}
+	if serviceConfig == nil {
+		serviceConfig = &config.Config{}
+	}
+	return &ImageAnalysisService{
+		analyzer:  analyzer,
+		schemas:   schemas,
+		templates: promptTemplates,
+		config:    serviceConfig,
+	}
 }
 
-// ProcessImageAndPrompt processes an image with a given prompt using the configured analyzer.
+// ProcessImageAndPrompt processes one or more images with a given prompt using the configured analyzer.
 // It validates the input parameters and returns the analysis result or an error if the processing fails.
-func (imageAnalysisService *ImageAnalysisService) ProcessImageAndPrompt(ctx context.Context, imageData []byte, mimeType string, prompt string) (string, error) {
+// When a JSON schema is requested the response is validated and the analysis retried once with a corrective prompt.
+// HTML responses are sanitized and markdown responses converted when requested.
+// In tiling mode the answers about every tile are merged into the result.
+// Failures after the analyzer consumed tokens are returned as a UsageError.
+func (imageAnalysisService *ImageAnalysisService) ProcessImageAndPrompt(
+	ctx context.Context,
+	images []ai.Image,
+	prompt string,
+	options *AnalysisOptions,
+) (*ai.Result, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzerOptions, responseSchema, err := imageAnalysisService.resolveOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	prompt, promptTemplate, err := imageAnalysisService.resolvePrompt(prompt, options, analyzerOptions)
+	if err != nil {
+		return nil, err
+	}
+	images, imagesSize, err := imageAnalysisService.validateImagesAndPrompt(images, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ai.Result
+	if tiling := options.tiling(); tiling != nil {
+		logger.Info(fmt.Sprintf("Processing %d image(s) of %d bytes in tiling mode with prompt: %s", len(images), imagesSize, prompt))
+		result, err = imageAnalysisService.analyzeTiles(ctx, images, prompt, tiling, analyzerOptions)
+	} else {
+		logger.Info(fmt.Sprintf("Processing %d image(s) of %d bytes with prompt: %s", len(images), imagesSize, prompt))
+		result, err = imageAnalysisService.analyzer.Analyze(ctx, images, prompt, analyzerOptions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logTemplate(logger, promptTemplate)
+	if responseSchema == nil {
+		rendered, err := renderResult(result, options)
+		if err != nil {
+			return nil, withUsage(err, result)
+		}
+		return rendered, nil
+	}
+
+	validationErr := validateJSONResult(responseSchema, result)
+	if validationErr == nil {
+		return result, nil
+	}
+	logger.Warn(fmt.Sprintf("Retrying analysis as the response does not satisfy schema %s: %v", responseSchema.Name, validationErr))
+	correctivePrompt := fmt.Sprintf(
+		"%s\n\nYour previous response was rejected because the %v. "+
+			"Respond again with only a JSON document that satisfies the schema.",
+		prompt,
+		validationErr,
+	)
+	retryResult, err := imageAnalysisService.analyzer.Analyze(ctx, images, correctivePrompt, analyzerOptions)
+	if err != nil {
+		return nil, withUsage(err, result)
+	}
+	addUsage(retryResult, result)
+	if err := validateJSONResult(responseSchema, retryResult); err != nil {
+		return nil, withUsage(
+			fmt.Errorf("response does not satisfy schema %s after retry: %v", responseSchema.Name, err),
+			retryResult,
+		)
+	}
+	return retryResult, nil
+}
+
+// ProcessImageAndPromptStream processes one or more images with a given prompt using the configured analyzer,
+// handing the response to send in chunks as the analyzer produces them.
+// JSON responses are requested from the model but cannot be validated while streaming.
+// HTML responses cannot be sanitized nor markdown responses be converted, both are rejected.
+// The streamed response is returned with its usage once the analyzer is done,
+// the usage of a stream that fails midway is returned in a UsageError.
+func (imageAnalysisService *ImageAnalysisService) ProcessImageAndPromptStream(
+	ctx context.Context,
+	images []ai.Image,
+	prompt string,
+	options *AnalysisOptions,
+	send func(chunk string) error,
+) (*ai.Result, error) {
 	logger, err := log.GetLoggerFromContext(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	analyzerOptions, _, err := imageAnalysisService.resolveOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if analyzerOptions != nil && analyzerOptions.OutputFormat == ai.OutputFormatHTML {
+		return nil, &Error{
+			Message: "the HTML output format is not supported when streaming",
+		}
+	}
+	if options != nil && options.ConvertTo != "" {
+		return nil, &Error{
+			Message: "converting the response is not supported when streaming",
+		}
+	}
+	if options.tiling() != nil {
+		return nil, &Error{
+			Message: "tiling is not supported when streaming",
+		}
+	}
+	prompt, promptTemplate, err := imageAnalysisService.resolvePrompt(prompt, options, analyzerOptions)
+	if err != nil {
+		return nil, err
+	}
+	images, imagesSize, err := imageAnalysisService.validateImagesAndPrompt(images, prompt, options)
+	if err != nil {
+		return nil, err
 	}
 
+	logger.Info(fmt.Sprintf("Streaming analysis of %d image(s) of %d bytes with prompt: %s", len(images), imagesSize, prompt))
+	result, err := imageAnalysisService.analyzer.AnalyzeStream(ctx, images, prompt, analyzerOptions, send)
+	if err != nil {
+		return nil, withUsage(err, result)
+	}
+	logTemplate(logger, promptTemplate)
+	return result, nil
+}
+
+// validateImagesAndPrompt checks the prompt and the images against the configured limits
+// and returns the images with their detected mime types and their total size
+func (imageAnalysisService *ImageAnalysisService) validateImagesAndPrompt(
+	images []ai.Image,
+	prompt string,
+	options *AnalysisOptions,
+) ([]ai.Image, int64, error) {
+	if len(images) > 0 && prompt == "" {
+		return nil, 0, &Error{
+			Message: "no prompt provided",
+		}
+	}
+	return imageAnalysisService.validateImages(images, options)
+}
+
+// validateImages checks the images and PDF documents against the configured limits and returns them with their total size.
+// The format of every image is detected from its content, the declared mime type is replaced by the detected one
+// when mime type correction is enabled. Images of the formats the analyzer does not accept are transcoded,
+// every image is then preprocessed, only the selected pages of the documents are kept.
+// Images are not downscaled in tiling mode. The total size is the one of the inputs passed to the analyzer.
+func (imageAnalysisService *ImageAnalysisService) validateImages(images []ai.Image, options *AnalysisOptions) ([]ai.Image, int64, error) {
+	limits := imageAnalysisService.config.Images
+	preprocessing := imageAnalysisService.config.Preprocessing
+	if options.tiling() != nil {
+		preprocessing.MaxLongEdge = 0
+	}
 	switch {
-	case len(imageData) == 0:
-		return "", &Error{
+	case len(images) == 0:
+		return nil, 0, &Error{
 			Message: "no image provided",
 		}
-	case prompt == "":
-		return "", &Error{
-			Message: "no prompt provided",
+	case limits.MaxCount > 0 && len(images) > limits.MaxCount:
+		return nil, 0, &Error{
+			Message: fmt.Sprintf("%d images exceed the limit of %d images per request", len(images), limits.MaxCount),
+		}
+	}
+	validatedImages := make([]ai.Image, 0, len(images))
+	var totalSize int64
+	for index, image := range images {
+		if len(image.Data) == 0 {
+			return nil, 0, &Error{
+				Message: fmt.Sprintf("image %d is empty", index),
+			}
+		}
+		var err error
+		if document.IsPDF(image.Data) && !imageAnalysisService.analyzer.SupportsDocuments() {
+			return nil, 0, &Error{
+				Message: fmt.Sprintf("document %d is a PDF document, which the configured model provider cannot read", index),
+				Reason:  ErrorReasonUnsupportedImage,
+			}
+		}
+		if document.IsPDF(image.Data) {
+			image, err = validateDocument(index, image, options.pages(), &imageAnalysisService.config.Documents, &limits)
+		} else {
+			image, err = validateImage(index, image, &limits)
+			if err == nil {
+				image, err = preprocessImage(index, image, &preprocessing)
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		validatedImages = append(validatedImages, image)
+		totalSize += int64(len(image.Data))
+	}
+	if limits.MaxTotalBytes > 0 && totalSize > limits.MaxTotalBytes {
+		return nil, 0, &Error{
+			Message: fmt.Sprintf("images of %d bytes exceed the limit of %d bytes per request", totalSize, limits.MaxTotalBytes),
+		}
+	}
+	return validatedImages, totalSize, nil
+}
+
+// resolveOptions converts the request options into analyzer options,
+// resolving the JSON schema the response has to satisfy if any
+func (imageAnalysisService *ImageAnalysisService) resolveOptions(options *AnalysisOptions) (*ai.Options, *schema.Schema, error) {
+	if options == nil {
+		return nil, nil, nil
+	}
+	if err := validateOutputFormat(options); err != nil {
+		return nil, nil, err
+	}
+	analyzerOptions := &ai.Options{
+		OutputFormat: options.outputFormat(),
+		Generation:   options.Generation,
+	}
+	if options.Model != "" {
+		model, err := resolveModel(imageAnalysisService.config.Models, options.Model)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := applyModelLimits(model, &analyzerOptions.Generation); err != nil {
+			return nil, nil, err
+		}
+		analyzerOptions.Model = model.ModelName
+	}
+	if err := validateGenerationParams(&imageAnalysisService.config.Generation, &analyzerOptions.Generation); err != nil {
+		return nil, nil, err
+	}
+	if analyzerOptions.Generation.Seed != nil && !imageAnalysisService.analyzer.SupportsSeed() {
+		return nil, nil, &Error{Message: "seed is not supported by the configured model provider"}
+	}
+	var responseSchema *schema.Schema
+	switch {
+	case options.JSONSchema != "":
+		compiledSchema, err := schema.Compile("request", options.JSONSchema)
+		if err != nil {
+			return nil, nil, &Error{Message: err.Error()}
+		}
+		responseSchema = compiledSchema
+	case options.SchemaName != "":
+		registeredSchema, ok := imageAnalysisService.schemas.Get(options.SchemaName)
+		if !ok {
+			return nil, nil, &Error{Message: fmt.Sprintf("unknown schema %q", options.SchemaName)}
 		}
-	case mimeType != "image/jpeg" && mimeType != "image/png":
-		return "", &Error{
-			Message: fmt.Sprintf("unsupported mime type %q", mimeType),
+		responseSchema = registeredSchema
+	}
+	if responseSchema != nil {
+		analyzerOptions.JSONSchema = responseSchema.Document
+	}
+	return analyzerOptions, responseSchema, nil
+}
+
+// resolvePrompt renders the referenced prompt template and sets its system instruction on the analyzer options.
+// Without a template reference the request prompt is returned unchanged.
+func (imageAnalysisService *ImageAnalysisService) resolvePrompt(
+	prompt string,
+	options *AnalysisOptions,
+	analyzerOptions *ai.Options,
+) (string, *templates.Template, error) {
+	if options == nil || options.Template == nil {
+		return prompt, nil, nil
+	}
+	if prompt != "" {
+		return "", nil, &Error{Message: "a prompt cannot be combined with a prompt template"}
+	}
+	reference := options.Template
+	promptTemplate, ok := imageAnalysisService.templates.Get(reference.ID, reference.Version)
+	if !ok {
+		return "", nil, &Error{Message: fmt.Sprintf("unknown prompt template %q version %d", reference.ID, reference.Version)}
+	}
+	renderedPrompt, systemInstruction, err := promptTemplate.Render(reference.Variables)
+	if err != nil {
+		return "", nil, &Error{Message: err.Error()}
+	}
+	analyzerOptions.SystemInstruction = systemInstruction
+	return renderedPrompt, promptTemplate, nil
+}
+
+func logTemplate(logger log.Loggerer, promptTemplate *templates.Template) {
+	if promptTemplate == nil {
+		return
+	}
+	logger.Info(fmt.Sprintf("Answer produced by prompt template %s version %d", promptTemplate.ID, promptTemplate.Version))
+}
+
+func validateOutputFormat(options *AnalysisOptions) error {
+	outputFormat := options.outputFormat()
+	switch {
+	case !outputFormat.IsValid():
+		return &Error{Message: fmt.Sprintf("unsupported output format %q", options.OutputFormat)}
+	case (options.JSONSchema != "" || options.SchemaName != "") && outputFormat != ai.OutputFormatJSON:
+		return &Error{Message: fmt.Sprintf("a JSON schema cannot be used with output format %q", outputFormat)}
+	case (options.JSONSchema != "" || options.SchemaName != "") && options.Tiling != nil:
+		return &Error{Message: "a JSON schema cannot be used in tiling mode"}
+	case options.ConvertTo == "":
+		return nil
+	case options.ConvertTo != ai.OutputFormatHTML && options.ConvertTo != ai.OutputFormatPlainText:
+		return &Error{Message: fmt.Sprintf("cannot convert the response to %q", options.ConvertTo)}
+	case outputFormat != ai.OutputFormatMarkdown:
+		return &Error{Message: fmt.Sprintf("only markdown responses can be converted, not %q", outputFormat)}
+	}
+	return nil
+}
+
+// renderResult sanitizes HTML responses and converts markdown responses into the requested format
+func renderResult(result *ai.Result, options *AnalysisOptions) (*ai.Result, error) {
+	if options == nil {
+		return result, nil
+	}
+	for index := range result.Candidates {
+		candidate := &result.Candidates[index]
+		switch {
+		case options.outputFormat() == ai.OutputFormatHTML:
+			candidate.Text = render.SanitizeHTML(candidate.Text)
+		case options.ConvertTo == ai.OutputFormatHTML:
+			html, err := render.MarkdownToHTML(candidate.Text)
+			if err != nil {
+				return nil, err
+			}
+			candidate.Text = html
+		case options.ConvertTo == ai.OutputFormatPlainText:
+			candidate.Text = render.MarkdownToPlainText(candidate.Text)
 		}
 	}
+	return result, nil
+}
+
+// validateJSONResult validates the first candidate against the schema and strips any code fence around it
+func validateJSONResult(responseSchema *schema.Schema, result *ai.Result) error {
+	if len(result.Candidates) == 0 {
+		return fmt.Errorf("response has no candidates")
+	}
+	if err := responseSchema.ValidateResponse(result.Candidates[0].Text); err != nil {
+		return err
+	}
+	result.Candidates[0].Text = schema.TrimCodeFence(result.Candidates[0].Text)
+	return nil
+}
 
-	logger.Info(fmt.Sprintf("Processing image of size %d bytes with prompt: %s", len(imageData), prompt))
-	return imageAnalysisService.analyzer.Analyze(ctx, imageData, mimeType, prompt)
+// addUsage adds the tokens and cost of a previous attempt to the result
+func addUsage(result *ai.Result, previous *ai.Result) {
+	result.Usage.PromptTokens += previous.Usage.PromptTokens
+	result.Usage.CandidatesTokens += previous.Usage.CandidatesTokens
+	result.Usage.TotalTokens += previous.Usage.TotalTokens
+	result.EstimatedCost += previous.EstimatedCost
 }
 
 // Close closes the image analysis service and its underlying analyzer.
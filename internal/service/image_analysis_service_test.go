@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -10,15 +11,20 @@ import (
 	loggerMock "github.com/quadev-ltd/qd-common/pkg/log/mock"
 	"github.com/stretchr/testify/assert"
 
+	"qd-image-analysis-api/internal/ai"
 	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/templates"
 )
 
+var testImages = []ai.Image{{Data: testPNG, MimeType: "image/png"}}
+
 func TestNewImageAnalysisService(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	assert.NotNil(t, service)
 	assert.Equal(t, mockAnalyzer, service.analyzer)
@@ -30,25 +36,26 @@ func TestProcessImageAndPrompt_Success(t *testing.T) {
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
 	mockLogger := loggerMock.NewMockLoggerer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
 	ctx = log.AddCorrelationIDToOutgoingContext(ctx, "test-correlation-id")
 
-	imageData := []byte("test-image-data")
-	mimeType := "image/png"
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
 	prompt := "What is in this image?"
-	expectedResponse := "# Image Analysis\n\nThis is a test image."
+	expectedResponse := &ai.Result{
+		Candidates: []ai.Candidate{{Text: "# Image Analysis\n\nThis is a test image.", FinishReason: ai.FinishReasonStop}},
+	}
 
 	mockLogger.EXPECT().
 		Info(gomock.Any()).
 		Times(1)
 
 	mockAnalyzer.EXPECT().
-		Analyze(ctx, imageData, mimeType, prompt).
+		Analyze(ctx, images, prompt, nil).
 		Return(expectedResponse, nil)
 
-	response, err := service.ProcessImageAndPrompt(ctx, imageData, mimeType, prompt)
+	response, err := service.ProcessImageAndPrompt(ctx, images, prompt, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResponse, response)
@@ -60,15 +67,15 @@ func TestProcessImageAndPrompt_NoImage(t *testing.T) {
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
 	mockLogger := loggerMock.NewMockLoggerer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
 	ctx = log.AddCorrelationIDToOutgoingContext(ctx, "test-correlation-id")
 
-	response, err := service.ProcessImageAndPrompt(ctx, nil, "image/png", "test prompt")
+	response, err := service.ProcessImageAndPrompt(ctx, nil, "test prompt", nil)
 
 	assert.Error(t, err)
-	assert.Empty(t, response)
+	assert.Nil(t, response)
 	assert.Equal(t, "no image provided", err.Error())
 }
 
@@ -78,15 +85,15 @@ func TestProcessImageAndPrompt_NoPrompt(t *testing.T) {
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
 	mockLogger := loggerMock.NewMockLoggerer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
 	ctx = log.AddCorrelationIDToOutgoingContext(ctx, "test-correlation-id")
 
-	response, err := service.ProcessImageAndPrompt(ctx, []byte("test"), "image/png", "")
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "", nil)
 
 	assert.Error(t, err)
-	assert.Empty(t, response)
+	assert.Nil(t, response)
 	assert.Equal(t, "no prompt provided", err.Error())
 }
 
@@ -96,15 +103,15 @@ func TestProcessImageAndPrompt_InvalidMimeType(t *testing.T) {
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
 	mockLogger := loggerMock.NewMockLoggerer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
 	ctx = log.AddCorrelationIDToOutgoingContext(ctx, "test-correlation-id")
 
-	response, err := service.ProcessImageAndPrompt(ctx, []byte("test"), "image/gif", "test prompt")
+	response, err := service.ProcessImageAndPrompt(ctx, []ai.Image{{Data: []byte("test"), MimeType: "image/gif"}}, "test prompt", nil)
 
 	assert.Error(t, err)
-	assert.Empty(t, response)
+	assert.Nil(t, response)
 	assert.Equal(t, "unsupported mime type \"image/gif\"", err.Error())
 }
 
@@ -114,13 +121,12 @@ func TestProcessImageAndPrompt_AnalyzerError(t *testing.T) {
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
 	mockLogger := loggerMock.NewMockLoggerer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
 	ctx = log.AddCorrelationIDToOutgoingContext(ctx, "test-correlation-id")
 
-	imageData := []byte("test-image-data")
-	mimeType := "image/png"
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
 	prompt := "What is in this image?"
 	expectedError := errors.New("analyzer error")
 
@@ -129,13 +135,13 @@ func TestProcessImageAndPrompt_AnalyzerError(t *testing.T) {
 		Times(1)
 
 	mockAnalyzer.EXPECT().
-		Analyze(ctx, imageData, mimeType, prompt).
-		Return("", expectedError)
+		Analyze(ctx, images, prompt, nil).
+		Return(nil, expectedError)
 
-	response, err := service.ProcessImageAndPrompt(ctx, imageData, mimeType, prompt)
+	response, err := service.ProcessImageAndPrompt(ctx, images, prompt, nil)
 
 	assert.Error(t, err)
-	assert.Empty(t, response)
+	assert.Nil(t, response)
 	assert.Equal(t, expectedError, err)
 }
 
@@ -144,12 +150,576 @@ func TestProcessImageAndPrompt_NoLoggerInContext(t *testing.T) {
 	defer controller.Finish()
 
 	mockAnalyzer := mock.NewMockAnalyzer(controller)
-	service := NewImageAnalysisService(mockAnalyzer)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
 
 	ctx := context.Background()
-	response, err := service.ProcessImageAndPrompt(ctx, []byte("test"), "image/png", "test prompt")
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", nil)
 
 	assert.Error(t, err)
-	assert.Empty(t, response)
+	assert.Nil(t, response)
 	assert.Contains(t, err.Error(), "Logger not found in context")
 }
+
+func TestProcessImageAndPromptStream_Success(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
+	prompt := "What is in this image?"
+
+	mockLogger.EXPECT().
+		Info(gomock.Any()).
+		Times(1)
+
+	mockAnalyzer.EXPECT().
+		AnalyzeStream(ctx, images, prompt, nil, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, _ *ai.Options, send func(chunk string) error) (*ai.Result, error) {
+			if err := send("# Image "); err != nil {
+				return nil, err
+			}
+			if err := send("Analysis"); err != nil {
+				return nil, err
+			}
+			return &ai.Result{
+				Candidates: []ai.Candidate{{Text: "# Image Analysis", FinishReason: ai.FinishReasonStop}},
+				Usage:      ai.Usage{PromptTokens: 10, CandidatesTokens: 4, TotalTokens: 14},
+			}, nil
+		})
+
+	var chunks []string
+	result, err := service.ProcessImageAndPromptStream(ctx, images, prompt, nil, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"# Image ", "Analysis"}, chunks)
+	assert.Equal(t, int32(14), result.Usage.TotalTokens)
+}
+
+func TestProcessImageAndPromptStream_Cancelled(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
+
+	mockLogger.EXPECT().
+		Info(gomock.Any()).
+		Times(1)
+
+	mockAnalyzer.EXPECT().
+		AnalyzeStream(ctx, images, "prompt", nil, gomock.Any()).
+		Return(&ai.Result{
+			Candidates:    []ai.Candidate{{Text: "# Image "}},
+			Usage:         ai.Usage{CandidatesTokens: 1, TotalTokens: 1},
+			EstimatedCost: 0.01,
+		}, context.Canceled)
+
+	result, err := service.ProcessImageAndPromptStream(ctx, images, "prompt", nil, func(chunk string) error {
+		return nil
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.Canceled)
+	var usageErr *UsageError
+	assert.ErrorAs(t, err, &usageErr)
+	assert.Equal(t, ai.Usage{CandidatesTokens: 1, TotalTokens: 1}, usageErr.Usage)
+	assert.Equal(t, 0.01, usageErr.EstimatedCost)
+}
+
+func TestProcessImageAndPromptStream_InvalidMimeType(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	_, err := service.ProcessImageAndPromptStream(ctx, []ai.Image{{Data: []byte("test"), MimeType: "image/gif"}}, "test prompt", nil, func(string) error {
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "unsupported mime type \"image/gif\"", err.Error())
+}
+
+const testInvoiceSchema = `{"type":"object","properties":{"total":{"type":"number"}},"required":["total"]}`
+
+func newJSONResult(text string, promptTokens int32) *ai.Result {
+	return &ai.Result{
+		Candidates: []ai.Candidate{{Text: text, FinishReason: ai.FinishReasonStop}},
+		Usage:      ai.Usage{PromptTokens: promptTokens, CandidatesTokens: 10, TotalTokens: promptTokens + 10},
+	}
+}
+
+func TestProcessImageAndPrompt_JSONSchemaValid(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, images, "Read the invoice", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, options *ai.Options) (*ai.Result, error) {
+			assert.Equal(t, ai.OutputFormatJSON, options.OutputFormat)
+			assert.Equal(t, "object", options.JSONSchema["type"])
+			return newJSONResult("```json\n{\"total\": 12.5}\n```", 100), nil
+		})
+
+	response, err := service.ProcessImageAndPrompt(ctx, images, "Read the invoice", &AnalysisOptions{
+		OutputFormat: ai.OutputFormatJSON,
+		JSONSchema:   testInvoiceSchema,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"total": 12.5}`, response.Text())
+}
+
+func TestProcessImageAndPrompt_JSONSchemaRetrySucceeds(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	images := []ai.Image{{Data: testPNG, MimeType: "image/png"}}
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockLogger.EXPECT().Warn(gomock.Any()).Times(1)
+	gomock.InOrder(
+		mockAnalyzer.EXPECT().
+			Analyze(ctx, images, "Read the invoice", gomock.Any()).
+			Return(newJSONResult(`{"amount": 12.5}`, 100), nil),
+		mockAnalyzer.EXPECT().
+			Analyze(ctx, images, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ []ai.Image, prompt string, _ *ai.Options) (*ai.Result, error) {
+				assert.Contains(t, prompt, "Read the invoice")
+				assert.Contains(t, prompt, "Your previous response was rejected")
+				return newJSONResult(`{"total": 12.5}`, 150), nil
+			}),
+	)
+
+	response, err := service.ProcessImageAndPrompt(ctx, images, "Read the invoice", &AnalysisOptions{
+		JSONSchema: testInvoiceSchema,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"total": 12.5}`, response.Text())
+	assert.Equal(t, int32(250), response.Usage.PromptTokens)
+	assert.Equal(t, int32(270), response.Usage.TotalTokens)
+}
+
+func TestProcessImageAndPrompt_JSONSchemaRetryFails(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockLogger.EXPECT().Warn(gomock.Any()).Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(newJSONResult("not json", 100), nil).
+		Times(2)
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "Read the invoice", &AnalysisOptions{
+		JSONSchema: testInvoiceSchema,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after retry")
+	assert.Nil(t, response)
+	var usageErr *UsageError
+	assert.ErrorAs(t, err, &usageErr)
+	assert.Equal(t, ai.Usage{PromptTokens: 200, CandidatesTokens: 20, TotalTokens: 220}, usageErr.Usage)
+}
+
+func TestProcessImageAndPrompt_UnknownSchemaName(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", &AnalysisOptions{
+		SchemaName: "invoice",
+	})
+
+	var serviceError *Error
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, `unknown schema "invoice"`, serviceError.Message)
+	assert.Nil(t, response)
+}
+
+func TestProcessImageAndPrompt_ConvertToPlainText(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, gomock.Any(), "test prompt", &ai.Options{OutputFormat: ai.OutputFormatMarkdown}).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: "# Cat\n\nA **tabby** cat."}}}, nil)
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", &AnalysisOptions{
+		ConvertTo: ai.OutputFormatPlainText,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Cat\n\nA tabby cat.", response.Text())
+}
+
+func TestProcessImageAndPrompt_SanitizesHTML(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, gomock.Any(), "test prompt", &ai.Options{OutputFormat: ai.OutputFormatHTML}).
+		Return(&ai.Result{Candidates: []ai.Candidate{{Text: `<p>A cat</p><script>alert(1)</script>`}}}, nil)
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", &AnalysisOptions{
+		OutputFormat: ai.OutputFormatHTML,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>A cat</p>", response.Text())
+}
+
+func TestProcessImageAndPrompt_InvalidOutputOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  *AnalysisOptions
+		expected string
+	}{
+		{
+			name:     "unsupported output format",
+			options:  &AnalysisOptions{OutputFormat: "xml"},
+			expected: `unsupported output format "xml"`,
+		},
+		{
+			name:     "schema without JSON output",
+			options:  &AnalysisOptions{OutputFormat: ai.OutputFormatHTML, SchemaName: "invoice"},
+			expected: `a JSON schema cannot be used with output format "html"`,
+		},
+		{
+			name:     "unsupported conversion",
+			options:  &AnalysisOptions{ConvertTo: ai.OutputFormatJSON},
+			expected: `cannot convert the response to "json"`,
+		},
+		{
+			name:     "conversion of non markdown output",
+			options:  &AnalysisOptions{OutputFormat: ai.OutputFormatPlainText, ConvertTo: ai.OutputFormatHTML},
+			expected: `only markdown responses can be converted, not "plain_text"`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			mockLogger := loggerMock.NewMockLoggerer(controller)
+			service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, nil)
+			ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+			response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", testCase.options)
+
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expected, serviceError.Message)
+			assert.Nil(t, response)
+		})
+	}
+}
+
+func TestProcessImageAndPromptStream_ConvertNotSupported(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, nil)
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	_, err := service.ProcessImageAndPromptStream(
+		ctx,
+		testImages,
+		"test prompt",
+		&AnalysisOptions{ConvertTo: ai.OutputFormatHTML},
+		func(string) error { return nil },
+	)
+
+	var serviceError *Error
+	assert.ErrorAs(t, err, &serviceError)
+}
+
+func TestProcessImageAndPromptStream_UnsupportedOptionsBeforeImages(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, nil)
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	// the images are invalid, the options are rejected before the images are validated
+	images := []ai.Image{{Data: []byte("not an image"), MimeType: "image/png"}}
+
+	testCases := []struct {
+		name          string
+		options       *AnalysisOptions
+		expectedError string
+	}{
+		{
+			name:          "Error_ConvertTo",
+			options:       &AnalysisOptions{ConvertTo: ai.OutputFormatPlainText},
+			expectedError: "converting the response is not supported when streaming",
+		},
+		{
+			name:          "Error_Tiling",
+			options:       &AnalysisOptions{Tiling: &TilingOptions{}},
+			expectedError: "tiling is not supported when streaming",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := service.ProcessImageAndPromptStream(ctx, images, "test prompt", testCase.options, func(string) error { return nil })
+
+			assert.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestProcessImageAndPromptStream_HTMLNotSupported(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, nil)
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+	_, err := service.ProcessImageAndPromptStream(
+		ctx,
+		testImages,
+		"test prompt",
+		&AnalysisOptions{OutputFormat: ai.OutputFormatHTML},
+		func(string) error { return nil },
+	)
+
+	var serviceError *Error
+	assert.ErrorAs(t, err, &serviceError)
+	assert.EqualError(t, err, "the HTML output format is not supported when streaming")
+}
+
+func newTestTemplates(t *testing.T) *templates.Registry {
+	t.Helper()
+	registry := templates.NewRegistry()
+	for _, content := range []string{
+		"id: caption\nversion: 1\nprompt: Write a caption.",
+		"id: caption\nversion: 2\nsystem_instruction: Answer in {{.language}}.\nprompt: Write a {{.tone}} caption.",
+	} {
+		promptTemplate, err := templates.Parse([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, registry.Add(promptTemplate))
+	}
+	return registry
+}
+
+func TestProcessImageAndPrompt_Template(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, newTestTemplates(t), nil)
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	expectedResponse := &ai.Result{Candidates: []ai.Candidate{{Text: "Sunset over the bay"}}}
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockLogger.EXPECT().Info("Answer produced by prompt template caption version 2").Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, gomock.Any(), "Write a funny caption.", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, options *ai.Options) (*ai.Result, error) {
+			assert.Equal(t, "Answer in French.", options.SystemInstruction)
+			return expectedResponse, nil
+		})
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "", &AnalysisOptions{
+		Template: &TemplateReference{
+			ID:        "caption",
+			Variables: map[string]string{"language": "French", "tone": "funny"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResponse, response)
+}
+
+func TestProcessImageAndPrompt_InvalidTemplate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prompt   string
+		template *TemplateReference
+		expected string
+	}{
+		{
+			name:     "prompt and template",
+			prompt:   "Describe it",
+			template: &TemplateReference{ID: "caption"},
+			expected: "a prompt cannot be combined with a prompt template",
+		},
+		{
+			name:     "unknown version",
+			template: &TemplateReference{ID: "caption", Version: 3},
+			expected: `unknown prompt template "caption" version 3`,
+		},
+		{
+			name:     "missing variable",
+			template: &TemplateReference{ID: "caption", Version: 2, Variables: map[string]string{"tone": "funny"}},
+			expected: "failed to render prompt template",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			mockLogger := loggerMock.NewMockLoggerer(controller)
+			service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, newTestTemplates(t), nil)
+			ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+			response, err := service.ProcessImageAndPrompt(ctx, testImages, testCase.prompt, &AnalysisOptions{
+				Template: testCase.template,
+			})
+
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Contains(t, serviceError.Message, testCase.expected)
+			assert.Nil(t, response)
+		})
+	}
+}
+
+func TestProcessImageAndPrompt_MultipleImages(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, &config.Config{
+		Images: config.ImagesConfig{MaxCount: 2, MaxTotalBytes: int64(len(testPNG) + len(testJPEG))},
+	})
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	images := []ai.Image{
+		{Data: testPNG, MimeType: "image/png"},
+		{Data: testJPEG, MimeType: "image/jpeg"},
+	}
+	expectedResponse := &ai.Result{Candidates: []ai.Candidate{{Text: "The door is dented"}}}
+
+	mockLogger.EXPECT().
+		Info(fmt.Sprintf("Processing 2 image(s) of %d bytes with prompt: Compare these photos", len(testPNG)+len(testJPEG))).
+		Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, images, "Compare these photos", nil).
+		Return(expectedResponse, nil)
+
+	response, err := service.ProcessImageAndPrompt(ctx, images, "Compare these photos", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResponse, response)
+}
+
+func TestProcessImageAndPrompt_ImageLimits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		images   []ai.Image
+		expected string
+	}{
+		{
+			name: "too many images",
+			images: []ai.Image{
+				{Data: testPNG, MimeType: "image/png"},
+				{Data: testPNG, MimeType: "image/png"},
+				{Data: testPNG, MimeType: "image/png"},
+			},
+			expected: "3 images exceed the limit of 2 images per request",
+		},
+		{
+			name: "too many bytes",
+			images: []ai.Image{
+				{Data: testPNG, MimeType: "image/png"},
+				{Data: testPNG, MimeType: "image/png"},
+			},
+			expected: fmt.Sprintf(
+				"images of %d bytes exceed the limit of %d bytes per request",
+				2*len(testPNG),
+				2*len(testPNG)-1,
+			),
+		},
+		{
+			name: "empty image",
+			images: []ai.Image{
+				{Data: testPNG, MimeType: "image/png"},
+				{MimeType: "image/png"},
+			},
+			expected: "image 1 is empty",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			mockLogger := loggerMock.NewMockLoggerer(controller)
+			service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, &config.Config{
+				Images: config.ImagesConfig{MaxCount: 2, MaxTotalBytes: int64(2*len(testPNG) - 1)},
+			})
+			ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+
+			response, err := service.ProcessImageAndPrompt(ctx, testCase.images, "test prompt", nil)
+
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expected, serviceError.Message)
+			assert.Nil(t, response)
+		})
+	}
+}
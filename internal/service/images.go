@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/document"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+// defaultNativeMimeTypes are passed to the analyzer when no native mime types are configured
+var defaultNativeMimeTypes = []string{imageformat.JPEG.MimeType, imageformat.PNG.MimeType}
+
+// imageHandling tells how images of a mime type are passed to the analyzer
+type imageHandling int
+
+const (
+	imageUnsupported imageHandling = iota
+	imageNative
+	imageTranscoded
+)
+
+func handlingOf(limits *config.ImagesConfig, mimeType string) imageHandling {
+	nativeMimeTypes := limits.NativeMimeTypes
+	if len(nativeMimeTypes) == 0 {
+		nativeMimeTypes = defaultNativeMimeTypes
+	}
+	switch {
+	case slices.Contains(nativeMimeTypes, mimeType):
+		return imageNative
+	case slices.Contains(limits.TranscodedMimeTypes, mimeType):
+		return imageTranscoded
+	}
+	return imageUnsupported
+}
+
+// validateImage detects the format of the image from its content, checks it against the limits
+// and transcodes it when its format is not passed to the analyzer as it is
+func validateImage(index int, image ai.Image, limits *config.ImagesConfig) (ai.Image, error) {
+	// documents declared as PDF whose content is an image are reported as mime type mismatches below
+	if !limits.CorrectMimeType && image.MimeType != document.MimeTypePDF && handlingOf(limits, image.MimeType) == imageUnsupported {
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("unsupported mime type %q", image.MimeType),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	}
+	info, err := inspectImage(index, image.Data, limits)
+	if err != nil {
+		return ai.Image{}, err
+	}
+	if info.Format.MimeType != image.MimeType {
+		if !limits.CorrectMimeType {
+			return ai.Image{}, &Error{
+				Message: fmt.Sprintf("image %d is declared as %q but its content is %q", index, image.MimeType, info.Format.MimeType),
+				Reason:  ErrorReasonMimeTypeMismatch,
+			}
+		}
+		image.MimeType = info.Format.MimeType
+	}
+	if handlingOf(limits, image.MimeType) == imageTranscoded {
+		return transcodeImage(index, image, limits)
+	}
+	return image, nil
+}
+
+// inspectImage detects the format of the image from its content and checks its dimensions against the limits
+func inspectImage(index int, data []byte, limits *config.ImagesConfig) (*imageformat.Info, error) {
+	info, err := imageformat.Inspect(data)
+	switch {
+	case errors.Is(err, imageformat.ErrUnknownFormat):
+		return nil, &Error{
+			Message: fmt.Sprintf("image %d is not in a supported format", index),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	case err != nil:
+		return nil, &Error{
+			Message: fmt.Sprintf("image %d is invalid: %v", index, err),
+			Reason:  ErrorReasonCorruptImage,
+		}
+	case handlingOf(limits, info.Format.MimeType) == imageUnsupported:
+		return nil, &Error{
+			Message: fmt.Sprintf("image %d is in the unsupported format %s", index, info.Format.Name),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	}
+	smallest, largest := info.Width, info.Height
+	if smallest > largest {
+		smallest, largest = largest, smallest
+	}
+	if (limits.MinDimension > 0 && smallest < limits.MinDimension) ||
+		(limits.MaxDimension > 0 && largest > limits.MaxDimension) {
+		return nil, &Error{
+			Message: fmt.Sprintf(
+				"image %d of %dx%d pixels is outside the allowed dimensions %s",
+				index,
+				info.Width,
+				info.Height,
+				formatRange(limits.MinDimension, limits.MaxDimension, limits.MaxDimension > 0),
+			),
+			Reason: ErrorReasonImageDimensions,
+		}
+	}
+	return info, nil
+}
+
+// transcodeImage converts an image of a format the analyzer does not accept into the configured format,
+// a PNG unless configured otherwise
+func transcodeImage(index int, image ai.Image, limits *config.ImagesConfig) (ai.Image, error) {
+	target := imageformat.PNG
+	if limits.TranscodeTo != "" {
+		format, ok := imageformat.ByMimeType(limits.TranscodeTo)
+		if !ok || (format != imageformat.PNG && format != imageformat.JPEG) {
+			return ai.Image{}, fmt.Errorf("images cannot be transcoded to %q", limits.TranscodeTo)
+		}
+		target = format
+	}
+	data, err := imageformat.Transcode(image.Data, target, limits.TranscodeQuality)
+	switch {
+	case errors.Is(err, imageformat.ErrNotDecodable):
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("image %d cannot be converted: %v", index, err),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	case errors.Is(err, imageformat.ErrCorrupt):
+		return ai.Image{}, &Error{
+			Message: fmt.Sprintf("image %d is invalid: %v", index, err),
+			Reason:  ErrorReasonCorruptImage,
+		}
+	case err != nil:
+		return ai.Image{}, err
+	}
+	return ai.Image{Data: data, MimeType: target.MimeType}, nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/bmp"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+var (
+	testPNG  = encodeTestImage(png.Encode, 32, 32)
+	testJPEG = encodeTestImage(func(buffer io.Writer, img image.Image) error { return jpeg.Encode(buffer, img, nil) }, 32, 32)
+	testBMP  = encodeTestImage(bmp.Encode, 32, 32)
+	testHEIC = newTestHEIC(32, 32)
+)
+
+var testBMPAsPNG, _ = imageformat.Transcode(testBMP, imageformat.PNG, 0)
+
+// newTestHEIC returns the header of a HEIC file, enough to detect its format and dimensions
+func newTestHEIC(width, height int) []byte {
+	box := func(kind string, fullBox bool, payload ...byte) []byte {
+		if fullBox {
+			payload = append([]byte{0, 0, 0, 0}, payload...)
+		}
+		return append(append(binary.BigEndian.AppendUint32(nil, uint32(8+len(payload))), kind...), payload...)
+	}
+	extents := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(width)), uint32(height))
+	properties := box("iprp", false, append(
+		box("ipco", false, box("ispe", true, extents...)...),
+		box("ipma", true, 0, 0, 0, 1, 0, 1, 1, 0x81)...,
+	)...)
+	meta := box("meta", true, append(box("pitm", true, 0, 1), properties...)...)
+	return append(box("ftyp", false, []byte("heic\x00\x00\x00\x00mif1")...), meta...)
+}
+
+func encodeTestImage(encode func(io.Writer, image.Image) error, width, height int) []byte {
+	var buffer bytes.Buffer
+	if err := encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		panic(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestValidateImages(t *testing.T) {
+	testCases := []struct {
+		name           string
+		limits         config.ImagesConfig
+		images         []ai.Image
+		expectedImages []ai.Image
+		expectedReason ErrorReason
+		expectedError  string
+	}{
+		{
+			name:           "Success",
+			images:         []ai.Image{{Data: testPNG, MimeType: "image/png"}, {Data: testJPEG, MimeType: "image/jpeg"}},
+			expectedImages: []ai.Image{{Data: testPNG, MimeType: "image/png"}, {Data: testJPEG, MimeType: "image/jpeg"}},
+		},
+		{
+			name:           "Error_MimeTypeMismatch",
+			images:         []ai.Image{{Data: testJPEG, MimeType: "image/png"}},
+			expectedReason: ErrorReasonMimeTypeMismatch,
+			expectedError:  `image 0 is declared as "image/png" but its content is "image/jpeg"`,
+		},
+		{
+			name:           "Success_CorrectedMimeType",
+			limits:         config.ImagesConfig{CorrectMimeType: true},
+			images:         []ai.Image{{Data: testJPEG, MimeType: "application/octet-stream"}},
+			expectedImages: []ai.Image{{Data: testJPEG, MimeType: "image/jpeg"}},
+		},
+		{
+			name:           "Error_FormatNotConfigured",
+			images:         []ai.Image{{Data: testBMP, MimeType: "image/bmp"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  `unsupported mime type "image/bmp"`,
+		},
+		{
+			name: "Error_FormatNotConfigured_CorrectedMimeType",
+			limits: config.ImagesConfig{
+				CorrectMimeType: true,
+				NativeMimeTypes: []string{"image/jpeg"},
+			},
+			images:         []ai.Image{{Data: testPNG, MimeType: "image/jpeg"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  "image 0 is in the unsupported format png",
+		},
+		{
+			name:           "Success_Transcoded",
+			limits:         config.ImagesConfig{TranscodedMimeTypes: []string{"image/bmp"}},
+			images:         []ai.Image{{Data: testBMP, MimeType: "image/bmp"}, {Data: testPNG, MimeType: "image/png"}},
+			expectedImages: []ai.Image{{Data: testBMPAsPNG, MimeType: "image/png"}, {Data: testPNG, MimeType: "image/png"}},
+		},
+		{
+			name: "Error_TranscodedFormatNotDecodable",
+			limits: config.ImagesConfig{
+				CorrectMimeType:     true,
+				TranscodedMimeTypes: []string{"image/heic"},
+			},
+			images:         []ai.Image{{Data: testHEIC, MimeType: "image/heic"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  "image 0 cannot be converted: image format cannot be decoded: heic",
+		},
+		{
+			name:           "Success_NativeHEIC",
+			limits:         config.ImagesConfig{NativeMimeTypes: []string{"image/heic"}},
+			images:         []ai.Image{{Data: testHEIC, MimeType: "image/heic"}},
+			expectedImages: []ai.Image{{Data: testHEIC, MimeType: "image/heic"}},
+		},
+		{
+			name:           "Error_UnknownFormat",
+			images:         []ai.Image{{Data: []byte("<html></html>"), MimeType: "image/png"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  "image 0 is not in a supported format",
+		},
+		{
+			name:           "Error_UnknownFormat_CorrectedMimeType",
+			limits:         config.ImagesConfig{CorrectMimeType: true},
+			images:         []ai.Image{{Data: []byte("<html></html>"), MimeType: "text/html"}},
+			expectedReason: ErrorReasonUnsupportedImage,
+			expectedError:  "image 0 is not in a supported format",
+		},
+		{
+			name:           "Error_Corrupt",
+			images:         []ai.Image{{Data: testPNG[:20], MimeType: "image/png"}},
+			expectedReason: ErrorReasonCorruptImage,
+			expectedError:  "image 0 is invalid: corrupt image: unexpected EOF",
+		},
+		{
+			name:           "Error_TooSmall",
+			limits:         config.ImagesConfig{MinDimension: 64},
+			images:         []ai.Image{{Data: testPNG, MimeType: "image/png"}},
+			expectedReason: ErrorReasonImageDimensions,
+			expectedError:  "image 0 of 32x32 pixels is outside the allowed dimensions [64, +inf)",
+		},
+		{
+			name:   "Error_TooLarge",
+			limits: config.ImagesConfig{MinDimension: 1, MaxDimension: 16},
+			images: []ai.Image{
+				{Data: testPNG, MimeType: "image/png"},
+				{Data: encodeTestImage(png.Encode, 8, 17), MimeType: "image/png"},
+			},
+			expectedReason: ErrorReasonImageDimensions,
+			expectedError:  "image 0 of 32x32 pixels is outside the allowed dimensions [1, 16]",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			service := NewImageAnalysisService(nil, nil, nil, &config.Config{Images: testCase.limits})
+
+			images, _, err := service.validateImages(testCase.images, nil)
+
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedImages, images)
+				return
+			}
+			var serviceError *Error
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, testCase.expectedError, serviceError.Message)
+			assert.Equal(t, testCase.expectedReason, serviceError.Reason)
+			assert.Nil(t, images)
+		})
+	}
+}
+
+func TestValidateImages_DoesNotModifyRequestImages(t *testing.T) {
+	service := NewImageAnalysisService(nil, nil, nil, &config.Config{
+		Images: config.ImagesConfig{CorrectMimeType: true},
+	})
+	images := []ai.Image{{Data: testPNG, MimeType: "image/jpeg"}}
+
+	validatedImages, _, err := service.validateImages(images, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", validatedImages[0].MimeType)
+	assert.Equal(t, "image/jpeg", images[0].MimeType)
+}
+
+func TestTranscodeImage_JPEG(t *testing.T) {
+	limits := &config.ImagesConfig{TranscodeTo: "image/jpeg", TranscodeQuality: 75}
+
+	transcoded, err := transcodeImage(0, ai.Image{Data: testBMP, MimeType: "image/bmp"}, limits)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", transcoded.MimeType)
+	info, err := imageformat.Inspect(transcoded.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, imageformat.JPEG, info.Format)
+}
+
+func TestTranscodeImage_InvalidTarget(t *testing.T) {
+	limits := &config.ImagesConfig{TranscodeTo: "image/webp"}
+
+	_, err := transcodeImage(0, ai.Image{Data: testBMP, MimeType: "image/bmp"}, limits)
+
+	assert.EqualError(t, err, `images cannot be transcoded to "image/webp"`)
+}
@@ -6,6 +6,8 @@ package mock
 
 import (
 	context "context"
+	ai "qd-image-analysis-api/internal/ai"
+	service "qd-image-analysis-api/internal/service"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -49,16 +51,31 @@ func (mr *MockImageAnalysisServicerMockRecorder) Close() *gomock.Call {
 }
 
 // ProcessImageAndPrompt mocks base method.
-func (m *MockImageAnalysisServicer) ProcessImageAndPrompt(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error) {
+func (m *MockImageAnalysisServicer) ProcessImageAndPrompt(ctx context.Context, images []ai.Image, prompt string, options *service.AnalysisOptions) (*ai.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessImageAndPrompt", ctx, imageData, mimeType, prompt)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "ProcessImageAndPrompt", ctx, images, prompt, options)
+	ret0, _ := ret[0].(*ai.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProcessImageAndPrompt indicates an expected call of ProcessImageAndPrompt.
-func (mr *MockImageAnalysisServicerMockRecorder) ProcessImageAndPrompt(ctx, imageData, mimeType, prompt interface{}) *gomock.Call {
+func (mr *MockImageAnalysisServicerMockRecorder) ProcessImageAndPrompt(ctx, images, prompt, options interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessImageAndPrompt", reflect.TypeOf((*MockImageAnalysisServicer)(nil).ProcessImageAndPrompt), ctx, imageData, mimeType, prompt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessImageAndPrompt", reflect.TypeOf((*MockImageAnalysisServicer)(nil).ProcessImageAndPrompt), ctx, images, prompt, options)
+}
+
+// ProcessImageAndPromptStream mocks base method.
+func (m *MockImageAnalysisServicer) ProcessImageAndPromptStream(ctx context.Context, images []ai.Image, prompt string, options *service.AnalysisOptions, send func(string) error) (*ai.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessImageAndPromptStream", ctx, images, prompt, options, send)
+	ret0, _ := ret[0].(*ai.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessImageAndPromptStream indicates an expected call of ProcessImageAndPromptStream.
+func (mr *MockImageAnalysisServicerMockRecorder) ProcessImageAndPromptStream(ctx, images, prompt, options, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessImageAndPromptStream", reflect.TypeOf((*MockImageAnalysisServicer)(nil).ProcessImageAndPromptStream), ctx, images, prompt, options, send)
 }
@@ -0,0 +1,97 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: session_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	ai "qd-image-analysis-api/internal/ai"
+	service "qd-image-analysis-api/internal/service"
+	session "qd-image-analysis-api/internal/session"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSessionServicer is a mock of SessionServicer interface.
+type MockSessionServicer struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionServicerMockRecorder
+}
+
+// MockSessionServicerMockRecorder is the mock recorder for MockSessionServicer.
+type MockSessionServicerMockRecorder struct {
+	mock *MockSessionServicer
+}
+
+// NewMockSessionServicer creates a new mock instance.
+func NewMockSessionServicer(ctrl *gomock.Controller) *MockSessionServicer {
+	mock := &MockSessionServicer{ctrl: ctrl}
+	mock.recorder = &MockSessionServicerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionServicer) EXPECT() *MockSessionServicerMockRecorder {
+	return m.recorder
+}
+
+// CreateSession mocks base method.
+func (m *MockSessionServicer) CreateSession(ctx context.Context, owner string, images []ai.Image) (*session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, owner, images)
+	ret0, _ := ret[0].(*session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockSessionServicerMockRecorder) CreateSession(ctx, owner, images interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockSessionServicer)(nil).CreateSession), ctx, owner, images)
+}
+
+// DeleteSession mocks base method.
+func (m *MockSessionServicer) DeleteSession(ctx context.Context, owner, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, owner, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockSessionServicerMockRecorder) DeleteSession(ctx, owner, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockSessionServicer)(nil).DeleteSession), ctx, owner, sessionID)
+}
+
+// GetSession mocks base method.
+func (m *MockSessionServicer) GetSession(ctx context.Context, owner, sessionID string) (*session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, owner, sessionID)
+	ret0, _ := ret[0].(*session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockSessionServicerMockRecorder) GetSession(ctx, owner, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockSessionServicer)(nil).GetSession), ctx, owner, sessionID)
+}
+
+// SendMessage mocks base method.
+func (m *MockSessionServicer) SendMessage(ctx context.Context, owner, sessionID, prompt string, options *service.AnalysisOptions) (*ai.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessage", ctx, owner, sessionID, prompt, options)
+	ret0, _ := ret[0].(*ai.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessage indicates an expected call of SendMessage.
+func (mr *MockSessionServicerMockRecorder) SendMessage(ctx, owner, sessionID, prompt, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockSessionServicer)(nil).SendMessage), ctx, owner, sessionID, prompt, options)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+)
+
+// resolveModel looks the requested model up in the catalog, first by alias and then by concrete model name.
+// A model name shared by several aliases resolves to the first of them in alphabetical order.
+func resolveModel(models map[string]config.ModelConfig, name string) (*config.ModelConfig, error) {
+	if model, ok := models[name]; ok {
+		return &model, nil
+	}
+	aliases := make([]string, 0, len(models))
+	for alias := range models {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if model := models[alias]; model.ModelName == name {
+			return &model, nil
+		}
+	}
+	return nil, &Error{
+		Message: fmt.Sprintf("unknown model %q, available models are %v", name, aliases),
+	}
+}
+
+// applyModelLimits defaults the output tokens to the model maximum and rejects parameters above the model limits
+func applyModelLimits(model *config.ModelConfig, params *ai.GenerationParams) error {
+	if model.MaxTokens > 0 {
+		if params.MaxTokens == nil {
+			maxTokens := model.MaxTokens
+			params.MaxTokens = &maxTokens
+		} else if *params.MaxTokens > model.MaxTokens {
+			return &Error{
+				Message: fmt.Sprintf("max_tokens %d exceeds the limit %d of model %s", *params.MaxTokens, model.MaxTokens, model.ModelName),
+			}
+		}
+	}
+	if model.MaxCandidateCount > 0 && params.CandidateCount != nil && *params.CandidateCount > model.MaxCandidateCount {
+		return &Error{
+			Message: fmt.Sprintf(
+				"candidate_count %d exceeds the limit %d of model %s",
+				*params.CandidateCount,
+				model.MaxCandidateCount,
+				model.ModelName,
+			),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quadev-ltd/qd-common/pkg/log"
+	loggerMock "github.com/quadev-ltd/qd-common/pkg/log/mock"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+)
+
+var testModels = map[string]config.ModelConfig{
+	"fast":     {ModelName: "gemini-flash", MaxTokens: 1024, MaxCandidateCount: 1},
+	"accurate": {ModelName: "gemini-pro"},
+}
+
+func TestResolveModel(t *testing.T) {
+	model, err := resolveModel(testModels, "fast")
+	assert.NoError(t, err)
+	assert.Equal(t, "gemini-flash", model.ModelName)
+
+	model, err = resolveModel(testModels, "gemini-pro")
+	assert.NoError(t, err)
+	assert.Equal(t, "gemini-pro", model.ModelName)
+
+	model, err = resolveModel(testModels, "gpt-4")
+	var serviceError *Error
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, `unknown model "gpt-4", available models are [accurate fast]`, serviceError.Message)
+	assert.Nil(t, model)
+}
+
+func TestResolveModel_SharedModelName(t *testing.T) {
+	models := map[string]config.ModelConfig{
+		"fast":  {ModelName: "gemini-flash", MaxTokens: 1024},
+		"cheap": {ModelName: "gemini-flash", MaxTokens: 512},
+	}
+
+	for range 20 {
+		model, err := resolveModel(models, "gemini-flash")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(512), model.MaxTokens)
+	}
+	model, err := resolveModel(models, "fast")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1024), model.MaxTokens)
+}
+
+func TestApplyModelLimits(t *testing.T) {
+	fast := testModels["fast"]
+
+	params := ai.GenerationParams{}
+	assert.NoError(t, applyModelLimits(&fast, &params))
+	assert.Equal(t, int32(1024), *params.MaxTokens)
+
+	maxTokens := int32(2048)
+	params = ai.GenerationParams{MaxTokens: &maxTokens}
+	assert.EqualError(t, applyModelLimits(&fast, &params), "max_tokens 2048 exceeds the limit 1024 of model gemini-flash")
+
+	candidateCount := int32(2)
+	params = ai.GenerationParams{CandidateCount: &candidateCount}
+	assert.EqualError(t, applyModelLimits(&fast, &params), "candidate_count 2 exceeds the limit 1 of model gemini-flash")
+}
+
+func TestProcessImageAndPrompt_ModelAlias(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, &config.Config{Models: testModels})
+
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	expectedResponse := &ai.Result{Candidates: []ai.Candidate{{Text: "A cat"}}, Model: "gemini-flash"}
+
+	mockLogger.EXPECT().Info(gomock.Any()).Times(1)
+	mockAnalyzer.EXPECT().
+		Analyze(ctx, gomock.Any(), "test prompt", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, options *ai.Options) (*ai.Result, error) {
+			assert.Equal(t, "gemini-flash", options.Model)
+			assert.Equal(t, int32(1024), *options.Generation.MaxTokens)
+			return expectedResponse, nil
+		})
+
+	response, err := service.ProcessImageAndPrompt(ctx, testImages, "test prompt", &AnalysisOptions{
+		Model: "fast",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResponse, response)
+}
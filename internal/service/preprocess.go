@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+// preprocessImage applies the configured preprocessing steps to a validated image.
+// The image is only decoded when it has to be rotated, downscaled or re-encoded,
+// its metadata is otherwise stripped without altering its pixels.
+// Documents are returned as they are and images of the formats that cannot be decoded are only stripped.
+func preprocessImage(index int, image ai.Image, preprocessing *config.PreprocessingConfig) (ai.Image, error) {
+	format, ok := imageformat.Detect(image.Data)
+	if !ok {
+		return image, nil
+	}
+	if !format.Decodable() {
+		if !preprocessing.StripMetadata {
+			return image, nil
+		}
+		return stripMetadata(index, image)
+	}
+	info, err := imageformat.Inspect(image.Data)
+	if err != nil {
+		return ai.Image{}, newPreprocessingError(index, err)
+	}
+	orientation := imageformat.OrientationNormal
+	if preprocessing.ApplyOrientation && format == imageformat.JPEG {
+		orientation = imageformat.ReadOrientation(image.Data)
+	}
+	downscale := preprocessing.MaxLongEdge > 0 && max(info.Width, info.Height) > preprocessing.MaxLongEdge
+
+	if orientation == imageformat.OrientationNormal && !downscale && !preprocessing.Reencode {
+		if !preprocessing.StripMetadata {
+			return image, nil
+		}
+		return stripMetadata(index, image)
+	}
+
+	decoded, err := imageformat.Decode(image.Data)
+	if err != nil {
+		return ai.Image{}, newPreprocessingError(index, err)
+	}
+	// downscaling first leaves fewer pixels to orient
+	decoded = imageformat.Downscale(decoded, preprocessing.MaxLongEdge)
+	decoded = imageformat.Orient(decoded, orientation)
+	target := imageformat.PNG
+	if format == imageformat.JPEG {
+		target = imageformat.JPEG
+	}
+	data, err := imageformat.Encode(decoded, target, preprocessing.JPEGQuality)
+	if err != nil {
+		return ai.Image{}, err
+	}
+	return ai.Image{Data: data, MimeType: target.MimeType}, nil
+}
+
+func stripMetadata(index int, image ai.Image) (ai.Image, error) {
+	data, err := imageformat.StripMetadata(image.Data)
+	if err != nil {
+		return ai.Image{}, newPreprocessingError(index, err)
+	}
+	return ai.Image{Data: data, MimeType: image.MimeType}, nil
+}
+
+func newPreprocessingError(index int, err error) error {
+	return &Error{
+		Message: fmt.Sprintf("image %d is invalid: %v", index, err),
+		Reason:  ErrorReasonCorruptImage,
+	}
+}
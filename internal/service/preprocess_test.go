@@ -0,0 +1,169 @@
+package service
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden images of the preprocessing tests")
+
+func readPreprocessTestImage(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "preprocess", name))
+	assert.NoError(t, err)
+	return data
+}
+
+func TestPreprocessImage_Golden(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		preprocessing config.PreprocessingConfig
+		golden        string
+		expectedInfo  imageformat.Info
+	}{
+		{
+			name:          "ApplyOrientation",
+			input:         "photo_rotated.jpg",
+			preprocessing: config.PreprocessingConfig{ApplyOrientation: true},
+			golden:        "photo_oriented.golden.jpg",
+			expectedInfo:  imageformat.Info{Format: imageformat.JPEG, Width: 48, Height: 96},
+		},
+		{
+			name:          "Downscale",
+			input:         "diagram.png",
+			preprocessing: config.PreprocessingConfig{MaxLongEdge: 60},
+			golden:        "diagram_downscaled.golden.png",
+			expectedInfo:  imageformat.Info{Format: imageformat.PNG, Width: 60, Height: 20},
+		},
+		{
+			name:          "DownscaleAndApplyOrientation",
+			input:         "photo_rotated.jpg",
+			preprocessing: config.PreprocessingConfig{ApplyOrientation: true, MaxLongEdge: 48, JPEGQuality: 75},
+			golden:        "photo_downscaled_oriented.golden.jpg",
+			expectedInfo:  imageformat.Info{Format: imageformat.JPEG, Width: 24, Height: 48},
+		},
+		{
+			name:          "Reencode",
+			input:         "photo_rotated.jpg",
+			preprocessing: config.PreprocessingConfig{Reencode: true, JPEGQuality: 50},
+			golden:        "photo_reencoded.golden.jpg",
+			expectedInfo:  imageformat.Info{Format: imageformat.JPEG, Width: 96, Height: 48},
+		},
+		{
+			name:          "StripMetadata",
+			input:         "photo_rotated.jpg",
+			preprocessing: config.PreprocessingConfig{StripMetadata: true},
+			golden:        "photo_stripped.golden.jpg",
+			expectedInfo:  imageformat.Info{Format: imageformat.JPEG, Width: 96, Height: 48},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			input := readPreprocessTestImage(t, testCase.input)
+			format, _ := imageformat.Detect(input)
+
+			preprocessed, err := preprocessImage(0, ai.Image{Data: input, MimeType: format.MimeType}, &testCase.preprocessing)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedInfo.Format.MimeType, preprocessed.MimeType)
+			info, err := imageformat.Inspect(preprocessed.Data)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedInfo, *info)
+			assert.NotContains(t, string(preprocessed.Data), "Exif")
+
+			goldenPath := filepath.Join("testdata", "preprocess", testCase.golden)
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(goldenPath, preprocessed.Data, 0o644))
+			}
+			assert.Equal(t, readPreprocessTestImage(t, testCase.golden), preprocessed.Data)
+		})
+	}
+}
+
+func TestPreprocessImage_StripMetadataGolden(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		golden       string
+		expectedInfo imageformat.Info
+	}{
+		{
+			name:         "WebP",
+			input:        "photo_gps.webp",
+			golden:       "photo_gps_stripped.golden.webp",
+			expectedInfo: imageformat.Info{Format: imageformat.WebP, Width: 150, Height: 100},
+		},
+		{
+			name:         "HEIC",
+			input:        "photo_gps.heic",
+			golden:       "photo_gps_stripped.golden.heic",
+			expectedInfo: imageformat.Info{Format: imageformat.HEIC, Width: 4032, Height: 3024},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			input := readPreprocessTestImage(t, testCase.input)
+			// the inputs hold the GPS location in their EXIF TIFF structure and in their XMP packet
+			assert.Contains(t, string(input), "MM\x00\x2a")
+			assert.Contains(t, string(input), "GPSLatitude")
+			image := ai.Image{Data: input, MimeType: testCase.expectedInfo.Format.MimeType}
+
+			preprocessed, err := preprocessImage(0, image, &config.PreprocessingConfig{StripMetadata: true})
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedInfo.Format.MimeType, preprocessed.MimeType)
+			info, err := imageformat.Inspect(preprocessed.Data)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedInfo, *info)
+			assert.NotContains(t, string(preprocessed.Data), "MM\x00\x2a")
+			assert.NotContains(t, string(preprocessed.Data), "GPSLatitude")
+
+			goldenPath := filepath.Join("testdata", "preprocess", testCase.golden)
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(goldenPath, preprocessed.Data, 0o644))
+			}
+			assert.Equal(t, readPreprocessTestImage(t, testCase.golden), preprocessed.Data)
+		})
+	}
+}
+
+func TestPreprocessImage_Disabled(t *testing.T) {
+	input := readPreprocessTestImage(t, "photo_rotated.jpg")
+	image := ai.Image{Data: input, MimeType: "image/jpeg"}
+
+	preprocessed, err := preprocessImage(0, image, &config.PreprocessingConfig{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, image, preprocessed)
+}
+
+func TestPreprocessImage_NotDecodable(t *testing.T) {
+	image := ai.Image{Data: testHEIC, MimeType: "image/heic"}
+
+	preprocessed, err := preprocessImage(0, image, &config.PreprocessingConfig{MaxLongEdge: 8, StripMetadata: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, image, preprocessed)
+}
+
+func TestValidateImages_Preprocessing(t *testing.T) {
+	input := readPreprocessTestImage(t, "diagram.png")
+	service := NewImageAnalysisService(nil, nil, nil, &config.Config{
+		Preprocessing: config.PreprocessingConfig{MaxLongEdge: 60},
+	})
+
+	images, size, err := service.validateImages([]ai.Image{{Data: input, MimeType: "image/png"}}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, readPreprocessTestImage(t, "diagram_downscaled.golden.png"), images[0].Data)
+	assert.Equal(t, int64(len(images[0].Data)), size)
+}
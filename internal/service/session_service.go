@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/quadev-ltd/qd-common/pkg/log"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/session"
+)
+
+// SessionServicer defines the interface for conversations about previously uploaded images
+type SessionServicer interface {
+	CreateSession(ctx context.Context, owner string, images []ai.Image) (*session.Session, error)
+	SendMessage(ctx context.Context, owner string, sessionID string, prompt string, options *AnalysisOptions) (*ai.Result, error)
+	GetSession(ctx context.Context, owner string, sessionID string) (*session.Session, error)
+	DeleteSession(ctx context.Context, owner string, sessionID string) error
+}
+
+// SessionService implements the SessionServicer interface on top of the image analysis service
+type SessionService struct {
+	imageAnalysisService *ImageAnalysisService
+	store                session.Storer
+	config               *config.SessionsConfig
+}
+
+var _ SessionServicer = &SessionService{}
+
+// NewSessionService creates a new instance of the session service.
+// The images and options of the messages are validated like those of the image analysis service.
+func NewSessionService(
+	imageAnalysisService *ImageAnalysisService,
+	store session.Storer,
+	sessionsConfig *config.SessionsConfig,
+) *SessionService {
+	if sessionsConfig == nil {
+		sessionsConfig = &config.SessionsConfig{}
+	}
+	return &SessionService{
+		imageAnalysisService: imageAnalysisService,
+		store:                store,
+		config:               sessionsConfig,
+	}
+}
+
+// CreateSession validates the images and stores them in a new session of the owner without analysing them
+func (sessionService *SessionService) CreateSession(ctx context.Context, owner string, images []ai.Image) (*session.Session, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images, imagesSize, err := sessionService.imageAnalysisService.validateImages(images, nil)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	newSession := &session.Session{
+		ID:     sessionID,
+		Owner:  owner,
+		Images: images,
+	}
+	if err := sessionService.store.Create(ctx, newSession); err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("Created a session with %d image(s) of %d bytes", len(images), imagesSize))
+	return newSession, nil
+}
+
+// SendMessage asks the analyzer about the images of the session, replaying the previous prompts and answers.
+// The prompt and the first answer are added to the history of the session.
+// JSON responses are validated against the requested schema but not retried.
+// Failures after the analyzer answered are returned as a UsageError.
+func (sessionService *SessionService) SendMessage(
+	ctx context.Context,
+	owner string,
+	sessionID string,
+	prompt string,
+	options *AnalysisOptions,
+) (*ai.Result, error) {
+	logger, err := log.GetLoggerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzerOptions, responseSchema, err := sessionService.imageAnalysisService.resolveOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	prompt, promptTemplate, err := sessionService.imageAnalysisService.resolvePrompt(prompt, options, analyzerOptions)
+	if err != nil {
+		return nil, err
+	}
+	if prompt == "" {
+		return nil, &Error{
+			Message: "no prompt provided",
+		}
+	}
+	if options.tiling() != nil {
+		return nil, &Error{
+			Message: "tiling is not supported in sessions",
+		}
+	}
+	existingSession, err := sessionService.getSession(ctx, owner, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	maxMessages := sessionService.config.MaxMessages
+	if maxMessages > 0 && len(existingSession.History)+2 > maxMessages {
+		return nil, &Error{
+			Message: fmt.Sprintf("session %s reached the limit of %d messages", sessionID, maxMessages),
+		}
+	}
+
+	logger.Info(fmt.Sprintf(
+		"Processing message %d of a session with prompt: %s",
+		len(existingSession.History)/2+1,
+		prompt,
+	))
+	result, err := sessionService.imageAnalysisService.analyzer.Chat(
+		ctx,
+		existingSession.Images,
+		existingSession.History,
+		prompt,
+		analyzerOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	logTemplate(logger, promptTemplate)
+	if responseSchema != nil {
+		if err := validateJSONResult(responseSchema, result); err != nil {
+			return nil, withUsage(fmt.Errorf("response does not satisfy schema %s: %v", responseSchema.Name, err), result)
+		}
+	}
+
+	err = sessionService.store.AppendMessages(
+		ctx,
+		sessionID,
+		ai.Message{Role: ai.RoleUser, Text: prompt},
+		ai.Message{Role: ai.RoleModel, Text: result.Text()},
+	)
+	if err != nil {
+		return nil, withUsage(err, result)
+	}
+	rendered, err := renderResult(result, options)
+	if err != nil {
+		return nil, withUsage(err, result)
+	}
+	return rendered, nil
+}
+
+// GetSession returns the session of the owner with its history
+func (sessionService *SessionService) GetSession(ctx context.Context, owner string, sessionID string) (*session.Session, error) {
+	return sessionService.getSession(ctx, owner, sessionID)
+}
+
+// DeleteSession removes the session of the owner and its images
+func (sessionService *SessionService) DeleteSession(ctx context.Context, owner string, sessionID string) error {
+	if _, err := sessionService.getSession(ctx, owner, sessionID); err != nil {
+		return err
+	}
+	return sessionService.store.Delete(ctx, sessionID)
+}
+
+// getSession returns the session when it belongs to the owner,
+// the sessions of other clients are not found so that their existence is not disclosed
+func (sessionService *SessionService) getSession(ctx context.Context, owner string, sessionID string) (*session.Session, error) {
+	existingSession, err := sessionService.store.Get(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if existingSession.Owner != owner {
+		return nil, session.ErrNotFound
+	}
+	return existingSession, nil
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %v", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quadev-ltd/qd-common/pkg/log"
+	loggerMock "github.com/quadev-ltd/qd-common/pkg/log/mock"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/session"
+)
+
+// testOwner is the owner of the sessions created by the tests
+const testOwner = "address:10.0.0.1"
+
+func newTestSessionService(
+	controller *gomock.Controller,
+	sessionsConfig *config.SessionsConfig,
+) (*SessionService, *mock.MockAnalyzer, *loggerMock.MockLoggerer, context.Context) {
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	mockLogger := loggerMock.NewMockLoggerer(controller)
+	sessionService := NewSessionService(
+		NewImageAnalysisService(mockAnalyzer, nil, nil, nil),
+		session.NewMemoryStore(time.Minute, session.Limits{}),
+		sessionsConfig,
+	)
+	ctx := context.WithValue(context.Background(), log.LoggerKey, mockLogger)
+	return sessionService, mockAnalyzer, mockLogger, ctx
+}
+
+func TestSessionService_CreateSession(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, _, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any())
+
+		createdSession, err := sessionService.CreateSession(ctx, testOwner, testImages)
+
+		assert.NoError(t, err)
+		assert.Len(t, createdSession.ID, 32)
+		storedSession, err := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, testImages, storedSession.Images)
+		assert.Empty(t, storedSession.History)
+	})
+
+	t.Run("Error_NoImage", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, _, _, ctx := newTestSessionService(controller, nil)
+
+		createdSession, err := sessionService.CreateSession(ctx, testOwner, nil)
+
+		assert.Nil(t, createdSession)
+		assert.EqualError(t, err, "no image provided")
+	})
+}
+
+func TestSessionService_SendMessage(t *testing.T) {
+	t.Run("Success_ReplaysHistory", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, mockAnalyzer, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any()).Times(3)
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+		firstResult := &ai.Result{Candidates: []ai.Candidate{{Text: "A cat."}}}
+		secondResult := &ai.Result{Candidates: []ai.Candidate{{Text: "Black."}}}
+		firstExchange := []ai.Message{
+			{Role: ai.RoleUser, Text: "What is this?"},
+			{Role: ai.RoleModel, Text: "A cat."},
+		}
+		gomock.InOrder(
+			mockAnalyzer.EXPECT().
+				Chat(ctx, testImages, nil, "What is this?", nil).
+				Return(firstResult, nil),
+			mockAnalyzer.EXPECT().
+				Chat(ctx, testImages, firstExchange, "What colour is it?", nil).
+				Return(secondResult, nil),
+		)
+
+		result, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "What is this?", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, firstResult, result)
+		result, err = sessionService.SendMessage(ctx, testOwner, createdSession.ID, "What colour is it?", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, secondResult, result)
+
+		storedSession, _ := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+		assert.Equal(t, append(firstExchange,
+			ai.Message{Role: ai.RoleUser, Text: "What colour is it?"},
+			ai.Message{Role: ai.RoleModel, Text: "Black."},
+		), storedSession.History)
+	})
+
+	t.Run("Success_KeepsMarkdownInHistory", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, mockAnalyzer, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any()).Times(2)
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+		mockAnalyzer.EXPECT().
+			Chat(ctx, testImages, nil, "What is this?", gomock.Any()).
+			Return(&ai.Result{Candidates: []ai.Candidate{{Text: "**A cat.**"}}}, nil)
+
+		result, err := sessionService.SendMessage(
+			ctx,
+			testOwner,
+			createdSession.ID,
+			"What is this?",
+			&AnalysisOptions{ConvertTo: ai.OutputFormatPlainText},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A cat.", result.Text())
+		storedSession, _ := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+		assert.Equal(t, "**A cat.**", storedSession.History[1].Text)
+	})
+
+	t.Run("Error_NotFound", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, _, _, ctx := newTestSessionService(controller, nil)
+
+		result, err := sessionService.SendMessage(ctx, testOwner, "unknown", "What is this?", nil)
+
+		assert.Nil(t, result)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("Error_NoPrompt", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, _, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any())
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+
+		result, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "", nil)
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "no prompt provided")
+	})
+
+	t.Run("Error_MaxMessages", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, mockAnalyzer, mockLogger, ctx := newTestSessionService(
+			controller,
+			&config.SessionsConfig{MaxMessages: 2},
+		)
+		mockLogger.EXPECT().Info(gomock.Any()).Times(2)
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+		mockAnalyzer.EXPECT().
+			Chat(ctx, testImages, nil, "What is this?", nil).
+			Return(&ai.Result{Candidates: []ai.Candidate{{Text: "A cat."}}}, nil)
+		_, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "What is this?", nil)
+		assert.NoError(t, err)
+
+		result, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "What colour is it?", nil)
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "session "+createdSession.ID+" reached the limit of 2 messages")
+	})
+
+	t.Run("Error_Schema_ReturnsUsage", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, mockAnalyzer, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any()).Times(2)
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+		mockAnalyzer.EXPECT().
+			Chat(ctx, testImages, nil, "Read the invoice", gomock.Any()).
+			Return(newJSONResult("not json", 100), nil)
+
+		result, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "Read the invoice", &AnalysisOptions{
+			JSONSchema: testInvoiceSchema,
+		})
+
+		assert.Nil(t, result)
+		var usageErr *UsageError
+		assert.ErrorAs(t, err, &usageErr)
+		assert.Equal(t, int32(110), usageErr.Usage.TotalTokens)
+		storedSession, _ := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+		assert.Empty(t, storedSession.History)
+	})
+
+	t.Run("Error_Analyzer_HistoryUnchanged", func(t *testing.T) {
+		controller := gomock.NewController(t)
+		defer controller.Finish()
+		sessionService, mockAnalyzer, mockLogger, ctx := newTestSessionService(controller, nil)
+		mockLogger.EXPECT().Info(gomock.Any()).Times(2)
+		createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+		mockAnalyzer.EXPECT().
+			Chat(ctx, testImages, nil, "What is this?", nil).
+			Return(nil, errors.New("analyzer error"))
+
+		result, err := sessionService.SendMessage(ctx, testOwner, createdSession.ID, "What is this?", nil)
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "analyzer error")
+		storedSession, _ := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+		assert.Empty(t, storedSession.History)
+	})
+}
+
+func TestSessionService_DeleteSession(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	sessionService, _, mockLogger, ctx := newTestSessionService(controller, nil)
+	mockLogger.EXPECT().Info(gomock.Any())
+	createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+
+	assert.NoError(t, sessionService.DeleteSession(ctx, testOwner, createdSession.ID))
+
+	_, err := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+	assert.Equal(t, session.ErrNotFound, err)
+	assert.Equal(t, session.ErrNotFound, sessionService.DeleteSession(ctx, testOwner, createdSession.ID))
+}
+
+func TestSessionService_OtherOwner(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	sessionService, _, mockLogger, ctx := newTestSessionService(controller, nil)
+	mockLogger.EXPECT().Info(gomock.Any())
+	createdSession, _ := sessionService.CreateSession(ctx, testOwner, testImages)
+	otherOwner := "address:10.0.0.2"
+
+	_, err := sessionService.GetSession(ctx, otherOwner, createdSession.ID)
+	assert.Equal(t, session.ErrNotFound, err)
+	_, err = sessionService.SendMessage(ctx, otherOwner, createdSession.ID, "What is this?", nil)
+	assert.Equal(t, session.ErrNotFound, err)
+	assert.Equal(t, session.ErrNotFound, sessionService.DeleteSession(ctx, otherOwner, createdSession.ID))
+
+	storedSession, err := sessionService.GetSession(ctx, testOwner, createdSession.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, testOwner, storedSession.Owner)
+}
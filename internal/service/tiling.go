@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+const (
+	defaultTileSize      = 768
+	defaultMaxTiles      = 16
+	defaultThumbnailSize = 768
+	overviewLabel        = "overview"
+)
+
+// TilingOptions requests the tiling mode, in which the image is analysed as overlapping tiles and a thumbnail
+// of the whole image whose answers are merged by a final call
+type TilingOptions struct {
+	// Rows and Columns of the tile grid, both are computed from the configured tile size when zero
+	Rows    int
+	Columns int
+}
+
+// tile is one of the parts of an image analysed separately
+type tile struct {
+	answer ai.TileAnswer
+	image  ai.Image
+	prompt string
+}
+
+// analyzeTiles analyses the tiles and the thumbnail of the image concurrently,
+// then asks the analyzer to merge their answers citing the tiles each finding comes from.
+// The usage of the tiles analysed before a failure is returned with it.
+func (imageAnalysisService *ImageAnalysisService) analyzeTiles(
+	ctx context.Context,
+	images []ai.Image,
+	prompt string,
+	tiling *TilingOptions,
+	analyzerOptions *ai.Options,
+) (*ai.Result, error) {
+	if len(images) != 1 {
+		return nil, &Error{Message: fmt.Sprintf("tiling requires exactly one image, not %d", len(images))}
+	}
+	tiles, err := splitTiles(images[0], prompt, tiling, &imageAnalysisService.config.Tiling, &imageAnalysisService.config.Preprocessing)
+	if err != nil {
+		return nil, err
+	}
+
+	tileOptions := newTileAnalyzerOptions(analyzerOptions)
+	results := make([]*ai.Result, len(tiles))
+	group, groupCtx := errgroup.WithContext(ctx)
+	if maxParallelism := imageAnalysisService.config.Tiling.MaxParallelism; maxParallelism > 0 {
+		group.SetLimit(maxParallelism)
+	}
+	for index, tile := range tiles {
+		group.Go(func() error {
+			result, err := imageAnalysisService.analyzer.Analyze(groupCtx, []ai.Image{tile.image}, tile.prompt, tileOptions)
+			if err != nil {
+				return err
+			}
+			results[index] = result
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, withUsage(err, results...)
+	}
+
+	answers := make([]ai.TileAnswer, 0, len(tiles))
+	for index, tile := range tiles {
+		answer := tile.answer
+		answer.Text = results[index].Text()
+		answers = append(answers, answer)
+	}
+	merged, err := imageAnalysisService.analyzer.Analyze(ctx, nil, newMergePrompt(prompt, answers), analyzerOptions)
+	if err != nil {
+		return nil, withUsage(err, results...)
+	}
+	for _, result := range results {
+		addUsage(merged, result)
+	}
+	merged.Tiles = answers
+	return merged, nil
+}
+
+// splitTiles decodes the image and splits it into the thumbnail of the whole image followed by the tiles of the grid
+func splitTiles(
+	source ai.Image,
+	prompt string,
+	tiling *TilingOptions,
+	limits *config.TilingConfig,
+	preprocessing *config.PreprocessingConfig,
+) ([]tile, error) {
+	format, ok := imageformat.Detect(source.Data)
+	if !ok || !format.Decodable() {
+		return nil, &Error{
+			Message: fmt.Sprintf("images of type %q cannot be split into tiles", source.MimeType),
+			Reason:  ErrorReasonUnsupportedImage,
+		}
+	}
+	decoded, err := imageformat.Decode(source.Data)
+	if err != nil {
+		return nil, newPreprocessingError(0, err)
+	}
+	bounds := decoded.Bounds()
+	rows, columns, err := tileGrid(bounds.Size(), tiling, limits)
+	if err != nil {
+		return nil, err
+	}
+	target := imageformat.PNG
+	if format == imageformat.JPEG {
+		target = imageformat.JPEG
+	}
+	encode := func(img image.Image) (ai.Image, error) {
+		data, err := imageformat.Encode(img, target, preprocessing.JPEGQuality)
+		if err != nil {
+			return ai.Image{}, err
+		}
+		return ai.Image{Data: data, MimeType: target.MimeType}, nil
+	}
+
+	thumbnailSize := limits.ThumbnailSize
+	if thumbnailSize <= 0 {
+		thumbnailSize = defaultThumbnailSize
+	}
+	thumbnail, err := encode(imageformat.Downscale(decoded, thumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]tile, 0, rows*columns+1)
+	tiles = append(tiles, tile{
+		answer: ai.TileAnswer{Label: overviewLabel, Bounds: bounds},
+		image:  thumbnail,
+		prompt: fmt.Sprintf("This image is a downscaled overview of a larger image.\n\n%s", prompt),
+	})
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			tileBounds := image.Rect(
+				bounds.Min.X+column*bounds.Dx()/columns-limits.OverlapPixels,
+				bounds.Min.Y+row*bounds.Dy()/rows-limits.OverlapPixels,
+				bounds.Min.X+(column+1)*bounds.Dx()/columns+limits.OverlapPixels,
+				bounds.Min.Y+(row+1)*bounds.Dy()/rows+limits.OverlapPixels,
+			).Intersect(bounds)
+			tileImage, err := encode(imageformat.Crop(decoded, tileBounds))
+			if err != nil {
+				return nil, err
+			}
+			tiles = append(tiles, tile{
+				answer: ai.TileAnswer{
+					Label:  fmt.Sprintf("R%dC%d", row+1, column+1),
+					Row:    row + 1,
+					Column: column + 1,
+					Bounds: tileBounds,
+				},
+				image: tileImage,
+				prompt: fmt.Sprintf(
+					"This image is the tile at row %d and column %d of a larger image split into %d rows and %d columns. "+
+						"Answer only about what is visible in this tile, reply \"nothing relevant\" when it has nothing relevant.\n\n%s",
+					row+1,
+					column+1,
+					rows,
+					columns,
+					prompt,
+				),
+			})
+		}
+	}
+	return tiles, nil
+}
+
+// tileGrid returns the rows and columns requested or, when there are none, those of tiles of the configured size
+func tileGrid(size image.Point, tiling *TilingOptions, limits *config.TilingConfig) (int, int, error) {
+	rows, columns := tiling.Rows, tiling.Columns
+	if rows < 0 || columns < 0 {
+		return 0, 0, &Error{Message: fmt.Sprintf("invalid tile grid of %d rows and %d columns", rows, columns)}
+	}
+	tileSize := limits.TileSize
+	if tileSize <= 0 {
+		tileSize = defaultTileSize
+	}
+	if rows == 0 {
+		rows = (size.Y + tileSize - 1) / tileSize
+	}
+	if columns == 0 {
+		columns = (size.X + tileSize - 1) / tileSize
+	}
+	rows, columns = min(rows, size.Y), min(columns, size.X)
+	maxTiles := limits.MaxTiles
+	if maxTiles <= 0 {
+		maxTiles = defaultMaxTiles
+	}
+	if rows*columns > maxTiles {
+		return 0, 0, &Error{
+			Message: fmt.Sprintf("%d rows and %d columns of tiles exceed the limit of %d tiles", rows, columns, maxTiles),
+		}
+	}
+	return rows, columns, nil
+}
+
+// newTileAnalyzerOptions asks for a single plain text answer per tile, the merge call producing the requested format
+func newTileAnalyzerOptions(analyzerOptions *ai.Options) *ai.Options {
+	tileOptions := &ai.Options{}
+	if analyzerOptions != nil {
+		*tileOptions = *analyzerOptions
+	}
+	candidateCount := int32(1)
+	tileOptions.OutputFormat = ai.OutputFormatPlainText
+	tileOptions.JSONSchema = nil
+	tileOptions.Generation.CandidateCount = &candidateCount
+	return tileOptions
+}
+
+func newMergePrompt(prompt string, answers []ai.TileAnswer) string {
+	var builder strings.Builder
+	builder.WriteString("An image was analysed as a downscaled overview and as overlapping tiles to answer the question below.\n\n")
+	fmt.Fprintf(&builder, "Question: %s\n\n", prompt)
+	for _, answer := range answers {
+		fmt.Fprintf(&builder, "[%s] %s\n\n", answer.Label, answer.Text)
+	}
+	builder.WriteString(
+		"Merge these answers into a single answer to the question without repeating findings seen in several tiles. " +
+			"Follow every finding with the labels of the tiles it comes from in square brackets, such as [R1C2].",
+	)
+	return builder.String()
+}
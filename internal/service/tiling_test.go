@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+	"qd-image-analysis-api/internal/ai/mock"
+	"qd-image-analysis-api/internal/config"
+	"qd-image-analysis-api/internal/imageformat"
+)
+
+func newTilingTestImage(t *testing.T, width, height int) ai.Image {
+	t.Helper()
+	data, err := imageformat.Encode(image.NewNRGBA(image.Rect(0, 0, width, height)), imageformat.PNG, 0)
+	assert.NoError(t, err)
+	return ai.Image{Data: data, MimeType: imageformat.PNG.MimeType}
+}
+
+func TestTileGrid(t *testing.T) {
+	testCases := []struct {
+		name            string
+		size            image.Point
+		tiling          TilingOptions
+		limits          config.TilingConfig
+		expectedRows    int
+		expectedColumns int
+		expectedError   string
+	}{
+		{
+			name:            "Success_ComputedFromTileSize",
+			size:            image.Pt(2000, 1000),
+			limits:          config.TilingConfig{TileSize: 768},
+			expectedRows:    2,
+			expectedColumns: 3,
+		},
+		{
+			name:            "Success_DefaultTileSize",
+			size:            image.Pt(768, 769),
+			expectedRows:    2,
+			expectedColumns: 1,
+		},
+		{
+			name:            "Success_Requested",
+			size:            image.Pt(2000, 1000),
+			tiling:          TilingOptions{Rows: 3, Columns: 1},
+			limits:          config.TilingConfig{TileSize: 768},
+			expectedRows:    3,
+			expectedColumns: 1,
+		},
+		{
+			name:            "Success_NoMorePixelsThanTiles",
+			size:            image.Pt(2, 1),
+			tiling:          TilingOptions{Rows: 4, Columns: 4},
+			expectedRows:    1,
+			expectedColumns: 2,
+		},
+		{
+			name:          "Error_TooManyTiles",
+			size:          image.Pt(2000, 1000),
+			tiling:        TilingOptions{Rows: 4, Columns: 5},
+			limits:        config.TilingConfig{MaxTiles: 16},
+			expectedError: "4 rows and 5 columns of tiles exceed the limit of 16 tiles",
+		},
+		{
+			name:          "Error_TooManyTilesByDefault",
+			size:          image.Pt(8192, 8192),
+			tiling:        TilingOptions{Rows: 8192, Columns: 8192},
+			expectedError: "8192 rows and 8192 columns of tiles exceed the limit of 16 tiles",
+		},
+		{
+			name:          "Error_Negative",
+			size:          image.Pt(2000, 1000),
+			tiling:        TilingOptions{Rows: -1},
+			expectedError: "invalid tile grid of -1 rows and 0 columns",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rows, columns, err := tileGrid(testCase.size, &testCase.tiling, &testCase.limits)
+
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedRows, rows)
+			assert.Equal(t, testCase.expectedColumns, columns)
+		})
+	}
+}
+
+func TestSplitTiles(t *testing.T) {
+	limits := &config.TilingConfig{TileSize: 100, OverlapPixels: 10, ThumbnailSize: 50}
+
+	tiles, err := splitTiles(newTilingTestImage(t, 200, 100), "prompt", &TilingOptions{}, limits, &config.PreprocessingConfig{})
+
+	assert.NoError(t, err)
+	assert.Len(t, tiles, 3)
+	assert.Equal(t, ai.TileAnswer{Label: "overview", Bounds: image.Rect(0, 0, 200, 100)}, tiles[0].answer)
+	assert.Equal(t, ai.TileAnswer{Label: "R1C1", Row: 1, Column: 1, Bounds: image.Rect(0, 0, 110, 100)}, tiles[1].answer)
+	assert.Equal(t, ai.TileAnswer{Label: "R1C2", Row: 1, Column: 2, Bounds: image.Rect(90, 0, 200, 100)}, tiles[2].answer)
+	expectedSizes := []image.Point{image.Pt(50, 25), image.Pt(110, 100), image.Pt(110, 100)}
+	for index, tile := range tiles {
+		assert.Equal(t, imageformat.PNG.MimeType, tile.image.MimeType)
+		info, err := imageformat.Inspect(tile.image.Data)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedSizes[index], image.Pt(info.Width, info.Height))
+		assert.Contains(t, tile.prompt, "prompt")
+	}
+	assert.Contains(t, tiles[2].prompt, "row 1 and column 2")
+}
+
+func TestSplitTiles_NotDecodable(t *testing.T) {
+	_, err := splitTiles(
+		ai.Image{Data: testHEIC, MimeType: imageformat.HEIC.MimeType},
+		"prompt",
+		&TilingOptions{},
+		&config.TilingConfig{},
+		&config.PreprocessingConfig{},
+	)
+
+	var serviceError *Error
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, ErrorReasonUnsupportedImage, serviceError.Reason)
+}
+
+func TestAnalyzeTiles(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, &config.Config{
+		Tiling: config.TilingConfig{TileSize: 100, OverlapPixels: 10, MaxParallelism: 2},
+	})
+	options := &ai.Options{OutputFormat: ai.OutputFormatMarkdown}
+
+	mockAnalyzer.EXPECT().
+		Analyze(gomock.Any(), gomock.Len(1), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, _ string, tileOptions *ai.Options) (*ai.Result, error) {
+			assert.Equal(t, ai.OutputFormatPlainText, tileOptions.OutputFormat)
+			return &ai.Result{
+				Candidates:    []ai.Candidate{{Text: "a cat"}},
+				Usage:         ai.Usage{PromptTokens: 10, CandidatesTokens: 2, TotalTokens: 12},
+				EstimatedCost: 0.5,
+			}, nil
+		}).
+		Times(3)
+	mockAnalyzer.EXPECT().
+		Analyze(gomock.Any(), gomock.Nil(), gomock.Any(), options).
+		DoAndReturn(func(_ context.Context, _ []ai.Image, prompt string, _ *ai.Options) (*ai.Result, error) {
+			assert.Contains(t, prompt, "Question: what is this?")
+			assert.Contains(t, prompt, "[overview] a cat")
+			assert.Contains(t, prompt, "[R1C2] a cat")
+			return &ai.Result{
+				Candidates:    []ai.Candidate{{Text: "a cat [overview] [R1C1]"}},
+				Usage:         ai.Usage{PromptTokens: 20, CandidatesTokens: 5, TotalTokens: 25},
+				EstimatedCost: 1,
+			}, nil
+		})
+
+	result, err := service.analyzeTiles(
+		context.Background(),
+		[]ai.Image{newTilingTestImage(t, 200, 100)},
+		"what is this?",
+		&TilingOptions{},
+		options,
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a cat [overview] [R1C1]", result.Text())
+	assert.Equal(t, ai.Usage{PromptTokens: 50, CandidatesTokens: 11, TotalTokens: 61}, result.Usage)
+	assert.Equal(t, 2.5, result.EstimatedCost)
+	assert.Len(t, result.Tiles, 3)
+	for _, tile := range result.Tiles {
+		assert.Equal(t, "a cat", tile.Text)
+	}
+	assert.Equal(t, "R1C2", result.Tiles[2].Label)
+}
+
+func TestAnalyzeTiles_MergeFails(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAnalyzer := mock.NewMockAnalyzer(controller)
+	service := NewImageAnalysisService(mockAnalyzer, nil, nil, &config.Config{
+		Tiling: config.TilingConfig{TileSize: 100},
+	})
+	mergeErr := errors.New("merge failed")
+
+	mockAnalyzer.EXPECT().
+		Analyze(gomock.Any(), gomock.Len(1), gomock.Any(), gomock.Any()).
+		Return(&ai.Result{
+			Candidates:    []ai.Candidate{{Text: "a cat"}},
+			Usage:         ai.Usage{PromptTokens: 10, CandidatesTokens: 2, TotalTokens: 12},
+			EstimatedCost: 0.5,
+		}, nil).
+		Times(3)
+	mockAnalyzer.EXPECT().
+		Analyze(gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Any()).
+		Return(nil, mergeErr)
+
+	result, err := service.analyzeTiles(
+		context.Background(),
+		[]ai.Image{newTilingTestImage(t, 200, 100)},
+		"what is this?",
+		&TilingOptions{},
+		nil,
+	)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, mergeErr)
+	var usageErr *UsageError
+	assert.ErrorAs(t, err, &usageErr)
+	assert.Equal(t, ai.Usage{PromptTokens: 30, CandidatesTokens: 6, TotalTokens: 36}, usageErr.Usage)
+	assert.Equal(t, 1.5, usageErr.EstimatedCost)
+}
+
+func TestAnalyzeTiles_SeveralImages(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	service := NewImageAnalysisService(mock.NewMockAnalyzer(controller), nil, nil, nil)
+	image := newTilingTestImage(t, 10, 10)
+
+	_, err := service.analyzeTiles(context.Background(), []ai.Image{image, image}, "prompt", &TilingOptions{}, nil)
+
+	assert.EqualError(t, err, "tiling requires exactly one image, not 2")
+}
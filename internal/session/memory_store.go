@@ -0,0 +1,145 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"qd-image-analysis-api/internal/ai"
+)
+
+// DefaultTTL is the time to live of the sessions when none is configured
+const DefaultTTL = 30 * time.Minute
+
+// MemoryStore is an in-process implementation of Storer whose sessions expire after a time to live.
+// Expired sessions are evicted whenever a session is created.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	sessions map[string]*Session
+	ttl      time.Duration
+	limits   Limits
+	now      func() time.Time
+}
+
+var _ Storer = &MemoryStore{}
+
+// NewMemoryStore creates a new instance of MemoryStore keeping at most the sessions the limits allow
+// for ttl since their last message, DefaultTTL is used when it is not positive.
+func NewMemoryStore(ttl time.Duration, limits Limits) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &MemoryStore{
+		sessions: map[string]*Session{},
+		ttl:      ttl,
+		limits:   limits,
+		now:      time.Now,
+	}
+}
+
+// Create stores a copy of the session once the expired sessions are evicted and the limits checked
+func (memoryStore *MemoryStore) Create(ctx context.Context, session *Session) error {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	now := memoryStore.now()
+	memoryStore.evictExpired(now)
+	if _, ok := memoryStore.sessions[session.ID]; ok {
+		return fmt.Errorf("session %s already exists", session.ID)
+	}
+	if err := memoryStore.checkLimits(session.Owner); err != nil {
+		return err
+	}
+	session.CreatedAt = now
+	session.ExpiresAt = now.Add(memoryStore.ttl)
+	memoryStore.sessions[session.ID] = copySession(session)
+	return nil
+}
+
+// Get returns a copy of the session
+func (memoryStore *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	session, err := memoryStore.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return copySession(session), nil
+}
+
+// AppendMessages adds the messages to the history of the session and restarts its time to live
+func (memoryStore *MemoryStore) AppendMessages(ctx context.Context, id string, messages ...ai.Message) error {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	session, err := memoryStore.get(id)
+	if err != nil {
+		return err
+	}
+	session.History = append(session.History, messages...)
+	session.ExpiresAt = memoryStore.now().Add(memoryStore.ttl)
+	return nil
+}
+
+// Delete removes the session
+func (memoryStore *MemoryStore) Delete(ctx context.Context, id string) error {
+	memoryStore.mutex.Lock()
+	defer memoryStore.mutex.Unlock()
+
+	if _, err := memoryStore.get(id); err != nil {
+		return err
+	}
+	delete(memoryStore.sessions, id)
+	return nil
+}
+
+// get returns the stored session, removing it if it has expired
+func (memoryStore *MemoryStore) get(id string) (*Session, error) {
+	session, ok := memoryStore.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !memoryStore.now().Before(session.ExpiresAt) {
+		delete(memoryStore.sessions, id)
+		return nil, ErrNotFound
+	}
+	return session, nil
+}
+
+// checkLimits returns an error when one more session would exceed the limits of the store or of the owner
+func (memoryStore *MemoryStore) checkLimits(owner string) error {
+	if maxSessions := memoryStore.limits.MaxSessions; maxSessions > 0 && len(memoryStore.sessions) >= maxSessions {
+		return ErrLimitReached
+	}
+	maxSessionsPerOwner := memoryStore.limits.MaxSessionsPerOwner
+	if maxSessionsPerOwner <= 0 {
+		return nil
+	}
+	ownerSessions := 0
+	for _, session := range memoryStore.sessions {
+		if session.Owner == owner {
+			ownerSessions++
+		}
+	}
+	if ownerSessions >= maxSessionsPerOwner {
+		return ErrOwnerLimitReached
+	}
+	return nil
+}
+
+func (memoryStore *MemoryStore) evictExpired(now time.Time) {
+	for id, session := range memoryStore.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(memoryStore.sessions, id)
+		}
+	}
+}
+
+func copySession(session *Session) *Session {
+	sessionCopy := *session
+	sessionCopy.Images = append([]ai.Image(nil), session.Images...)
+	sessionCopy.History = append([]ai.Message(nil), session.History...)
+	return &sessionCopy
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"qd-image-analysis-api/internal/ai"
+)
+
+func newTestStore(ttl time.Duration) (*MemoryStore, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(ttl, Limits{})
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	images := []ai.Image{{Data: []byte("image"), MimeType: "image/png"}}
+
+	t.Run("Create_Get_Success", func(t *testing.T) {
+		store, now := newTestStore(time.Minute)
+
+		err := store.Create(ctx, &Session{ID: "session-id", Images: images})
+		assert.NoError(t, err)
+
+		session, err := store.Get(ctx, "session-id")
+		assert.NoError(t, err)
+		assert.Equal(t, images, session.Images)
+		assert.Empty(t, session.History)
+		assert.Equal(t, *now, session.CreatedAt)
+		assert.Equal(t, now.Add(time.Minute), session.ExpiresAt)
+	})
+
+	t.Run("Create_Error_Duplicate", func(t *testing.T) {
+		store, _ := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+
+		err := store.Create(ctx, &Session{ID: "session-id"})
+
+		assert.EqualError(t, err, "session session-id already exists")
+	})
+
+	t.Run("Create_Error_Limits", func(t *testing.T) {
+		store, now := newTestStore(time.Minute)
+		store.limits = Limits{MaxSessions: 3, MaxSessionsPerOwner: 2}
+		assert.NoError(t, store.Create(ctx, &Session{ID: "first", Owner: "owner"}))
+		assert.NoError(t, store.Create(ctx, &Session{ID: "second", Owner: "owner"}))
+
+		assert.Equal(t, ErrOwnerLimitReached, store.Create(ctx, &Session{ID: "third", Owner: "owner"}))
+		assert.NoError(t, store.Create(ctx, &Session{ID: "third", Owner: "other"}))
+		assert.Equal(t, ErrLimitReached, store.Create(ctx, &Session{ID: "fourth", Owner: "another"}))
+
+		assert.NoError(t, store.Delete(ctx, "first"))
+		assert.NoError(t, store.Create(ctx, &Session{ID: "fourth", Owner: "owner"}))
+		// expired sessions no longer count
+		*now = now.Add(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "fifth", Owner: "owner"}))
+	})
+
+	t.Run("Get_Error_NotFound", func(t *testing.T) {
+		store, _ := newTestStore(time.Minute)
+
+		session, err := store.Get(ctx, "unknown")
+
+		assert.Nil(t, session)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("Get_Error_Expired", func(t *testing.T) {
+		store, now := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+		*now = now.Add(time.Minute)
+
+		_, err := store.Get(ctx, "session-id")
+
+		assert.Equal(t, ErrNotFound, err)
+		assert.Empty(t, store.sessions)
+	})
+
+	t.Run("AppendMessages_Success_ExtendsExpiry", func(t *testing.T) {
+		store, now := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+		*now = now.Add(30 * time.Second)
+
+		err := store.AppendMessages(
+			ctx,
+			"session-id",
+			ai.Message{Role: ai.RoleUser, Text: "What is this?"},
+			ai.Message{Role: ai.RoleModel, Text: "A cat."},
+		)
+		assert.NoError(t, err)
+		*now = now.Add(45 * time.Second)
+
+		session, err := store.Get(ctx, "session-id")
+		assert.NoError(t, err)
+		assert.Equal(t, []ai.Message{
+			{Role: ai.RoleUser, Text: "What is this?"},
+			{Role: ai.RoleModel, Text: "A cat."},
+		}, session.History)
+	})
+
+	t.Run("AppendMessages_Error_NotFound", func(t *testing.T) {
+		store, _ := newTestStore(time.Minute)
+
+		err := store.AppendMessages(ctx, "unknown", ai.Message{Role: ai.RoleUser, Text: "Hello"})
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("Get_ReturnsCopy", func(t *testing.T) {
+		store, _ := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+		session, _ := store.Get(ctx, "session-id")
+		session.History = append(session.History, ai.Message{Role: ai.RoleUser, Text: "Hello"})
+
+		storedSession, _ := store.Get(ctx, "session-id")
+
+		assert.Empty(t, storedSession.History)
+	})
+
+	t.Run("Delete_Success", func(t *testing.T) {
+		store, _ := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+
+		assert.NoError(t, store.Delete(ctx, "session-id"))
+
+		_, err := store.Get(ctx, "session-id")
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, ErrNotFound, store.Delete(ctx, "session-id"))
+	})
+
+	t.Run("Create_EvictsExpired", func(t *testing.T) {
+		store, now := newTestStore(time.Minute)
+		assert.NoError(t, store.Create(ctx, &Session{ID: "expired"}))
+		*now = now.Add(2 * time.Minute)
+
+		assert.NoError(t, store.Create(ctx, &Session{ID: "session-id"}))
+
+		assert.Len(t, store.sessions, 1)
+		assert.Contains(t, store.sessions, "session-id")
+	})
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"qd-image-analysis-api/internal/ai"
+)
+
+var (
+	// ErrNotFound is returned when a session does not exist or has expired
+	ErrNotFound = errors.New("session not found")
+	// ErrLimitReached is returned when a session is created while the store holds as many sessions as it may
+	ErrLimitReached = errors.New("too many sessions")
+	// ErrOwnerLimitReached is returned when a session is created by an owner who has as many sessions as it may
+	ErrOwnerLimitReached = errors.New("too many sessions for the client")
+)
+
+// Limits bounds the number of sessions kept at once, zero values leave them unbounded
+type Limits struct {
+	MaxSessions         int
+	MaxSessionsPerOwner int
+}
+
+// Session is a conversation about one or more images kept between requests
+type Session struct {
+	ID string
+	// Owner is the key of the identity of the client that created the session, no other client may use it
+	Owner  string
+	Images []ai.Image
+	// History holds the prompts and answers exchanged so far, oldest first
+	History   []ai.Message
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Storer defines the interface of the session stores
+type Storer interface {
+	// Create stores a new session and sets its creation and expiry times,
+	// it returns ErrLimitReached or ErrOwnerLimitReached when the store or the owner of the session has too many sessions
+	Create(ctx context.Context, session *Session) error
+	// Get returns a copy of the session or ErrNotFound
+	Get(ctx context.Context, id string) (*Session, error)
+	// AppendMessages adds messages to the history of the session and extends its expiry
+	AppendMessages(ctx context.Context, id string, messages ...ai.Message) error
+	// Delete removes the session or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,160 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template is a versioned prompt with Go text/template variables and an optional system instruction
+type Template struct {
+	ID                string `yaml:"id"`
+	Version           int    `yaml:"version"`
+	SystemInstruction string `yaml:"system_instruction"`
+	Prompt            string `yaml:"prompt"`
+	prompt            *template.Template
+	systemInstruction *template.Template
+}
+
+// Parse reads and compiles a YAML prompt template
+func Parse(content []byte) (*Template, error) {
+	var promptTemplate Template
+	if err := yaml.Unmarshal(content, &promptTemplate); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %v", err)
+	}
+	switch {
+	case promptTemplate.ID == "":
+		return nil, fmt.Errorf("prompt template id is required")
+	case promptTemplate.Version <= 0:
+		return nil, fmt.Errorf("prompt template %s version must be positive", promptTemplate.ID)
+	case promptTemplate.Prompt == "":
+		return nil, fmt.Errorf("prompt template %s version %d has no prompt", promptTemplate.ID, promptTemplate.Version)
+	}
+	compiled, err := compile(promptTemplate.ID, promptTemplate.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	promptTemplate.prompt = compiled
+	if promptTemplate.SystemInstruction != "" {
+		compiled, err := compile(promptTemplate.ID, promptTemplate.SystemInstruction)
+		if err != nil {
+			return nil, err
+		}
+		promptTemplate.systemInstruction = compiled
+	}
+	return &promptTemplate, nil
+}
+
+func compile(id, text string) (*template.Template, error) {
+	compiled, err := template.New(id).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile prompt template %s: %v", id, err)
+	}
+	return compiled, nil
+}
+
+// Render executes the prompt and the system instruction with the variables.
+// Referencing a variable that is not provided is an error.
+func (promptTemplate *Template) Render(variables map[string]string) (string, string, error) {
+	prompt, err := execute(promptTemplate.prompt, variables)
+	if err != nil {
+		return "", "", err
+	}
+	if promptTemplate.systemInstruction == nil {
+		return prompt, "", nil
+	}
+	systemInstruction, err := execute(promptTemplate.systemInstruction, variables)
+	if err != nil {
+		return "", "", err
+	}
+	return prompt, systemInstruction, nil
+}
+
+func execute(compiled *template.Template, variables map[string]string) (string, error) {
+	if variables == nil {
+		variables = map[string]string{}
+	}
+	var builder strings.Builder
+	if err := compiled.Execute(&builder, variables); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %v", err)
+	}
+	return builder.String(), nil
+}
+
+// Registry keeps every version of the server-side prompt templates
+type Registry struct {
+	templates map[string]map[int]*Template
+}
+
+// NewRegistry creates an empty prompt template registry
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]map[int]*Template)}
+}
+
+// LoadRegistry parses every *.yml and *.yaml file of the directory, each file holding one template version.
+// An empty directory path results in an empty registry.
+func LoadRegistry(directory string) (*Registry, error) {
+	registry := NewRegistry()
+	if directory == "" {
+		return registry, nil
+	}
+	var paths []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(directory, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prompt templates: %v", err)
+		}
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template %s: %v", path, err)
+		}
+		promptTemplate, err := Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if err := registry.Add(promptTemplate); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Add registers a template version, a version can only be registered once
+func (registry *Registry) Add(promptTemplate *Template) error {
+	versions, ok := registry.templates[promptTemplate.ID]
+	if !ok {
+		versions = make(map[int]*Template)
+		registry.templates[promptTemplate.ID] = versions
+	}
+	if _, ok := versions[promptTemplate.Version]; ok {
+		return fmt.Errorf("prompt template %s version %d is defined twice", promptTemplate.ID, promptTemplate.Version)
+	}
+	versions[promptTemplate.Version] = promptTemplate
+	return nil
+}
+
+// Get returns the requested version of a template, a zero version returns the latest one
+func (registry *Registry) Get(id string, version int) (*Template, bool) {
+	versions, ok := registry.templates[id]
+	if !ok {
+		return nil, false
+	}
+	if version != 0 {
+		promptTemplate, ok := versions[version]
+		return promptTemplate, ok
+	}
+	var latest *Template
+	for _, promptTemplate := range versions {
+		if latest == nil || promptTemplate.Version > latest.Version {
+			latest = promptTemplate
+		}
+	}
+	return latest, latest != nil
+}
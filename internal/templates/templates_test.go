@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	registry, err := LoadRegistry("testdata")
+	assert.NoError(t, err)
+
+	latest, ok := registry.Get("receipt", 0)
+	assert.True(t, ok)
+	assert.Equal(t, 2, latest.Version)
+
+	first, ok := registry.Get("receipt", 1)
+	assert.True(t, ok)
+	assert.Equal(t, 1, first.Version)
+
+	_, ok = registry.Get("receipt", 3)
+	assert.False(t, ok)
+	_, ok = registry.Get("invoice", 0)
+	assert.False(t, ok)
+}
+
+func TestLoadRegistry_ConfigExample(t *testing.T) {
+	registry, err := LoadRegistry("../config/templates")
+	assert.NoError(t, err)
+
+	_, ok := registry.Get("receipt", 1)
+	assert.True(t, ok)
+}
+
+func TestLoadRegistry_EmptyDirectory(t *testing.T) {
+	registry, err := LoadRegistry("")
+
+	assert.NoError(t, err)
+	_, ok := registry.Get("receipt", 0)
+	assert.False(t, ok)
+}
+
+func TestTemplate_Render(t *testing.T) {
+	registry, err := LoadRegistry("testdata")
+	assert.NoError(t, err)
+	promptTemplate, _ := registry.Get("receipt", 2)
+
+	prompt, systemInstruction, err := promptTemplate.Render(map[string]string{"language": "French", "currency": "EUR"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "List the items of this receipt and their total in EUR.", prompt)
+	assert.Equal(t, "You are a bookkeeper. Answer in French.", systemInstruction)
+}
+
+func TestTemplate_Render_MissingVariable(t *testing.T) {
+	registry, err := LoadRegistry("testdata")
+	assert.NoError(t, err)
+	promptTemplate, _ := registry.Get("receipt", 2)
+
+	_, _, err = promptTemplate.Render(map[string]string{"currency": "EUR"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "language")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"missing id":       "version: 1\nprompt: hello",
+		"missing version":  "id: greeting\nprompt: hello",
+		"missing prompt":   "id: greeting\nversion: 1",
+		"invalid template": "id: greeting\nversion: 1\nprompt: \"hello {{.name\"",
+	}
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRegistry_Add_Duplicate(t *testing.T) {
+	registry := NewRegistry()
+	promptTemplate, err := Parse([]byte("id: greeting\nversion: 1\nprompt: hello"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, registry.Add(promptTemplate))
+	assert.EqualError(t, registry.Add(promptTemplate), "prompt template greeting version 1 is defined twice")
+}
@@ -0,0 +1,2021 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: qd-protobuf-definitions/v1/image-analysis/image-analysis.proto
+
+package pb_image_analysis
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type OutputFormat int32
+
+const (
+	OutputFormat_OUTPUT_FORMAT_UNSPECIFIED OutputFormat = 0
+	OutputFormat_OUTPUT_FORMAT_MARKDOWN    OutputFormat = 1
+	OutputFormat_OUTPUT_FORMAT_PLAIN_TEXT  OutputFormat = 2
+	OutputFormat_OUTPUT_FORMAT_HTML        OutputFormat = 3
+	OutputFormat_OUTPUT_FORMAT_JSON        OutputFormat = 4
+)
+
+// Enum value maps for OutputFormat.
+var (
+	OutputFormat_name = map[int32]string{
+		0: "OUTPUT_FORMAT_UNSPECIFIED",
+		1: "OUTPUT_FORMAT_MARKDOWN",
+		2: "OUTPUT_FORMAT_PLAIN_TEXT",
+		3: "OUTPUT_FORMAT_HTML",
+		4: "OUTPUT_FORMAT_JSON",
+	}
+	OutputFormat_value = map[string]int32{
+		"OUTPUT_FORMAT_UNSPECIFIED": 0,
+		"OUTPUT_FORMAT_MARKDOWN":    1,
+		"OUTPUT_FORMAT_PLAIN_TEXT":  2,
+		"OUTPUT_FORMAT_HTML":        3,
+		"OUTPUT_FORMAT_JSON":        4,
+	}
+)
+
+func (x OutputFormat) Enum() *OutputFormat {
+	p := new(OutputFormat)
+	*p = x
+	return p
+}
+
+func (x OutputFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_enumTypes[0].Descriptor()
+}
+
+func (OutputFormat) Type() protoreflect.EnumType {
+	return &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_enumTypes[0]
+}
+
+func (x OutputFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OutputFormat.Descriptor instead.
+func (OutputFormat) EnumDescriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+type ImagePromptRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ImageData []byte                 `protobuf:"bytes,1,opt,name=imageData,proto3" json:"imageData,omitempty"`
+	MimeType  string                 `protobuf:"bytes,2,opt,name=mimeType,proto3" json:"mimeType,omitempty"`
+	Prompt    string                 `protobuf:"bytes,3,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Options   *AnalysisOptions       `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	// images are analysed together with imageData, which can be left empty when they are set
+	Images        []*Image `protobuf:"bytes,5,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImagePromptRequest) Reset() {
+	*x = ImagePromptRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImagePromptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImagePromptRequest) ProtoMessage() {}
+
+func (x *ImagePromptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImagePromptRequest.ProtoReflect.Descriptor instead.
+func (*ImagePromptRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ImagePromptRequest) GetImageData() []byte {
+	if x != nil {
+		return x.ImageData
+	}
+	return nil
+}
+
+func (x *ImagePromptRequest) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *ImagePromptRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *ImagePromptRequest) GetOptions() *AnalysisOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *ImagePromptRequest) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+// Image is an image or a PDF document when its mimeType is application/pdf
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	MimeType      string                 `protobuf:"bytes,2,opt,name=mimeType,proto3" json:"mimeType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Image) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Image) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+type AnalysisOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// jsonOutput requests a JSON response instead of markdown when set
+	JsonOutput *JSONOutputOptions `protobuf:"bytes,1,opt,name=jsonOutput,proto3" json:"jsonOutput,omitempty"`
+	// outputFormat is the format the model writes the response in, markdown when unspecified
+	OutputFormat OutputFormat `protobuf:"varint,2,opt,name=outputFormat,proto3,enum=src.pb.OutputFormat" json:"outputFormat,omitempty"`
+	// convertTo converts a markdown response into sanitized HTML or plain text
+	ConvertTo OutputFormat `protobuf:"varint,3,opt,name=convertTo,proto3,enum=src.pb.OutputFormat" json:"convertTo,omitempty"`
+	// generation overrides the server generation parameters within the server bounds
+	Generation *GenerationParameters `protobuf:"bytes,4,opt,name=generation,proto3" json:"generation,omitempty"`
+	// model is an alias or a model name of the server model catalog, the server default is used when empty
+	Model string `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`
+	// template renders the prompt from a server-side prompt template, the request prompt must then be empty
+	Template *PromptTemplate `protobuf:"bytes,6,opt,name=template,proto3" json:"template,omitempty"`
+	// pages selects the pages of the PDF documents to analyse, all of them when unset
+	Pages *PageRange `protobuf:"bytes,7,opt,name=pages,proto3" json:"pages,omitempty"`
+	// tiling analyses the image as overlapping tiles and a thumbnail whose answers are merged, when set
+	Tiling        *TilingOptions `protobuf:"bytes,8,opt,name=tiling,proto3" json:"tiling,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalysisOptions) Reset() {
+	*x = AnalysisOptions{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisOptions) ProtoMessage() {}
+
+func (x *AnalysisOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisOptions.ProtoReflect.Descriptor instead.
+func (*AnalysisOptions) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AnalysisOptions) GetJsonOutput() *JSONOutputOptions {
+	if x != nil {
+		return x.JsonOutput
+	}
+	return nil
+}
+
+func (x *AnalysisOptions) GetOutputFormat() OutputFormat {
+	if x != nil {
+		return x.OutputFormat
+	}
+	return OutputFormat_OUTPUT_FORMAT_UNSPECIFIED
+}
+
+func (x *AnalysisOptions) GetConvertTo() OutputFormat {
+	if x != nil {
+		return x.ConvertTo
+	}
+	return OutputFormat_OUTPUT_FORMAT_UNSPECIFIED
+}
+
+func (x *AnalysisOptions) GetGeneration() *GenerationParameters {
+	if x != nil {
+		return x.Generation
+	}
+	return nil
+}
+
+func (x *AnalysisOptions) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *AnalysisOptions) GetTemplate() *PromptTemplate {
+	if x != nil {
+		return x.Template
+	}
+	return nil
+}
+
+func (x *AnalysisOptions) GetPages() *PageRange {
+	if x != nil {
+		return x.Pages
+	}
+	return nil
+}
+
+func (x *AnalysisOptions) GetTiling() *TilingOptions {
+	if x != nil {
+		return x.Tiling
+	}
+	return nil
+}
+
+type TilingOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// rows and columns of the tile grid, both are computed from the server tile size when zero
+	Rows          int32 `protobuf:"varint,1,opt,name=rows,proto3" json:"rows,omitempty"`
+	Columns       int32 `protobuf:"varint,2,opt,name=columns,proto3" json:"columns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TilingOptions) Reset() {
+	*x = TilingOptions{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TilingOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TilingOptions) ProtoMessage() {}
+
+func (x *TilingOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TilingOptions.ProtoReflect.Descriptor instead.
+func (*TilingOptions) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TilingOptions) GetRows() int32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *TilingOptions) GetColumns() int32 {
+	if x != nil {
+		return x.Columns
+	}
+	return 0
+}
+
+type PageRange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// first is the first page to analyse, starting at 1
+	First int32 `protobuf:"varint,1,opt,name=first,proto3" json:"first,omitempty"`
+	// last is the last page to analyse, the last page of the document when zero
+	Last          int32 `protobuf:"varint,2,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageRange) Reset() {
+	*x = PageRange{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageRange) ProtoMessage() {}
+
+func (x *PageRange) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageRange.ProtoReflect.Descriptor instead.
+func (*PageRange) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PageRange) GetFirst() int32 {
+	if x != nil {
+		return x.First
+	}
+	return 0
+}
+
+func (x *PageRange) GetLast() int32 {
+	if x != nil {
+		return x.Last
+	}
+	return 0
+}
+
+type PromptTemplate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// version of the template, the latest version is used when zero
+	Version       int32             `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Variables     map[string]string `protobuf:"bytes,3,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromptTemplate) Reset() {
+	*x = PromptTemplate{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromptTemplate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromptTemplate) ProtoMessage() {}
+
+func (x *PromptTemplate) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromptTemplate.ProtoReflect.Descriptor instead.
+func (*PromptTemplate) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PromptTemplate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PromptTemplate) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *PromptTemplate) GetVariables() map[string]string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+type GenerationParameters struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MaxTokens      *int32                 `protobuf:"varint,1,opt,name=maxTokens,proto3,oneof" json:"maxTokens,omitempty"`
+	Temperature    *float32               `protobuf:"fixed32,2,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	TopP           *float32               `protobuf:"fixed32,3,opt,name=topP,proto3,oneof" json:"topP,omitempty"`
+	TopK           *int32                 `protobuf:"varint,4,opt,name=topK,proto3,oneof" json:"topK,omitempty"`
+	StopSequences  []string               `protobuf:"bytes,5,rep,name=stopSequences,proto3" json:"stopSequences,omitempty"`
+	Seed           *int32                 `protobuf:"varint,6,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+	CandidateCount *int32                 `protobuf:"varint,7,opt,name=candidateCount,proto3,oneof" json:"candidateCount,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GenerationParameters) Reset() {
+	*x = GenerationParameters{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationParameters) ProtoMessage() {}
+
+func (x *GenerationParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationParameters.ProtoReflect.Descriptor instead.
+func (*GenerationParameters) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GenerationParameters) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *GenerationParameters) GetTemperature() float32 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *GenerationParameters) GetTopP() float32 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *GenerationParameters) GetTopK() int32 {
+	if x != nil && x.TopK != nil {
+		return *x.TopK
+	}
+	return 0
+}
+
+func (x *GenerationParameters) GetStopSequences() []string {
+	if x != nil {
+		return x.StopSequences
+	}
+	return nil
+}
+
+func (x *GenerationParameters) GetSeed() int32 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+func (x *GenerationParameters) GetCandidateCount() int32 {
+	if x != nil && x.CandidateCount != nil {
+		return *x.CandidateCount
+	}
+	return 0
+}
+
+type JSONOutputOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// jsonSchema is an inline JSON Schema document, it takes precedence over schemaName
+	JsonSchema string `protobuf:"bytes,1,opt,name=jsonSchema,proto3" json:"jsonSchema,omitempty"`
+	// schemaName references a JSON Schema registered on the server
+	SchemaName    string `protobuf:"bytes,2,opt,name=schemaName,proto3" json:"schemaName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JSONOutputOptions) Reset() {
+	*x = JSONOutputOptions{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JSONOutputOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JSONOutputOptions) ProtoMessage() {}
+
+func (x *JSONOutputOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JSONOutputOptions.ProtoReflect.Descriptor instead.
+func (*JSONOutputOptions) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *JSONOutputOptions) GetJsonSchema() string {
+	if x != nil {
+		return x.JsonSchema
+	}
+	return ""
+}
+
+func (x *JSONOutputOptions) GetSchemaName() string {
+	if x != nil {
+		return x.SchemaName
+	}
+	return ""
+}
+
+type ImagePromptResponse struct {
+	state            protoimpl.MessageState  `protogen:"open.v1"`
+	ResponseToPrompt string                  `protobuf:"bytes,1,opt,name=responseToPrompt,proto3" json:"responseToPrompt,omitempty"`
+	FinishReason     string                  `protobuf:"bytes,2,opt,name=finishReason,proto3" json:"finishReason,omitempty"`
+	Candidates       []*ImagePromptCandidate `protobuf:"bytes,3,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	Model            string                  `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	Usage            *TokenUsage             `protobuf:"bytes,5,opt,name=usage,proto3" json:"usage,omitempty"`
+	// estimatedCost is computed from the server price table of the model, zero when it has no price
+	EstimatedCost float64 `protobuf:"fixed64,6,opt,name=estimatedCost,proto3" json:"estimatedCost,omitempty"`
+	// tiles holds the answer about every tile in tiling mode, responseToPrompt then holds the merged answer
+	Tiles         []*TileAnswer `protobuf:"bytes,7,rep,name=tiles,proto3" json:"tiles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImagePromptResponse) Reset() {
+	*x = ImagePromptResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImagePromptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImagePromptResponse) ProtoMessage() {}
+
+func (x *ImagePromptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImagePromptResponse.ProtoReflect.Descriptor instead.
+func (*ImagePromptResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ImagePromptResponse) GetResponseToPrompt() string {
+	if x != nil {
+		return x.ResponseToPrompt
+	}
+	return ""
+}
+
+func (x *ImagePromptResponse) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *ImagePromptResponse) GetCandidates() []*ImagePromptCandidate {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *ImagePromptResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ImagePromptResponse) GetUsage() *TokenUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ImagePromptResponse) GetEstimatedCost() float64 {
+	if x != nil {
+		return x.EstimatedCost
+	}
+	return 0
+}
+
+func (x *ImagePromptResponse) GetTiles() []*TileAnswer {
+	if x != nil {
+		return x.Tiles
+	}
+	return nil
+}
+
+type TileAnswer struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// label is cited in the merged answer next to the findings of the tile, it is overview for the thumbnail
+	Label string `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	// row and column start at 1, both are zero for the overview
+	Row    int32 `protobuf:"varint,2,opt,name=row,proto3" json:"row,omitempty"`
+	Column int32 `protobuf:"varint,3,opt,name=column,proto3" json:"column,omitempty"`
+	// x, y, width and height are the bounds of the tile in pixels
+	X             int32  `protobuf:"varint,4,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32  `protobuf:"varint,5,opt,name=y,proto3" json:"y,omitempty"`
+	Width         int32  `protobuf:"varint,6,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32  `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+	Response      string `protobuf:"bytes,8,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TileAnswer) Reset() {
+	*x = TileAnswer{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TileAnswer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TileAnswer) ProtoMessage() {}
+
+func (x *TileAnswer) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TileAnswer.ProtoReflect.Descriptor instead.
+func (*TileAnswer) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TileAnswer) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *TileAnswer) GetRow() int32 {
+	if x != nil {
+		return x.Row
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetColumn() int32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *TileAnswer) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+type TokenUsage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens     int32                  `protobuf:"varint,1,opt,name=promptTokens,proto3" json:"promptTokens,omitempty"`
+	CandidatesTokens int32                  `protobuf:"varint,2,opt,name=candidatesTokens,proto3" json:"candidatesTokens,omitempty"`
+	TotalTokens      int32                  `protobuf:"varint,3,opt,name=totalTokens,proto3" json:"totalTokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TokenUsage) Reset() {
+	*x = TokenUsage{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenUsage) ProtoMessage() {}
+
+func (x *TokenUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenUsage.ProtoReflect.Descriptor instead.
+func (*TokenUsage) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TokenUsage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCandidatesTokens() int32 {
+	if x != nil {
+		return x.CandidatesTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+type ImagePromptCandidate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Response      string                 `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	FinishReason  string                 `protobuf:"bytes,2,opt,name=finishReason,proto3" json:"finishReason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImagePromptCandidate) Reset() {
+	*x = ImagePromptCandidate{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImagePromptCandidate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImagePromptCandidate) ProtoMessage() {}
+
+func (x *ImagePromptCandidate) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImagePromptCandidate.ProtoReflect.Descriptor instead.
+func (*ImagePromptCandidate) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ImagePromptCandidate) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+func (x *ImagePromptCandidate) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+type ImagePromptStreamResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ResponseChunk string                 `protobuf:"bytes,1,opt,name=responseChunk,proto3" json:"responseChunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImagePromptStreamResponse) Reset() {
+	*x = ImagePromptStreamResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImagePromptStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImagePromptStreamResponse) ProtoMessage() {}
+
+func (x *ImagePromptStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImagePromptStreamResponse.ProtoReflect.Descriptor instead.
+func (*ImagePromptStreamResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ImagePromptStreamResponse) GetResponseChunk() string {
+	if x != nil {
+		return x.ResponseChunk
+	}
+	return ""
+}
+
+type ImageUploadMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MimeType      string                 `protobuf:"bytes,1,opt,name=mimeType,proto3" json:"mimeType,omitempty"`
+	Prompt        string                 `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	TotalSize     int64                  `protobuf:"varint,3,opt,name=totalSize,proto3" json:"totalSize,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Options       *AnalysisOptions       `protobuf:"bytes,5,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageUploadMetadata) Reset() {
+	*x = ImageUploadMetadata{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageUploadMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageUploadMetadata) ProtoMessage() {}
+
+func (x *ImageUploadMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageUploadMetadata.ProtoReflect.Descriptor instead.
+func (*ImageUploadMetadata) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ImageUploadMetadata) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *ImageUploadMetadata) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *ImageUploadMetadata) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+func (x *ImageUploadMetadata) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *ImageUploadMetadata) GetOptions() *AnalysisOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type ImageUploadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ImageUploadRequest_Metadata
+	//	*ImageUploadRequest_Chunk
+	Payload       isImageUploadRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageUploadRequest) Reset() {
+	*x = ImageUploadRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageUploadRequest) ProtoMessage() {}
+
+func (x *ImageUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageUploadRequest.ProtoReflect.Descriptor instead.
+func (*ImageUploadRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ImageUploadRequest) GetPayload() isImageUploadRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ImageUploadRequest) GetMetadata() *ImageUploadMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*ImageUploadRequest_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *ImageUploadRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*ImageUploadRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isImageUploadRequest_Payload interface {
+	isImageUploadRequest_Payload()
+}
+
+type ImageUploadRequest_Metadata struct {
+	Metadata *ImageUploadMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type ImageUploadRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*ImageUploadRequest_Metadata) isImageUploadRequest_Payload() {}
+
+func (*ImageUploadRequest_Chunk) isImageUploadRequest_Payload() {}
+
+type CreateSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Images        []*Image               `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CreateSessionRequest) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type CreateSessionResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	// expiresAt is the unix time in seconds the session expires at unless a message is sent
+	ExpiresAt     int64 `protobuf:"varint,2,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionResponse) Reset() {
+	*x = CreateSessionResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionResponse) ProtoMessage() {}
+
+func (x *CreateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionResponse.ProtoReflect.Descriptor instead.
+func (*CreateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CreateSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *CreateSessionResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type SessionMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Prompt        string                 `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Options       *AnalysisOptions       `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionMessageRequest) Reset() {
+	*x = SessionMessageRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionMessageRequest) ProtoMessage() {}
+
+func (x *SessionMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionMessageRequest.ProtoReflect.Descriptor instead.
+func (*SessionMessageRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SessionMessageRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionMessageRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *SessionMessageRequest) GetOptions() *AnalysisOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type SessionMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// role is either user or model
+	Role          string `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Text          string `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionMessage) Reset() {
+	*x = SessionMessage{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionMessage) ProtoMessage() {}
+
+func (x *SessionMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionMessage.ProtoReflect.Descriptor instead.
+func (*SessionMessage) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SessionMessage) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type GetSessionHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionHistoryRequest) Reset() {
+	*x = GetSessionHistoryRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionHistoryRequest) ProtoMessage() {}
+
+func (x *GetSessionHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetSessionHistoryRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type GetSessionHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	Messages      []*SessionMessage      `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	ImageCount    int32                  `protobuf:"varint,3,opt,name=imageCount,proto3" json:"imageCount,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,5,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionHistoryResponse) Reset() {
+	*x = GetSessionHistoryResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionHistoryResponse) ProtoMessage() {}
+
+func (x *GetSessionHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetSessionHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetSessionHistoryResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *GetSessionHistoryResponse) GetMessages() []*SessionMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *GetSessionHistoryResponse) GetImageCount() int32 {
+	if x != nil {
+		return x.ImageCount
+	}
+	return 0
+}
+
+func (x *GetSessionHistoryResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *GetSessionHistoryResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type DeleteSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=sessionId,proto3" json:"sessionId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionRequest) Reset() {
+	*x = DeleteSessionRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionRequest) ProtoMessage() {}
+
+func (x *DeleteSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSessionRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeleteSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type DeleteSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionResponse) Reset() {
+	*x = DeleteSessionResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionResponse) ProtoMessage() {}
+
+func (x *DeleteSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSessionResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{22}
+}
+
+// GetUsageRequest asks for the usage of the tenant of the caller
+type GetUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageRequest) Reset() {
+	*x = GetUsageRequest{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageRequest) ProtoMessage() {}
+
+func (x *GetUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageRequest) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{23}
+}
+
+type GetUsageResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tenant is the tenant the requests of the caller are counted against
+	Tenant        string       `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Daily         *BudgetUsage `protobuf:"bytes,2,opt,name=daily,proto3" json:"daily,omitempty"`
+	Monthly       *BudgetUsage `protobuf:"bytes,3,opt,name=monthly,proto3" json:"monthly,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageResponse) Reset() {
+	*x = GetUsageResponse{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageResponse) ProtoMessage() {}
+
+func (x *GetUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetUsageResponse) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetUsageResponse) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *GetUsageResponse) GetDaily() *BudgetUsage {
+	if x != nil {
+		return x.Daily
+	}
+	return nil
+}
+
+func (x *GetUsageResponse) GetMonthly() *BudgetUsage {
+	if x != nil {
+		return x.Monthly
+	}
+	return nil
+}
+
+// BudgetUsage is the usage of a tenant in the current UTC day or month, limits of zero are unbounded
+type BudgetUsage struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	UsedTokens int64                  `protobuf:"varint,1,opt,name=usedTokens,proto3" json:"usedTokens,omitempty"`
+	UsedCost   float64                `protobuf:"fixed64,2,opt,name=usedCost,proto3" json:"usedCost,omitempty"`
+	TokenLimit int64                  `protobuf:"varint,3,opt,name=tokenLimit,proto3" json:"tokenLimit,omitempty"`
+	CostLimit  float64                `protobuf:"fixed64,4,opt,name=costLimit,proto3" json:"costLimit,omitempty"`
+	// remainingTokens and remainingCost are zero once the budget is spent and when it is unbounded
+	RemainingTokens int64   `protobuf:"varint,5,opt,name=remainingTokens,proto3" json:"remainingTokens,omitempty"`
+	RemainingCost   float64 `protobuf:"fixed64,6,opt,name=remainingCost,proto3" json:"remainingCost,omitempty"`
+	// resetsAt is the unix time in seconds the usage is reset at
+	ResetsAt      int64 `protobuf:"varint,7,opt,name=resetsAt,proto3" json:"resetsAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BudgetUsage) Reset() {
+	*x = BudgetUsage{}
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BudgetUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BudgetUsage) ProtoMessage() {}
+
+func (x *BudgetUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BudgetUsage.ProtoReflect.Descriptor instead.
+func (*BudgetUsage) Descriptor() ([]byte, []int) {
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BudgetUsage) GetUsedTokens() int64 {
+	if x != nil {
+		return x.UsedTokens
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetUsedCost() float64 {
+	if x != nil {
+		return x.UsedCost
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetTokenLimit() int64 {
+	if x != nil {
+		return x.TokenLimit
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetCostLimit() float64 {
+	if x != nil {
+		return x.CostLimit
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetRemainingTokens() int64 {
+	if x != nil {
+		return x.RemainingTokens
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetRemainingCost() float64 {
+	if x != nil {
+		return x.RemainingCost
+	}
+	return 0
+}
+
+func (x *BudgetUsage) GetResetsAt() int64 {
+	if x != nil {
+		return x.ResetsAt
+	}
+	return 0
+}
+
+var File_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto protoreflect.FileDescriptor
+
+const file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDesc = "" +
+	"\n" +
+	">qd-protobuf-definitions/v1/image-analysis/image-analysis.proto\x12\x06src.pb\"\xc0\x01\n" +
+	"\x12ImagePromptRequest\x12\x1c\n" +
+	"\timageData\x18\x01 \x01(\fR\timageData\x12\x1a\n" +
+	"\bmimeType\x18\x02 \x01(\tR\bmimeType\x12\x16\n" +
+	"\x06prompt\x18\x03 \x01(\tR\x06prompt\x121\n" +
+	"\aoptions\x18\x04 \x01(\v2\x17.src.pb.AnalysisOptionsR\aoptions\x12%\n" +
+	"\x06images\x18\x05 \x03(\v2\r.src.pb.ImageR\x06images\"7\n" +
+	"\x05Image\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1a\n" +
+	"\bmimeType\x18\x02 \x01(\tR\bmimeType\"\x9a\x03\n" +
+	"\x0fAnalysisOptions\x129\n" +
+	"\n" +
+	"jsonOutput\x18\x01 \x01(\v2\x19.src.pb.JSONOutputOptionsR\n" +
+	"jsonOutput\x128\n" +
+	"\foutputFormat\x18\x02 \x01(\x0e2\x14.src.pb.OutputFormatR\foutputFormat\x122\n" +
+	"\tconvertTo\x18\x03 \x01(\x0e2\x14.src.pb.OutputFormatR\tconvertTo\x12<\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\v2\x1c.src.pb.GenerationParametersR\n" +
+	"generation\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x122\n" +
+	"\btemplate\x18\x06 \x01(\v2\x16.src.pb.PromptTemplateR\btemplate\x12'\n" +
+	"\x05pages\x18\a \x01(\v2\x11.src.pb.PageRangeR\x05pages\x12-\n" +
+	"\x06tiling\x18\b \x01(\v2\x15.src.pb.TilingOptionsR\x06tiling\"=\n" +
+	"\rTilingOptions\x12\x12\n" +
+	"\x04rows\x18\x01 \x01(\x05R\x04rows\x12\x18\n" +
+	"\acolumns\x18\x02 \x01(\x05R\acolumns\"5\n" +
+	"\tPageRange\x12\x14\n" +
+	"\x05first\x18\x01 \x01(\x05R\x05first\x12\x12\n" +
+	"\x04last\x18\x02 \x01(\x05R\x04last\"\xbd\x01\n" +
+	"\x0ePromptTemplate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\x12C\n" +
+	"\tvariables\x18\x03 \x03(\v2%.src.pb.PromptTemplate.VariablesEntryR\tvariables\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xca\x02\n" +
+	"\x14GenerationParameters\x12!\n" +
+	"\tmaxTokens\x18\x01 \x01(\x05H\x00R\tmaxTokens\x88\x01\x01\x12%\n" +
+	"\vtemperature\x18\x02 \x01(\x02H\x01R\vtemperature\x88\x01\x01\x12\x17\n" +
+	"\x04topP\x18\x03 \x01(\x02H\x02R\x04topP\x88\x01\x01\x12\x17\n" +
+	"\x04topK\x18\x04 \x01(\x05H\x03R\x04topK\x88\x01\x01\x12$\n" +
+	"\rstopSequences\x18\x05 \x03(\tR\rstopSequences\x12\x17\n" +
+	"\x04seed\x18\x06 \x01(\x05H\x04R\x04seed\x88\x01\x01\x12+\n" +
+	"\x0ecandidateCount\x18\a \x01(\x05H\x05R\x0ecandidateCount\x88\x01\x01B\f\n" +
+	"\n" +
+	"_maxTokensB\x0e\n" +
+	"\f_temperatureB\a\n" +
+	"\x05_topPB\a\n" +
+	"\x05_topKB\a\n" +
+	"\x05_seedB\x11\n" +
+	"\x0f_candidateCount\"S\n" +
+	"\x11JSONOutputOptions\x12\x1e\n" +
+	"\n" +
+	"jsonSchema\x18\x01 \x01(\tR\n" +
+	"jsonSchema\x12\x1e\n" +
+	"\n" +
+	"schemaName\x18\x02 \x01(\tR\n" +
+	"schemaName\"\xb3\x02\n" +
+	"\x13ImagePromptResponse\x12*\n" +
+	"\x10responseToPrompt\x18\x01 \x01(\tR\x10responseToPrompt\x12\"\n" +
+	"\ffinishReason\x18\x02 \x01(\tR\ffinishReason\x12<\n" +
+	"\n" +
+	"candidates\x18\x03 \x03(\v2\x1c.src.pb.ImagePromptCandidateR\n" +
+	"candidates\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12(\n" +
+	"\x05usage\x18\x05 \x01(\v2\x12.src.pb.TokenUsageR\x05usage\x12$\n" +
+	"\restimatedCost\x18\x06 \x01(\x01R\restimatedCost\x12(\n" +
+	"\x05tiles\x18\a \x03(\v2\x12.src.pb.TileAnswerR\x05tiles\"\xb2\x01\n" +
+	"\n" +
+	"TileAnswer\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x10\n" +
+	"\x03row\x18\x02 \x01(\x05R\x03row\x12\x16\n" +
+	"\x06column\x18\x03 \x01(\x05R\x06column\x12\f\n" +
+	"\x01x\x18\x04 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x05 \x01(\x05R\x01y\x12\x14\n" +
+	"\x05width\x18\x06 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\a \x01(\x05R\x06height\x12\x1a\n" +
+	"\bresponse\x18\b \x01(\tR\bresponse\"~\n" +
+	"\n" +
+	"TokenUsage\x12\"\n" +
+	"\fpromptTokens\x18\x01 \x01(\x05R\fpromptTokens\x12*\n" +
+	"\x10candidatesTokens\x18\x02 \x01(\x05R\x10candidatesTokens\x12 \n" +
+	"\vtotalTokens\x18\x03 \x01(\x05R\vtotalTokens\"V\n" +
+	"\x14ImagePromptCandidate\x12\x1a\n" +
+	"\bresponse\x18\x01 \x01(\tR\bresponse\x12\"\n" +
+	"\ffinishReason\x18\x02 \x01(\tR\ffinishReason\"A\n" +
+	"\x19ImagePromptStreamResponse\x12$\n" +
+	"\rresponseChunk\x18\x01 \x01(\tR\rresponseChunk\"\xb2\x01\n" +
+	"\x13ImageUploadMetadata\x12\x1a\n" +
+	"\bmimeType\x18\x01 \x01(\tR\bmimeType\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12\x1c\n" +
+	"\ttotalSize\x18\x03 \x01(\x03R\ttotalSize\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x121\n" +
+	"\aoptions\x18\x05 \x01(\v2\x17.src.pb.AnalysisOptionsR\aoptions\"r\n" +
+	"\x12ImageUploadRequest\x129\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1b.src.pb.ImageUploadMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\apayload\"=\n" +
+	"\x14CreateSessionRequest\x12%\n" +
+	"\x06images\x18\x01 \x03(\v2\r.src.pb.ImageR\x06images\"S\n" +
+	"\x15CreateSessionResponse\x12\x1c\n" +
+	"\tsessionId\x18\x01 \x01(\tR\tsessionId\x12\x1c\n" +
+	"\texpiresAt\x18\x02 \x01(\x03R\texpiresAt\"\x80\x01\n" +
+	"\x15SessionMessageRequest\x12\x1c\n" +
+	"\tsessionId\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x121\n" +
+	"\aoptions\x18\x03 \x01(\v2\x17.src.pb.AnalysisOptionsR\aoptions\"8\n" +
+	"\x0eSessionMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"8\n" +
+	"\x18GetSessionHistoryRequest\x12\x1c\n" +
+	"\tsessionId\x18\x01 \x01(\tR\tsessionId\"\xc9\x01\n" +
+	"\x19GetSessionHistoryResponse\x12\x1c\n" +
+	"\tsessionId\x18\x01 \x01(\tR\tsessionId\x122\n" +
+	"\bmessages\x18\x02 \x03(\v2\x16.src.pb.SessionMessageR\bmessages\x12\x1e\n" +
+	"\n" +
+	"imageCount\x18\x03 \x01(\x05R\n" +
+	"imageCount\x12\x1c\n" +
+	"\tcreatedAt\x18\x04 \x01(\x03R\tcreatedAt\x12\x1c\n" +
+	"\texpiresAt\x18\x05 \x01(\x03R\texpiresAt\"4\n" +
+	"\x14DeleteSessionRequest\x12\x1c\n" +
+	"\tsessionId\x18\x01 \x01(\tR\tsessionId\"\x17\n" +
+	"\x15DeleteSessionResponse\"\x11\n" +
+	"\x0fGetUsageRequest\"\x84\x01\n" +
+	"\x10GetUsageResponse\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12)\n" +
+	"\x05daily\x18\x02 \x01(\v2\x13.src.pb.BudgetUsageR\x05daily\x12-\n" +
+	"\amonthly\x18\x03 \x01(\v2\x13.src.pb.BudgetUsageR\amonthly\"\xf3\x01\n" +
+	"\vBudgetUsage\x12\x1e\n" +
+	"\n" +
+	"usedTokens\x18\x01 \x01(\x03R\n" +
+	"usedTokens\x12\x1a\n" +
+	"\busedCost\x18\x02 \x01(\x01R\busedCost\x12\x1e\n" +
+	"\n" +
+	"tokenLimit\x18\x03 \x01(\x03R\n" +
+	"tokenLimit\x12\x1c\n" +
+	"\tcostLimit\x18\x04 \x01(\x01R\tcostLimit\x12(\n" +
+	"\x0fremainingTokens\x18\x05 \x01(\x03R\x0fremainingTokens\x12$\n" +
+	"\rremainingCost\x18\x06 \x01(\x01R\rremainingCost\x12\x1a\n" +
+	"\bresetsAt\x18\a \x01(\x03R\bresetsAt*\x97\x01\n" +
+	"\fOutputFormat\x12\x1d\n" +
+	"\x19OUTPUT_FORMAT_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16OUTPUT_FORMAT_MARKDOWN\x10\x01\x12\x1c\n" +
+	"\x18OUTPUT_FORMAT_PLAIN_TEXT\x10\x02\x12\x16\n" +
+	"\x12OUTPUT_FORMAT_HTML\x10\x03\x12\x16\n" +
+	"\x12OUTPUT_FORMAT_JSON\x10\x042\xa2\x05\n" +
+	"\x14ImageAnalysisService\x12P\n" +
+	"\x15ProcessImageAndPrompt\x12\x1a.src.pb.ImagePromptRequest\x1a\x1b.src.pb.ImagePromptResponse\x12^\n" +
+	"\x1bProcessImageAndPromptStream\x12\x1a.src.pb.ImagePromptRequest\x1a!.src.pb.ImagePromptStreamResponse0\x01\x12Q\n" +
+	"\x14UploadImageAndPrompt\x12\x1a.src.pb.ImageUploadRequest\x1a\x1b.src.pb.ImagePromptResponse(\x01\x12L\n" +
+	"\rCreateSession\x12\x1c.src.pb.CreateSessionRequest\x1a\x1d.src.pb.CreateSessionResponse\x12P\n" +
+	"\x12SendSessionMessage\x12\x1d.src.pb.SessionMessageRequest\x1a\x1b.src.pb.ImagePromptResponse\x12X\n" +
+	"\x11GetSessionHistory\x12 .src.pb.GetSessionHistoryRequest\x1a!.src.pb.GetSessionHistoryResponse\x12L\n" +
+	"\rDeleteSession\x12\x1c.src.pb.DeleteSessionRequest\x1a\x1d.src.pb.DeleteSessionResponse\x12=\n" +
+	"\bGetUsage\x12\x17.src.pb.GetUsageRequest\x1a\x18.src.pb.GetUsageResponseB\x1cZ\x1a./gen/go/pb_image_analysisb\x06proto3"
+
+var (
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescOnce sync.Once
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescData []byte
+)
+
+func file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescGZIP() []byte {
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescOnce.Do(func() {
+		file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDesc), len(file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDesc)))
+	})
+	return file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDescData
+}
+
+var file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_goTypes = []any{
+	(OutputFormat)(0),                 // 0: src.pb.OutputFormat
+	(*ImagePromptRequest)(nil),        // 1: src.pb.ImagePromptRequest
+	(*Image)(nil),                     // 2: src.pb.Image
+	(*AnalysisOptions)(nil),           // 3: src.pb.AnalysisOptions
+	(*TilingOptions)(nil),             // 4: src.pb.TilingOptions
+	(*PageRange)(nil),                 // 5: src.pb.PageRange
+	(*PromptTemplate)(nil),            // 6: src.pb.PromptTemplate
+	(*GenerationParameters)(nil),      // 7: src.pb.GenerationParameters
+	(*JSONOutputOptions)(nil),         // 8: src.pb.JSONOutputOptions
+	(*ImagePromptResponse)(nil),       // 9: src.pb.ImagePromptResponse
+	(*TileAnswer)(nil),                // 10: src.pb.TileAnswer
+	(*TokenUsage)(nil),                // 11: src.pb.TokenUsage
+	(*ImagePromptCandidate)(nil),      // 12: src.pb.ImagePromptCandidate
+	(*ImagePromptStreamResponse)(nil), // 13: src.pb.ImagePromptStreamResponse
+	(*ImageUploadMetadata)(nil),       // 14: src.pb.ImageUploadMetadata
+	(*ImageUploadRequest)(nil),        // 15: src.pb.ImageUploadRequest
+	(*CreateSessionRequest)(nil),      // 16: src.pb.CreateSessionRequest
+	(*CreateSessionResponse)(nil),     // 17: src.pb.CreateSessionResponse
+	(*SessionMessageRequest)(nil),     // 18: src.pb.SessionMessageRequest
+	(*SessionMessage)(nil),            // 19: src.pb.SessionMessage
+	(*GetSessionHistoryRequest)(nil),  // 20: src.pb.GetSessionHistoryRequest
+	(*GetSessionHistoryResponse)(nil), // 21: src.pb.GetSessionHistoryResponse
+	(*DeleteSessionRequest)(nil),      // 22: src.pb.DeleteSessionRequest
+	(*DeleteSessionResponse)(nil),     // 23: src.pb.DeleteSessionResponse
+	(*GetUsageRequest)(nil),           // 24: src.pb.GetUsageRequest
+	(*GetUsageResponse)(nil),          // 25: src.pb.GetUsageResponse
+	(*BudgetUsage)(nil),               // 26: src.pb.BudgetUsage
+	nil,                               // 27: src.pb.PromptTemplate.VariablesEntry
+}
+var file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_depIdxs = []int32{
+	3,  // 0: src.pb.ImagePromptRequest.options:type_name -> src.pb.AnalysisOptions
+	2,  // 1: src.pb.ImagePromptRequest.images:type_name -> src.pb.Image
+	8,  // 2: src.pb.AnalysisOptions.jsonOutput:type_name -> src.pb.JSONOutputOptions
+	0,  // 3: src.pb.AnalysisOptions.outputFormat:type_name -> src.pb.OutputFormat
+	0,  // 4: src.pb.AnalysisOptions.convertTo:type_name -> src.pb.OutputFormat
+	7,  // 5: src.pb.AnalysisOptions.generation:type_name -> src.pb.GenerationParameters
+	6,  // 6: src.pb.AnalysisOptions.template:type_name -> src.pb.PromptTemplate
+	5,  // 7: src.pb.AnalysisOptions.pages:type_name -> src.pb.PageRange
+	4,  // 8: src.pb.AnalysisOptions.tiling:type_name -> src.pb.TilingOptions
+	27, // 9: src.pb.PromptTemplate.variables:type_name -> src.pb.PromptTemplate.VariablesEntry
+	12, // 10: src.pb.ImagePromptResponse.candidates:type_name -> src.pb.ImagePromptCandidate
+	11, // 11: src.pb.ImagePromptResponse.usage:type_name -> src.pb.TokenUsage
+	10, // 12: src.pb.ImagePromptResponse.tiles:type_name -> src.pb.TileAnswer
+	3,  // 13: src.pb.ImageUploadMetadata.options:type_name -> src.pb.AnalysisOptions
+	14, // 14: src.pb.ImageUploadRequest.metadata:type_name -> src.pb.ImageUploadMetadata
+	2,  // 15: src.pb.CreateSessionRequest.images:type_name -> src.pb.Image
+	3,  // 16: src.pb.SessionMessageRequest.options:type_name -> src.pb.AnalysisOptions
+	19, // 17: src.pb.GetSessionHistoryResponse.messages:type_name -> src.pb.SessionMessage
+	26, // 18: src.pb.GetUsageResponse.daily:type_name -> src.pb.BudgetUsage
+	26, // 19: src.pb.GetUsageResponse.monthly:type_name -> src.pb.BudgetUsage
+	1,  // 20: src.pb.ImageAnalysisService.ProcessImageAndPrompt:input_type -> src.pb.ImagePromptRequest
+	1,  // 21: src.pb.ImageAnalysisService.ProcessImageAndPromptStream:input_type -> src.pb.ImagePromptRequest
+	15, // 22: src.pb.ImageAnalysisService.UploadImageAndPrompt:input_type -> src.pb.ImageUploadRequest
+	16, // 23: src.pb.ImageAnalysisService.CreateSession:input_type -> src.pb.CreateSessionRequest
+	18, // 24: src.pb.ImageAnalysisService.SendSessionMessage:input_type -> src.pb.SessionMessageRequest
+	20, // 25: src.pb.ImageAnalysisService.GetSessionHistory:input_type -> src.pb.GetSessionHistoryRequest
+	22, // 26: src.pb.ImageAnalysisService.DeleteSession:input_type -> src.pb.DeleteSessionRequest
+	24, // 27: src.pb.ImageAnalysisService.GetUsage:input_type -> src.pb.GetUsageRequest
+	9,  // 28: src.pb.ImageAnalysisService.ProcessImageAndPrompt:output_type -> src.pb.ImagePromptResponse
+	13, // 29: src.pb.ImageAnalysisService.ProcessImageAndPromptStream:output_type -> src.pb.ImagePromptStreamResponse
+	9,  // 30: src.pb.ImageAnalysisService.UploadImageAndPrompt:output_type -> src.pb.ImagePromptResponse
+	17, // 31: src.pb.ImageAnalysisService.CreateSession:output_type -> src.pb.CreateSessionResponse
+	9,  // 32: src.pb.ImageAnalysisService.SendSessionMessage:output_type -> src.pb.ImagePromptResponse
+	21, // 33: src.pb.ImageAnalysisService.GetSessionHistory:output_type -> src.pb.GetSessionHistoryResponse
+	23, // 34: src.pb.ImageAnalysisService.DeleteSession:output_type -> src.pb.DeleteSessionResponse
+	25, // 35: src.pb.ImageAnalysisService.GetUsage:output_type -> src.pb.GetUsageResponse
+	28, // [28:36] is the sub-list for method output_type
+	20, // [20:28] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_init() }
+func file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_init() {
+	if File_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto != nil {
+		return
+	}
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[6].OneofWrappers = []any{}
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes[14].OneofWrappers = []any{
+		(*ImageUploadRequest_Metadata)(nil),
+		(*ImageUploadRequest_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDesc), len(file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   27,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_goTypes,
+		DependencyIndexes: file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_depIdxs,
+		EnumInfos:         file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_enumTypes,
+		MessageInfos:      file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_msgTypes,
+	}.Build()
+	File_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto = out.File
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_goTypes = nil
+	file_qd_protobuf_definitions_v1_image_analysis_image_analysis_proto_depIdxs = nil
+}
@@ -0,0 +1,430 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: qd-protobuf-definitions/v1/image-analysis/image-analysis.proto
+
+package pb_image_analysis
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ImageAnalysisService_ProcessImageAndPrompt_FullMethodName       = "/src.pb.ImageAnalysisService/ProcessImageAndPrompt"
+	ImageAnalysisService_ProcessImageAndPromptStream_FullMethodName = "/src.pb.ImageAnalysisService/ProcessImageAndPromptStream"
+	ImageAnalysisService_UploadImageAndPrompt_FullMethodName        = "/src.pb.ImageAnalysisService/UploadImageAndPrompt"
+	ImageAnalysisService_CreateSession_FullMethodName               = "/src.pb.ImageAnalysisService/CreateSession"
+	ImageAnalysisService_SendSessionMessage_FullMethodName          = "/src.pb.ImageAnalysisService/SendSessionMessage"
+	ImageAnalysisService_GetSessionHistory_FullMethodName           = "/src.pb.ImageAnalysisService/GetSessionHistory"
+	ImageAnalysisService_DeleteSession_FullMethodName               = "/src.pb.ImageAnalysisService/DeleteSession"
+	ImageAnalysisService_GetUsage_FullMethodName                    = "/src.pb.ImageAnalysisService/GetUsage"
+)
+
+// ImageAnalysisServiceClient is the client API for ImageAnalysisService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImageAnalysisServiceClient interface {
+	ProcessImageAndPrompt(ctx context.Context, in *ImagePromptRequest, opts ...grpc.CallOption) (*ImagePromptResponse, error)
+	ProcessImageAndPromptStream(ctx context.Context, in *ImagePromptRequest, opts ...grpc.CallOption) (ImageAnalysisService_ProcessImageAndPromptStreamClient, error)
+	UploadImageAndPrompt(ctx context.Context, opts ...grpc.CallOption) (ImageAnalysisService_UploadImageAndPromptClient, error)
+	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error)
+	SendSessionMessage(ctx context.Context, in *SessionMessageRequest, opts ...grpc.CallOption) (*ImagePromptResponse, error)
+	GetSessionHistory(ctx context.Context, in *GetSessionHistoryRequest, opts ...grpc.CallOption) (*GetSessionHistoryResponse, error)
+	DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error)
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+}
+
+type imageAnalysisServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImageAnalysisServiceClient(cc grpc.ClientConnInterface) ImageAnalysisServiceClient {
+	return &imageAnalysisServiceClient{cc}
+}
+
+func (c *imageAnalysisServiceClient) ProcessImageAndPrompt(ctx context.Context, in *ImagePromptRequest, opts ...grpc.CallOption) (*ImagePromptResponse, error) {
+	out := new(ImagePromptResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_ProcessImageAndPrompt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageAnalysisServiceClient) ProcessImageAndPromptStream(ctx context.Context, in *ImagePromptRequest, opts ...grpc.CallOption) (ImageAnalysisService_ProcessImageAndPromptStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageAnalysisService_ServiceDesc.Streams[0], ImageAnalysisService_ProcessImageAndPromptStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageAnalysisServiceProcessImageAndPromptStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageAnalysisService_ProcessImageAndPromptStreamClient interface {
+	Recv() (*ImagePromptStreamResponse, error)
+	grpc.ClientStream
+}
+
+type imageAnalysisServiceProcessImageAndPromptStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageAnalysisServiceProcessImageAndPromptStreamClient) Recv() (*ImagePromptStreamResponse, error) {
+	m := new(ImagePromptStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageAnalysisServiceClient) UploadImageAndPrompt(ctx context.Context, opts ...grpc.CallOption) (ImageAnalysisService_UploadImageAndPromptClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageAnalysisService_ServiceDesc.Streams[1], ImageAnalysisService_UploadImageAndPrompt_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageAnalysisServiceUploadImageAndPromptClient{stream}
+	return x, nil
+}
+
+type ImageAnalysisService_UploadImageAndPromptClient interface {
+	Send(*ImageUploadRequest) error
+	CloseAndRecv() (*ImagePromptResponse, error)
+	grpc.ClientStream
+}
+
+type imageAnalysisServiceUploadImageAndPromptClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageAnalysisServiceUploadImageAndPromptClient) Send(m *ImageUploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *imageAnalysisServiceUploadImageAndPromptClient) CloseAndRecv() (*ImagePromptResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImagePromptResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageAnalysisServiceClient) CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error) {
+	out := new(CreateSessionResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_CreateSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageAnalysisServiceClient) SendSessionMessage(ctx context.Context, in *SessionMessageRequest, opts ...grpc.CallOption) (*ImagePromptResponse, error) {
+	out := new(ImagePromptResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_SendSessionMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageAnalysisServiceClient) GetSessionHistory(ctx context.Context, in *GetSessionHistoryRequest, opts ...grpc.CallOption) (*GetSessionHistoryResponse, error) {
+	out := new(GetSessionHistoryResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_GetSessionHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageAnalysisServiceClient) DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error) {
+	out := new(DeleteSessionResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_DeleteSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageAnalysisServiceClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, ImageAnalysisService_GetUsage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageAnalysisServiceServer is the server API for ImageAnalysisService service.
+// All implementations must embed UnimplementedImageAnalysisServiceServer
+// for forward compatibility
+type ImageAnalysisServiceServer interface {
+	ProcessImageAndPrompt(context.Context, *ImagePromptRequest) (*ImagePromptResponse, error)
+	ProcessImageAndPromptStream(*ImagePromptRequest, ImageAnalysisService_ProcessImageAndPromptStreamServer) error
+	UploadImageAndPrompt(ImageAnalysisService_UploadImageAndPromptServer) error
+	CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error)
+	SendSessionMessage(context.Context, *SessionMessageRequest) (*ImagePromptResponse, error)
+	GetSessionHistory(context.Context, *GetSessionHistoryRequest) (*GetSessionHistoryResponse, error)
+	DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error)
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+	mustEmbedUnimplementedImageAnalysisServiceServer()
+}
+
+// UnimplementedImageAnalysisServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedImageAnalysisServiceServer struct {
+}
+
+func (UnimplementedImageAnalysisServiceServer) ProcessImageAndPrompt(context.Context, *ImagePromptRequest) (*ImagePromptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessImageAndPrompt not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) ProcessImageAndPromptStream(*ImagePromptRequest, ImageAnalysisService_ProcessImageAndPromptStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessImageAndPromptStream not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) UploadImageAndPrompt(ImageAnalysisService_UploadImageAndPromptServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadImageAndPrompt not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) SendSessionMessage(context.Context, *SessionMessageRequest) (*ImagePromptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendSessionMessage not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) GetSessionHistory(context.Context, *GetSessionHistoryRequest) (*GetSessionHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSessionHistory not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSession not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsage not implemented")
+}
+func (UnimplementedImageAnalysisServiceServer) mustEmbedUnimplementedImageAnalysisServiceServer() {}
+
+// UnsafeImageAnalysisServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImageAnalysisServiceServer will
+// result in compilation errors.
+type UnsafeImageAnalysisServiceServer interface {
+	mustEmbedUnimplementedImageAnalysisServiceServer()
+}
+
+func RegisterImageAnalysisServiceServer(s grpc.ServiceRegistrar, srv ImageAnalysisServiceServer) {
+	s.RegisterService(&ImageAnalysisService_ServiceDesc, srv)
+}
+
+func _ImageAnalysisService_ProcessImageAndPrompt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImagePromptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).ProcessImageAndPrompt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_ProcessImageAndPrompt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).ProcessImageAndPrompt(ctx, req.(*ImagePromptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageAnalysisService_ProcessImageAndPromptStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ImagePromptRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageAnalysisServiceServer).ProcessImageAndPromptStream(m, &imageAnalysisServiceProcessImageAndPromptStreamServer{stream})
+}
+
+type ImageAnalysisService_ProcessImageAndPromptStreamServer interface {
+	Send(*ImagePromptStreamResponse) error
+	grpc.ServerStream
+}
+
+type imageAnalysisServiceProcessImageAndPromptStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageAnalysisServiceProcessImageAndPromptStreamServer) Send(m *ImagePromptStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageAnalysisService_UploadImageAndPrompt_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ImageAnalysisServiceServer).UploadImageAndPrompt(&imageAnalysisServiceUploadImageAndPromptServer{stream})
+}
+
+type ImageAnalysisService_UploadImageAndPromptServer interface {
+	SendAndClose(*ImagePromptResponse) error
+	Recv() (*ImageUploadRequest, error)
+	grpc.ServerStream
+}
+
+type imageAnalysisServiceUploadImageAndPromptServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageAnalysisServiceUploadImageAndPromptServer) SendAndClose(m *ImagePromptResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *imageAnalysisServiceUploadImageAndPromptServer) Recv() (*ImageUploadRequest, error) {
+	m := new(ImageUploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ImageAnalysisService_CreateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).CreateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_CreateSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).CreateSession(ctx, req.(*CreateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageAnalysisService_SendSessionMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).SendSessionMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_SendSessionMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).SendSessionMessage(ctx, req.(*SessionMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageAnalysisService_GetSessionHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).GetSessionHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_GetSessionHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).GetSessionHistory(ctx, req.(*GetSessionHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageAnalysisService_DeleteSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).DeleteSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_DeleteSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).DeleteSession(ctx, req.(*DeleteSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageAnalysisService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageAnalysisServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageAnalysisService_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageAnalysisServiceServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImageAnalysisService_ServiceDesc is the grpc.ServiceDesc for ImageAnalysisService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImageAnalysisService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "src.pb.ImageAnalysisService",
+	HandlerType: (*ImageAnalysisServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProcessImageAndPrompt",
+			Handler:    _ImageAnalysisService_ProcessImageAndPrompt_Handler,
+		},
+		{
+			MethodName: "CreateSession",
+			Handler:    _ImageAnalysisService_CreateSession_Handler,
+		},
+		{
+			MethodName: "SendSessionMessage",
+			Handler:    _ImageAnalysisService_SendSessionMessage_Handler,
+		},
+		{
+			MethodName: "GetSessionHistory",
+			Handler:    _ImageAnalysisService_GetSessionHistory_Handler,
+		},
+		{
+			MethodName: "DeleteSession",
+			Handler:    _ImageAnalysisService_DeleteSession_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _ImageAnalysisService_GetUsage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProcessImageAndPromptStream",
+			Handler:       _ImageAnalysisService_ProcessImageAndPromptStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadImageAndPrompt",
+			Handler:       _ImageAnalysisService_UploadImageAndPrompt_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "qd-protobuf-definitions/v1/image-analysis/image-analysis.proto",
+}
@@ -12,20 +12,19 @@ VERTEX_AI_CONFIG_VAR="${ENV_PREFIX}_ENV_VERTEX_AI_CONFIG_PATH"
 # Get the value of the dynamically constructed variable name
 VERTEX_AI_CONFIG_PATH=$(eval echo \$$VERTEX_AI_CONFIG_VAR)
 
-# Check if the constructed environment variable is set
-if [ -z "$VERTEX_AI_CONFIG_PATH" ]; then
-  echo "Error: ${VERTEX_AI_CONFIG_VAR} is not set."
-  exit 1
-fi
+# Vertex AI credentials are optional: providers such as ollama run without them
+if [ -z "$VERTEX_AI_CONFIG_PATH" ] || [ -z "$VERTEX_AI_SERVICE_ACCOUNT_BASE64" ]; then
+  echo "Warning: ${VERTEX_AI_CONFIG_VAR} or VERTEX_AI_SERVICE_ACCOUNT_BASE64 is not set, skipping Vertex AI credentials."
+else
+  # Create the directory if it does not exist
+  DIR=$(dirname "$VERTEX_AI_CONFIG_PATH")
+  if [ ! -d "$DIR" ]; then
+    mkdir -p "$DIR"
+  fi
 
-# Create the directory if it does not exist
-DIR=$(dirname "$VERTEX_AI_CONFIG_PATH")
-if [ ! -d "$DIR" ]; then
-  mkdir -p "$DIR"
+  # Decode the base64 encoded service account and write to the file
+  echo $VERTEX_AI_SERVICE_ACCOUNT_BASE64 | base64 -d > "$VERTEX_AI_CONFIG_PATH"
 fi
 
-# Decode the base64 encoded service account and write to the file
-echo $VERTEX_AI_SERVICE_ACCOUNT_BASE64 | base64 -d > "$VERTEX_AI_CONFIG_PATH"
-
 # Proceed with the rest of the entrypoint script or the application start
 exec "$@"
\ No newline at end of file